@@ -42,6 +42,7 @@ func main() {
 
 	// 初始化调度器
 	sched := scheduler.New()
+	sched.SweepLocalTempFiles()
 	if err := sched.LoadTasks(); err != nil {
 		logger.Module(logger.ModuleMain).Error("Failed to load tasks", "error", err)
 	}
@@ -50,6 +51,7 @@ func main() {
 
 	// 将调度器注入到 Handler 层，支持动态更新任务
 	apiHandler.SetScheduler(sched)
+	apiHandler.SetRunEvents(sched)
 
 	// 初始化 Gin 路由
 	r := setupRouter(cfg, authManager, apiHandler)
@@ -56,6 +56,7 @@ func setupRoutes(r *gin.Engine, cfg *config.Config, authManager *auth.Manager, a
 		protected.POST("/servers", apiHandler.CreateServer)
 		protected.PUT("/servers/:id", apiHandler.UpdateServer)
 		protected.PATCH("/servers/:id/enabled", apiHandler.SetServerEnabled)
+		protected.GET("/servers/:id/organizations", apiHandler.GetServerOrganizations)
 		protected.DELETE("/servers/:id", apiHandler.DeleteServer)
 
 		// 备份目标
@@ -76,10 +77,21 @@ func setupRoutes(r *gin.Engine, cfg *config.Config, authManager *auth.Manager, a
 		protected.PATCH("/tasks/:id/enabled", apiHandler.SetTaskEnabled)
 		protected.DELETE("/tasks/:id", apiHandler.DeleteTask)
 		protected.POST("/tasks/:id/execute", apiHandler.ExecuteTask)
+		protected.DELETE("/tasks/:id/queue", apiHandler.CancelTaskQueue)
+		protected.GET("/tasks/:id/schedule", apiHandler.GetTaskSchedule)
+		protected.POST("/cron/preview", apiHandler.PreviewCron)
+
+		// 全局禁止窗口
+		protected.GET("/blackouts", apiHandler.GetBlackouts)
+		protected.POST("/blackouts", apiHandler.CreateBlackout)
+		protected.PUT("/blackouts/:id", apiHandler.UpdateBlackout)
+		protected.DELETE("/blackouts/:id", apiHandler.DeleteBlackout)
 
 		// 日志
 		protected.GET("/logs", apiHandler.GetLogs)
 		protected.DELETE("/logs", apiHandler.DeleteLogs)
+		protected.POST("/logs/:id/cancel", apiHandler.CancelLog)
+		protected.GET("/logs/:id/stream", apiHandler.StreamLog)
 	}
 
 	// SPA History Mode Fallback
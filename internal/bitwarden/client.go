@@ -10,7 +10,6 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/mingzaily/bitwarden-backup/internal/logger"
@@ -18,8 +17,9 @@ import (
 	"github.com/mingzaily/bitwarden-backup/internal/safety"
 )
 
-// bwMu 全局互斥锁，防止并发调用 Bitwarden CLI
-var bwMu sync.Mutex
+// bwLock 全局进程锁，防止并发调用 Bitwarden CLI。使用容量为 1 的通道而不是
+// sync.Mutex，使排队等待 CLI 的任务也能响应取消。
+var bwLock = make(chan struct{}, 1)
 
 type processLockContextKey struct{}
 
@@ -42,6 +42,7 @@ type Client struct {
 	logs          []LogEntry
 	logSource     string
 	logSink       LogSink
+	listener      func(LogEntry)
 }
 
 // NewClient 创建新的 Bitwarden 客户端
@@ -64,6 +65,14 @@ func NewClientWithLogSink(source string, sink LogSink) *Client {
 	}
 }
 
+// SetListener registers a callback for every sanitized entry recorded by this
+// client, including entries forwarded from nested clients and providers. The
+// scheduler uses it to stream and checkpoint execution logs while a run is
+// still in progress.
+func (c *Client) SetListener(listener func(LogEntry)) {
+	c.listener = listener
+}
+
 // WithProcessLock serializes a complete sequence of Bitwarden CLI commands.
 // The CLI stores the configured server globally, so locking individual
 // commands is not enough when two workflows switch between source servers.
@@ -72,12 +81,30 @@ func (c *Client) WithProcessLock(ctx context.Context, fn func(context.Context) e
 		ctx = context.Background()
 	}
 
-	bwMu.Lock()
-	defer bwMu.Unlock()
+	if err := acquireProcessLock(ctx); err != nil {
+		return err
+	}
+	defer releaseProcessLock()
 
 	return fn(context.WithValue(ctx, processLockKey, true))
 }
 
+// acquireProcessLock waits for the Bitwarden CLI lock until ctx is done. A
+// cancelled run therefore leaves the queue instead of blocking behind a
+// long-running export held by another workflow.
+func acquireProcessLock(ctx context.Context) error {
+	select {
+	case bwLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for Bitwarden CLI lock: %w", context.Cause(ctx))
+	}
+}
+
+func releaseProcessLock() {
+	<-bwLock
+}
+
 func hasProcessLock(ctx context.Context) bool {
 	return ctx != nil && ctx.Value(processLockKey) == true
 }
@@ -151,6 +178,9 @@ func (c *Client) addLogWithSourceLevel(source, message, level string) {
 	}
 	c.logs = append(c.logs, entry)
 	c.logger.Info(cleanMessage, "source", source)
+	if c.listener != nil {
+		c.listener(entry)
+	}
 	if c.logSink != nil {
 		c.logSink(source, cleanMessage)
 	}
@@ -196,13 +226,21 @@ func redactBWArgs(args []string) []string {
 }
 
 func (c *Client) runBW(ctx context.Context, args []string, stdin string, extraEnv map[string]string) (bwExecResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	if !hasProcessLock(ctx) {
-		bwMu.Lock()
-		defer bwMu.Unlock()
+		if err := acquireProcessLock(ctx); err != nil {
+			return bwExecResult{ExitCode: -1}, err
+		}
+		defer releaseProcessLock()
 	}
 
 	start := time.Now()
 	cmd := exec.CommandContext(ctx, "bw", args...)
+	// The CLI may leave helper processes holding stdout/stderr after it is
+	// killed on cancellation. Bound the wait so the run can still finish.
+	cmd.WaitDelay = 5 * time.Second
 	// Do not inherit application secrets into the Bitwarden CLI process. The
 	// individual command receives only the secret it actually needs below.
 	cmd.Env = make([]string, 0, len(os.Environ())+len(extraEnv))
@@ -1,6 +1,10 @@
 package bitwarden
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+)
 
 func TestAddLogDoesNotMarkExpectedLogoutAsError(t *testing.T) {
 	client := NewClient()
@@ -31,3 +35,19 @@ func TestNestedClientKeepsExpectedLogoutInformational(t *testing.T) {
 		t.Fatalf("parent log level = %q, want info", logs[0].Level)
 	}
 }
+
+func TestProcessLockWaitRespectsContext(t *testing.T) {
+	if err := acquireProcessLock(context.Background()); err != nil {
+		t.Fatalf("acquire idle lock: %v", err)
+	}
+	defer releaseProcessLock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewClient().WithProcessLock(ctx, func(context.Context) error {
+		t.Fatal("callback should not run without the lock")
+		return nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithProcessLock() error = %v, want context.Canceled", err)
+	}
+}
@@ -26,6 +26,20 @@ func TestBuildExportArgsOmitsEmptyPassword(t *testing.T) {
 	}
 }
 
+func TestBuildImportArgsTargetsOrganization(t *testing.T) {
+	got := buildImportArgs("/tmp/backup.json", "json", "session-token", "org-id")
+	want := []string{"import", "json", "/tmp/backup.json", "--organizationid", "org-id", "--session", "session-token"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildImportArgs() = %#v, want %#v", got, want)
+	}
+
+	got = buildImportArgs("/tmp/backup.json", "json", "session-token", "")
+	want = []string{"import", "json", "/tmp/backup.json", "--session", "session-token"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildImportArgs() personal = %#v, want %#v", got, want)
+	}
+}
+
 func TestRedactBWArgsHidesPasswordAndSession(t *testing.T) {
 	got := redactBWArgs([]string{"export", "--session", "session-token", "--password", "export-password"})
 	want := []string{"export", "--session", "***", "--password", "***"}
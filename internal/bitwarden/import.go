@@ -6,16 +6,25 @@ import (
 	"strings"
 )
 
+func buildImportArgs(inputPath, format, sessionToken string, organizationID ...string) []string {
+	args := []string{"import", format, inputPath}
+	if len(organizationID) > 0 && organizationID[0] != "" {
+		args = append(args, "--organizationid", organizationID[0])
+	}
+	if sessionToken != "" {
+		args = append(args, "--session", sessionToken)
+	}
+	return args
+}
+
 // Import 导入数据到密码库
-func (c *Client) Import(ctx context.Context, inputPath, format string) error {
+// organizationID 参数为可选，提供时导入到该组织而不是个人密码库
+func (c *Client) Import(ctx context.Context, inputPath, format string, organizationID ...string) error {
 	if c.sessionToken == "" && !c.vaultUnlocked {
 		return fmt.Errorf("vault is not unlocked, please unlock first")
 	}
 
-	args := []string{"import", format, inputPath}
-	if c.sessionToken != "" {
-		args = append(args, "--session", c.sessionToken)
-	}
+	args := buildImportArgs(inputPath, format, c.sessionToken, organizationID...)
 
 	res, err := c.runBW(ctx, args, "", nil)
 	if err != nil {
@@ -0,0 +1,118 @@
+package bitwarden
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// VaultObject is an item or folder as the CLI prints it. Unknown fields are
+// kept as is, so an edited item round-trips everything the CLI returned.
+type VaultObject map[string]any
+
+// ID returns the object id, or "" for objects without one such as the
+// implicit "No Folder" folder.
+func (o VaultObject) ID() string {
+	id, _ := o["id"].(string)
+	return id
+}
+
+// String returns a string field, or "" when it is missing or null.
+func (o VaultObject) String(key string) string {
+	value, _ := o[key].(string)
+	return value
+}
+
+// ListItems 列出密码库中未删除的项目
+func (c *Client) ListItems(ctx context.Context) ([]VaultObject, error) {
+	return c.listObjects(ctx, "items")
+}
+
+// ListFolders 列出密码库中的文件夹
+func (c *Client) ListFolders(ctx context.Context) ([]VaultObject, error) {
+	return c.listObjects(ctx, "folders")
+}
+
+// ListOrganizations 列出账号所属的组织
+func (c *Client) ListOrganizations(ctx context.Context) ([]VaultObject, error) {
+	return c.listObjects(ctx, "organizations")
+}
+
+// ListCollections 列出组织中当前账号可访问的集合
+func (c *Client) ListCollections(ctx context.Context, organizationID string) ([]VaultObject, error) {
+	return c.listObjects(ctx, "collections", "--organizationid", organizationID)
+}
+
+func (c *Client) listObjects(ctx context.Context, kind string, extraArgs ...string) ([]VaultObject, error) {
+	res, err := c.runVaultCommand(ctx, append([]string{"list", kind}, extraArgs...), "")
+	if err != nil {
+		return nil, err
+	}
+	var objects []VaultObject
+	if err := json.Unmarshal([]byte(res.Stdout), &objects); err != nil {
+		return nil, fmt.Errorf("failed to parse bw list %s output: %w", kind, err)
+	}
+	return objects, nil
+}
+
+// CreateObject creates an item or folder and returns it with its new id.
+func (c *Client) CreateObject(ctx context.Context, kind string, object VaultObject) (VaultObject, error) {
+	return c.writeObject(ctx, []string{"create", kind}, object)
+}
+
+// EditObject replaces an existing item or folder.
+func (c *Client) EditObject(ctx context.Context, kind, id string, object VaultObject) (VaultObject, error) {
+	return c.writeObject(ctx, []string{"edit", kind, id}, object)
+}
+
+// writeObject passes the encoded object on stdin; as an argument it would
+// put the item's secrets into the process list and the command log.
+func (c *Client) writeObject(ctx context.Context, args []string, object VaultObject) (VaultObject, error) {
+	payload, err := json.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", args[1], err)
+	}
+	res, err := c.runVaultCommand(ctx, args, base64.StdEncoding.EncodeToString(payload))
+	if err != nil {
+		return nil, err
+	}
+	var written VaultObject
+	if err := json.Unmarshal([]byte(res.Stdout), &written); err != nil {
+		return nil, fmt.Errorf("failed to parse bw %s output: %w", args[0], err)
+	}
+	return written, nil
+}
+
+// DeleteObject moves an item to the trash, or removes it for good when
+// permanent is set. Folders are always removed.
+func (c *Client) DeleteObject(ctx context.Context, kind, id string, permanent bool) error {
+	args := []string{"delete", kind, id}
+	if permanent && kind == "item" {
+		args = append(args, "--permanent")
+	}
+	_, err := c.runVaultCommand(ctx, args, "")
+	return err
+}
+
+// runVaultCommand runs a vault command with the unlocked session. Errors
+// carry the sanitized stderr, stdout is only parsed, never logged.
+func (c *Client) runVaultCommand(ctx context.Context, args []string, stdin string) (bwExecResult, error) {
+	if c.sessionToken == "" && !c.vaultUnlocked {
+		return bwExecResult{}, fmt.Errorf("vault is not unlocked, please unlock first")
+	}
+	if c.sessionToken != "" {
+		args = append(args, "--session", c.sessionToken)
+	}
+	res, err := c.runBW(ctx, args, stdin, nil)
+	if err != nil {
+		command := strings.Join(args[:min(2, len(args))], " ")
+		if stderr := sanitizeBWOutput(res.Stderr); stderr != "" {
+			c.AddLog(fmt.Sprintf("bw %s stderr: %s", command, stderr))
+			return res, fmt.Errorf("%s failed (exit=%d): %s", command, res.ExitCode, stderr)
+		}
+		return res, fmt.Errorf("%s failed (exit=%d): %w", command, res.ExitCode, err)
+	}
+	return res, nil
+}
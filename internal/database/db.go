@@ -88,5 +88,6 @@ func autoMigrate() error {
 		&model.BackupTask{},
 		&model.BackupDestination{},
 		&model.BackupLog{},
+		&model.BlackoutWindow{},
 	)
 }
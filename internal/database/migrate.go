@@ -82,22 +82,13 @@ func migrateBackupDestinations() error {
 		dest := &destinations[i]
 		needsUpdate := false
 
-		fields := []struct {
-			name  string
-			value *string
-		}{
-			{"WebDAVPassword", &dest.WebDAVPassword},
-			{"S3AccessKey", &dest.S3AccessKey},
-			{"S3SecretKey", &dest.S3SecretKey},
-			{"EncryptionPassword", &dest.EncryptionPassword},
-		}
-		for _, field := range fields {
-			encrypted, changed, err := migrateSensitiveValue(*field.value)
+		for _, field := range dest.SensitiveFields() {
+			encrypted, changed, err := migrateSensitiveValue(*field.Value)
 			if err != nil {
-				return fmt.Errorf("failed to encrypt %s for destination %d: %w", field.name, dest.ID, err)
+				return fmt.Errorf("failed to encrypt %s for destination %d: %w", field.Name, dest.ID, err)
 			}
 			if changed {
-				*field.value = encrypted
+				*field.Value = encrypted
 				needsUpdate = true
 			}
 		}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// subscriberBuffer bounds the number of undelivered events per subscriber. A
+// subscriber that falls further behind is disconnected instead of blocking the
+// backup run; the client can reconnect and receive the history again.
+const subscriberBuffer = 256
+
+// Event types sent to live run subscribers.
+const (
+	TypeLog    = "log"
+	TypeStatus = "status"
+)
+
+// Event is one live update for a backup run.
+type Event struct {
+	Type   string          `json:"type"`
+	Entry  *model.LogEntry `json:"entry,omitempty"`
+	Status string          `json:"status,omitempty"`
+}
+
+type run struct {
+	history     []model.LogEntry
+	subscribers map[chan Event]struct{}
+}
+
+// Hub fans out execution logs of active backup runs to live subscribers such
+// as the SSE endpoint. Runs are keyed by their BackupLog ID and only exist
+// between Open and Close; finished runs are read from the database instead.
+type Hub struct {
+	mu   sync.Mutex
+	runs map[uint]*run
+}
+
+// NewHub 创建运行事件中心
+func NewHub() *Hub {
+	return &Hub{runs: make(map[uint]*run)}
+}
+
+// Open starts tracking a run. Publishing to a run that was not opened is a
+// no-op, so callers outside the scheduler never leak history.
+func (h *Hub) Open(logID uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.runs[logID]; !ok {
+		h.runs[logID] = &run{subscribers: make(map[chan Event]struct{})}
+	}
+}
+
+// Publish records an execution log entry and delivers it to subscribers.
+func (h *Hub) Publish(logID uint, entry model.LogEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.runs[logID]
+	if !ok {
+		return
+	}
+	r.history = append(r.history, entry)
+	event := Event{Type: TypeLog, Entry: &entry}
+	for ch := range r.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(r.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close sends the final run status to subscribers and stops tracking the run.
+func (h *Hub) Close(logID uint, status string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.runs[logID]
+	if !ok {
+		return
+	}
+	delete(h.runs, logID)
+	for ch := range r.subscribers {
+		select {
+		case ch <- Event{Type: TypeStatus, Status: status}:
+		default:
+		}
+		close(ch)
+	}
+}
+
+// Subscribe returns the entries published so far and a channel for further
+// events. The channel is closed after the final status event or when the
+// subscriber falls behind. ok is false when the run is not active.
+func (h *Hub) Subscribe(logID uint) (history []model.LogEntry, events <-chan Event, unsubscribe func(), ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.runs[logID]
+	if !ok {
+		return nil, nil, func() {}, false
+	}
+	ch := make(chan Event, subscriberBuffer)
+	r.subscribers[ch] = struct{}{}
+	history = append([]model.LogEntry(nil), r.history...)
+
+	unsubscribe = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if current, ok := h.runs[logID]; ok {
+			if _, subscribed := current.subscribers[ch]; subscribed {
+				delete(current.subscribers, ch)
+				close(ch)
+			}
+		}
+	}
+	return history, ch, unsubscribe, true
+}
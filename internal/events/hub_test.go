@@ -0,0 +1,47 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+func TestSubscribeReplaysHistoryAndFollowsRun(t *testing.T) {
+	hub := NewHub()
+	hub.Open(1)
+	hub.Publish(1, model.LogEntry{Message: "first"})
+
+	history, updates, unsubscribe, ok := hub.Subscribe(1)
+	if !ok {
+		t.Fatal("active run should be subscribable")
+	}
+	defer unsubscribe()
+	if len(history) != 1 || history[0].Message != "first" {
+		t.Fatalf("history = %#v, want the first entry", history)
+	}
+
+	hub.Publish(1, model.LogEntry{Message: "second"})
+	hub.Close(1, "success")
+
+	event := <-updates
+	if event.Type != TypeLog || event.Entry.Message != "second" {
+		t.Fatalf("first event = %#v, want second log entry", event)
+	}
+	event = <-updates
+	if event.Type != TypeStatus || event.Status != "success" {
+		t.Fatalf("second event = %#v, want final status", event)
+	}
+	if _, open := <-updates; open {
+		t.Fatal("updates should be closed after the final status")
+	}
+}
+
+func TestSubscribeRejectsInactiveRun(t *testing.T) {
+	hub := NewHub()
+	hub.Publish(2, model.LogEntry{Message: "ignored"})
+
+	if _, _, unsubscribe, ok := hub.Subscribe(2); ok {
+		unsubscribe()
+		t.Fatal("run that was never opened should not be subscribable")
+	}
+}
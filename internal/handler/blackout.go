@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// maxBlackoutWindows bounds the windows evaluated for each scheduled run.
+const maxBlackoutWindows = 50
+
+var errTooManyBlackoutWindows = errors.New("禁止窗口数量不能超过 50 个")
+
+// validateBlackoutWindows 校验并转换任务级禁止窗口
+func validateBlackoutWindows(requests []model.BlackoutWindowRequest) ([]model.BlackoutWindow, error) {
+	if len(requests) > maxBlackoutWindows {
+		return nil, errTooManyBlackoutWindows
+	}
+	windows := make([]model.BlackoutWindow, 0, len(requests))
+	for _, request := range requests {
+		if err := request.Validate(); err != nil {
+			return nil, err
+		}
+		windows = append(windows, request.ToBlackoutWindow())
+	}
+	return windows, nil
+}
+
+// GetBlackouts 获取全局禁止窗口
+func (a *API) GetBlackouts(c *gin.Context) {
+	if a.blackoutService == nil {
+		c.JSON(http.StatusOK, []model.BlackoutWindow{})
+		return
+	}
+	windows, err := a.blackoutService.GetGlobal()
+	if err != nil {
+		writeInternalError(c, "list blackout windows", err)
+		return
+	}
+	c.JSON(http.StatusOK, windows)
+}
+
+// CreateBlackout 创建全局禁止窗口
+func (a *API) CreateBlackout(c *gin.Context) {
+	if a.blackoutService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "blackout windows are not available"})
+		return
+	}
+	var request model.BlackoutWindowRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	if err := request.Validate(); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	existing, err := a.blackoutService.GetGlobal()
+	if err != nil {
+		writeInternalError(c, "list blackout windows", err)
+		return
+	}
+	if len(existing) >= maxBlackoutWindows {
+		writeBadRequest(c, errTooManyBlackoutWindows.Error())
+		return
+	}
+
+	window := request.ToBlackoutWindow()
+	if err := a.blackoutService.Create(&window); err != nil {
+		writeInternalError(c, "create blackout window", err)
+		return
+	}
+	c.JSON(http.StatusCreated, window)
+}
+
+// UpdateBlackout 更新全局禁止窗口
+func (a *API) UpdateBlackout(c *gin.Context) {
+	if a.blackoutService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "blackout windows are not available"})
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var request model.BlackoutWindowRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	if err := request.Validate(); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	window, err := a.blackoutService.GetByID(id)
+	if err != nil {
+		writeLookupError(c, "blackout window", "load blackout window", err)
+		return
+	}
+	request.ApplyTo(window)
+	if err := a.blackoutService.Update(id, window); err != nil {
+		writeLookupError(c, "blackout window", "update blackout window", err)
+		return
+	}
+	c.JSON(http.StatusOK, window)
+}
+
+// DeleteBlackout 删除全局禁止窗口
+func (a *API) DeleteBlackout(c *gin.Context) {
+	if a.blackoutService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "blackout windows are not available"})
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := a.blackoutService.Delete(id); err != nil {
+		writeLookupError(c, "blackout window", "delete blackout window", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Blackout window deleted"})
+}
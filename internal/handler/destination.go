@@ -60,6 +60,10 @@ func (a *API) CreateDestination(c *gin.Context) {
 	if destination.Type == "server" && !a.validateTargetServer(c, destination.TargetServerID, "target server") {
 		return
 	}
+	if err := validateStoredCredentials(destination); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
 	if err := a.destinationService.Create(destination); err != nil {
 		writeInternalError(c, "create destination", err)
 		return
@@ -104,6 +108,10 @@ func (a *API) UpdateDestination(c *gin.Context) {
 	if destination.Type == "server" && !a.validateTargetServer(c, destination.TargetServerID, "target server") {
 		return
 	}
+	if err := validateStoredCredentials(destination); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
 
 	if err := a.destinationService.Update(id, destination); err != nil {
 		writeLookupError(c, "destination", "update destination", err)
@@ -173,15 +181,16 @@ func (a *API) ToggleDestination(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Destination toggled"})
 }
 
-// TestDestination checks a WebDAV destination without creating a test task or
-// uploading a backup file. The provider error is intentionally returned so a
-// user can see the HTTP status/response detail in the toast.
+// TestDestination checks a destination without creating a test task or
+// uploading a backup file. Each check keeps the provider error so a user can
+// see the HTTP status/response detail of the step that failed.
 func (a *API) TestDestination(c *gin.Context) {
 	id, ok := parseID(c)
 	if !ok {
 		return
 	}
-	if err := a.destinationService.TestConnection(id); err != nil {
+	checks, err := a.destinationService.TestConnection(id)
+	if err != nil {
 		if isRecordNotFound(err) {
 			writeNotFound(c, "destination")
 			return
@@ -189,7 +198,14 @@ func (a *API) TestDestination(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "WebDAV connection succeeded"})
+	for _, check := range checks {
+		if !check.OK {
+			// 失败时仍返回全部检查项，前端据此展示已通过的步骤
+			c.JSON(http.StatusBadGateway, gin.H{"error": check.Name + " check failed: " + check.Detail, "success": false, "checks": checks})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "checks": checks})
 }
 
 func (a *API) validateTargetServer(c *gin.Context, serverID *uint, resource string) bool {
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/events"
 	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/provider"
 	"github.com/mingzaily/bitwarden-backup/internal/repository"
 	"github.com/mingzaily/bitwarden-backup/internal/service"
 	"gorm.io/gorm"
@@ -13,6 +17,9 @@ type TaskScheduler interface {
 	RemoveTask(taskID uint)
 	UpdateTask(task model.BackupTask) error
 	TriggerTask(taskID uint) bool
+	CancelTask(taskID uint) bool
+	CancelRun(logID uint) bool
+	NextRuns(taskID uint, count int) ([]time.Time, bool)
 }
 
 // ServerService describes the server operations needed by the HTTP layer.
@@ -25,6 +32,7 @@ type ServerService interface {
 	Update(id uint, server *model.ServerConfig) error
 	UpdateEnabled(id uint, enabled bool) error
 	Delete(id uint) error
+	ListOrganizations(id uint) ([]model.OrganizationResponse, error)
 }
 
 // DestinationService describes the destination operations needed by handlers.
@@ -36,11 +44,12 @@ type DestinationService interface {
 	UpdateEnabled(id uint, enabled bool) error
 	Delete(id uint) error
 	Toggle(id uint) error
-	TestConnection(id uint) error
+	TestConnection(id uint) ([]provider.ConnectionCheck, error)
 }
 
 // TaskService describes the task operations needed by handlers.
 type TaskService interface {
+	GetAll() ([]model.BackupTask, error)
 	GetByID(id uint) (*model.BackupTask, error)
 	GetPaginated(params model.PaginationParams) ([]model.BackupTask, int64, error)
 	CreateWithDestinations(task *model.BackupTask, destinationIDs []uint) error
@@ -51,10 +60,27 @@ type TaskService interface {
 
 // LogService describes the log operations needed by handlers.
 type LogService interface {
+	GetByID(id uint) (*model.BackupLog, error)
 	GetPaginated(params model.PaginationParams, taskID *uint) ([]model.LogResponse, int64, error)
 	DeleteByIDs(ids []uint) (int64, error)
 }
 
+// RunEventSource exposes live execution logs of active runs. ok is false when
+// the run has already finished and must be read from the log record.
+type RunEventSource interface {
+	SubscribeRun(logID uint) (history []model.LogEntry, events <-chan events.Event, unsubscribe func(), ok bool)
+}
+
+// BlackoutService describes the global blackout window operations. Task
+// specific windows are saved with the task itself.
+type BlackoutService interface {
+	GetGlobal() ([]model.BlackoutWindow, error)
+	GetByID(id uint) (*model.BlackoutWindow, error)
+	Create(window *model.BlackoutWindow) error
+	Update(id uint, window *model.BlackoutWindow) error
+	Delete(id uint) error
+}
+
 // OverviewService describes the aggregate data needed by the dashboard.
 type OverviewService interface {
 	Get() (model.OverviewResponse, error)
@@ -68,7 +94,9 @@ type API struct {
 	taskService        TaskService
 	logService         LogService
 	overviewService    OverviewService
+	blackoutService    BlackoutService
 	scheduler          TaskScheduler
+	runEvents          RunEventSource
 }
 
 // New constructs the HTTP API with the application's default services.
@@ -81,6 +109,7 @@ func New(db *gorm.DB) *API {
 		nil,
 	)
 	api.SetOverviewService(service.NewOverviewService(repository.NewOverviewRepository(db)))
+	api.SetBlackoutService(service.NewBlackoutService(repository.NewBlackoutRepository(db)))
 	return api
 }
 
@@ -109,9 +138,21 @@ func (a *API) SetScheduler(scheduler TaskScheduler) {
 	a.scheduler = scheduler
 }
 
+// SetRunEvents injects the live log source used by the run streaming
+// endpoint. Without it the endpoint replays the stored execution logs only.
+func (a *API) SetRunEvents(runEvents RunEventSource) {
+	a.runEvents = runEvents
+}
+
 // SetOverviewService injects the aggregate read service used by the home
 // dashboard. It remains a setter so existing handler tests and embedders that
 // use NewWithDependencies do not need to construct an overview dependency.
 func (a *API) SetOverviewService(overviewService OverviewService) {
 	a.overviewService = overviewService
 }
+
+// SetBlackoutService injects the global blackout window store. Like the
+// overview service it is optional for handler tests.
+func (a *API) SetBlackoutService(blackoutService BlackoutService) {
+	a.blackoutService = blackoutService
+}
@@ -69,3 +69,23 @@ func (a *API) DeleteLogs(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
 }
+
+// CancelLog stops the run that owns an active execution record. The record is
+// finalized by the scheduler as cancelled once the CLI process and logout
+// cleanup have finished, keeping the partial execution logs.
+func (a *API) CancelLog(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if a.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scheduler is unavailable"})
+		return
+	}
+
+	if !a.scheduler.CancelRun(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "run is not active"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Run cancellation requested"})
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingzaily/bitwarden-backup/internal/events"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// logStreamKeepAlive keeps idle SSE connections open through reverse proxies
+// while a run is blocked in a long CLI command.
+const logStreamKeepAlive = 15 * time.Second
+
+// StreamLog streams the execution logs of one run as Server-Sent Events. An
+// active run replays the entries recorded so far and then follows new ones
+// until its final status; a finished run replays the stored logs once.
+func (a *API) StreamLog(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var history []model.LogEntry
+	var updates <-chan events.Event
+	unsubscribe := func() {}
+	live := false
+	if a.runEvents != nil {
+		history, updates, unsubscribe, live = a.runEvents.SubscribeRun(id)
+	}
+	defer unsubscribe()
+
+	status := ""
+	if !live {
+		backupLog, err := a.logService.GetByID(id)
+		if err != nil {
+			writeLookupError(c, "log", "load log for streaming", err)
+			return
+		}
+		history = decodeExecutionLogs(backupLog.ExecutionLogs)
+		status = backupLog.Status
+	}
+
+	// The HTTP server applies a global write timeout suited to JSON APIs. A
+	// live stream legitimately outlives it, so clear the deadline per request.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for i := range history {
+		c.SSEvent(events.TypeLog, history[i])
+	}
+	if !live {
+		c.SSEvent(events.TypeStatus, gin.H{"status": status})
+		c.Writer.Flush()
+		return
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(logStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, open := <-updates:
+			if !open {
+				return
+			}
+			switch event.Type {
+			case events.TypeLog:
+				c.SSEvent(events.TypeLog, event.Entry)
+			case events.TypeStatus:
+				c.SSEvent(events.TypeStatus, gin.H{"status": event.Status})
+			}
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func decodeExecutionLogs(raw string) []model.LogEntry {
+	if raw == "" {
+		return nil
+	}
+	var entries []model.LogEntry
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil
+	}
+	return entries
+}
@@ -19,5 +19,12 @@ func (a *API) GetOverview(c *gin.Context) {
 		writeInternalError(c, "load overview", err)
 		return
 	}
+	if a.scheduler != nil {
+		for i := range overview.RecentTasks {
+			if runs, scheduled := a.scheduler.NextRuns(overview.RecentTasks[i].ID, 1); scheduled && len(runs) > 0 {
+				overview.RecentTasks[i].NextRunAt = &runs[0]
+			}
+		}
+	}
 	c.JSON(http.StatusOK, overview)
 }
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+const (
+	defaultSchedulePreviewCount = 10
+	maxSchedulePreviewCount     = 100
+)
+
+// GetTaskSchedule 返回任务接下来的触发时间，来自调度器中的实际条目
+func (a *API) GetTaskSchedule(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	count, ok := parsePreviewCount(c.Query("count"))
+	if !ok {
+		writeBadRequest(c, "count must be between 1 and 100")
+		return
+	}
+	if _, err := a.taskService.GetByID(id); err != nil {
+		writeLookupError(c, "task", "load task schedule", err)
+		return
+	}
+
+	preview := model.SchedulePreview{Timezone: serverTimezone(), NextRuns: []time.Time{}}
+	if a.scheduler != nil {
+		if runs, scheduled := a.scheduler.NextRuns(id, count); scheduled {
+			preview.Scheduled = true
+			preview.NextRuns = runs
+		}
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// PreviewCron 无状态地预览 Cron 表达式接下来的触发时间
+func (a *API) PreviewCron(c *gin.Context) {
+	var req model.CronPreviewRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	expression := strings.TrimSpace(req.CronExpression)
+	if expression == "" {
+		writeBadRequest(c, "请输入 Cron 表达式")
+		return
+	}
+	if err := validateCronExpression(expression); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	timezone := strings.TrimSpace(req.Timezone)
+	if err := model.ValidateTimezone(timezone); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	count := req.Count
+	if count == 0 {
+		count = defaultSchedulePreviewCount
+	}
+	if count < 1 || count > maxSchedulePreviewCount {
+		writeBadRequest(c, "count must be between 1 and 100")
+		return
+	}
+
+	runs, err := nextFireTimes(expression, timezone, time.Now(), count)
+	if err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, model.SchedulePreview{Timezone: serverTimezone(), Scheduled: true, NextRuns: runs})
+}
+
+// nextFireTimes mirrors the scheduler's spec construction: 5-field
+// expressions get a leading seconds field and a task timezone becomes a
+// CRON_TZ prefix. Results are converted to the server timezone.
+func nextFireTimes(expression, timezone string, from time.Time, count int) ([]time.Time, error) {
+	if len(strings.Fields(expression)) == 5 {
+		expression = "0 " + expression
+	}
+	if timezone != "" {
+		expression = "CRON_TZ=" + timezone + " " + expression
+	}
+	schedule, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]time.Time, 0, count)
+	next := from
+	for len(runs) < count {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next.In(time.Local))
+	}
+	return runs, nil
+}
+
+func parsePreviewCount(raw string) (int, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultSchedulePreviewCount, true
+	}
+	count, err := strconv.Atoi(raw)
+	if err != nil || count < 1 || count > maxSchedulePreviewCount {
+		return 0, false
+	}
+	return count, true
+}
+
+// serverTimezone names the timezone the preview times are expressed in.
+func serverTimezone() string {
+	if name := time.Local.String(); name != "Local" {
+		return name
+	}
+	name, _ := time.Now().Zone()
+	return name
+}
@@ -163,6 +163,28 @@ func (a *API) SetServerEnabled(c *gin.Context) {
 	c.JSON(http.StatusOK, server.ToResponse())
 }
 
+// GetServerOrganizations lists the organizations and collections of the
+// server's account, for picking the import target of a server destination.
+func (a *API) GetServerOrganizations(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	organizations, err := a.serverService.ListOrganizations(id)
+	if err != nil {
+		if isRecordNotFound(err) {
+			writeNotFound(c, "server")
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if organizations == nil {
+		organizations = []model.OrganizationResponse{}
+	}
+	c.JSON(http.StatusOK, organizations)
+}
+
 // DeleteServer 删除服务器配置
 func (a *API) DeleteServer(c *gin.Context) {
 	id, ok := parseID(c)
@@ -2,11 +2,13 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/provider"
 )
 
 // validateCronExpression 校验 Cron 表达式格式
@@ -36,6 +38,132 @@ func (a *API) validateSourceDestination(sourceServerID uint, destinationIDs []ui
 	return nil
 }
 
+// validateReplication 校验复制任务的来源和落点：来源需要能列出并读回备份文件，
+// 落点需要能列出已有文件以跳过已复制的备份。返回规范化后的来源目标 ID。
+func (a *API) validateReplication(sourceDestinationID *uint, destinationIDs []uint) (*uint, error) {
+	if sourceDestinationID == nil || *sourceDestinationID == 0 {
+		return nil, errors.New("请选择复制来源目标")
+	}
+	if len(destinationIDs) == 0 {
+		return nil, errors.New("请至少选择一个复制目标")
+	}
+	if len(destinationIDs) > 100 {
+		return nil, errors.New("复制目标数量不能超过 100 个")
+	}
+	source, err := a.destinationService.GetByID(*sourceDestinationID)
+	if err != nil {
+		return nil, errors.New("复制来源目标不存在")
+	}
+	sourceProvider, err := provider.GetRegistry().Get(source.Type)
+	if err != nil {
+		return nil, errors.New("复制来源目标类型不支持")
+	}
+	_, canList := sourceProvider.(provider.ArtifactLister)
+	_, canDownload := sourceProvider.(provider.ArtifactDownloader)
+	if !canList || !canDownload {
+		return nil, errors.New("复制来源目标不支持列出和下载备份文件")
+	}
+
+	seen := make(map[uint]struct{}, len(destinationIDs))
+	for _, destID := range destinationIDs {
+		if _, ok := seen[destID]; ok {
+			return nil, errors.New("复制目标不能重复")
+		}
+		seen[destID] = struct{}{}
+		if destID == source.ID {
+			return nil, errors.New("复制目标不能与来源目标相同")
+		}
+		dest, err := a.destinationService.GetByID(destID)
+		if err != nil {
+			return nil, errors.New("复制目标不存在")
+		}
+		p, err := provider.GetRegistry().Get(dest.Type)
+		if err != nil {
+			return nil, errors.New("复制目标类型不支持")
+		}
+		if _, ok := p.(provider.ArtifactLister); !ok {
+			return nil, fmt.Errorf("复制目标 %s 不支持列出备份文件", dest.Name)
+		}
+		// Artifacts are copied byte for byte, a plain export stays plain.
+		if dest.Encrypted && !source.Encrypted {
+			return nil, fmt.Errorf("加密目标 %s 不能接收来源目标的明文备份", dest.Name)
+		}
+	}
+	id := source.ID
+	return &id, nil
+}
+
+// validateTaskDependency 校验上游任务与触发条件，并拒绝形成环的依赖。
+// taskID 为 0 表示新建任务，新任务不会被任何任务依赖，因此不会成环。
+func (a *API) validateTaskDependency(taskID uint, upstreamID *uint, triggerOn string) error {
+	if upstreamID == nil {
+		return nil
+	}
+	if err := model.ValidateTriggerOn(triggerOn); err != nil {
+		return err
+	}
+	if taskID != 0 && *upstreamID == taskID {
+		return errors.New("任务不能依赖自身")
+	}
+	tasks, err := a.taskService.GetAll()
+	if err != nil {
+		return errors.New("加载任务依赖失败")
+	}
+	return detectDependencyCycle(taskID, *upstreamID, tasks)
+}
+
+// detectDependencyCycle walks the upstream chain starting at upstreamID. A
+// cycle exists when the chain reaches taskID again.
+func detectDependencyCycle(taskID, upstreamID uint, tasks []model.BackupTask) error {
+	upstreams := make(map[uint]*uint, len(tasks))
+	for i := range tasks {
+		upstreams[tasks[i].ID] = tasks[i].TriggerTaskID
+	}
+	if _, ok := upstreams[upstreamID]; !ok {
+		return errors.New("上游任务不存在")
+	}
+
+	visited := make(map[uint]bool)
+	for current := upstreamID; ; {
+		if current == taskID {
+			return errors.New("任务依赖形成循环")
+		}
+		if visited[current] {
+			// A pre-existing loop that does not pass through taskID is not
+			// introduced by this change; stop walking it.
+			return nil
+		}
+		visited[current] = true
+		next := upstreams[current]
+		if next == nil {
+			return nil
+		}
+		current = *next
+	}
+}
+
+// normalizeTaskDependency treats a zero upstream ID as "no dependency" and
+// defaults the trigger condition to success.
+func normalizeTaskDependency(upstreamID *uint, triggerOn string) (*uint, string) {
+	if upstreamID == nil || *upstreamID == 0 {
+		return nil, ""
+	}
+	triggerOn = strings.TrimSpace(triggerOn)
+	if triggerOn == "" {
+		triggerOn = model.TriggerOnSuccess
+	}
+	id := *upstreamID
+	return &id, triggerOn
+}
+
+// intValue 返回可选整数字段的值，缺省时使用 fallback
+func intValue(value *int, fallback int) int {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
 // GetTasks 获取所有任务（支持分页）
 func (a *API) GetTasks(c *gin.Context) {
 	var params model.PaginationParams
@@ -93,12 +221,29 @@ func (a *API) CreateTask(c *gin.Context) {
 		writeBadRequest(c, err.Error())
 		return
 	}
-	if req.SourceServerID == 0 {
-		writeBadRequest(c, "请选择源服务器")
-		return
-	}
-	if len(req.DestinationIDs) == 0 {
-		writeBadRequest(c, "请至少选择一个备份目标")
+
+	taskType := model.TaskTypeOrDefault(strings.TrimSpace(req.Type))
+	var sourceDestinationID *uint
+	switch taskType {
+	case model.TaskTypeBackup:
+		if req.SourceServerID == 0 {
+			writeBadRequest(c, "请选择源服务器")
+			return
+		}
+		if len(req.DestinationIDs) == 0 {
+			writeBadRequest(c, "请至少选择一个备份目标")
+			return
+		}
+	case model.TaskTypeReplication:
+		id, err := a.validateReplication(req.SourceDestinationID, req.DestinationIDs)
+		if err != nil {
+			writeBadRequest(c, err.Error())
+			return
+		}
+		sourceDestinationID = id
+		req.SourceServerID = 0
+	default:
+		writeBadRequest(c, "任务类型必须是 backup 或 replication")
 		return
 	}
 
@@ -109,17 +254,51 @@ func (a *API) CreateTask(c *gin.Context) {
 	}
 
 	// 校验源和目标不能相同
-	if err := a.validateSourceDestination(req.SourceServerID, req.DestinationIDs); err != nil {
+	if taskType == model.TaskTypeBackup {
+		if err := a.validateSourceDestination(req.SourceServerID, req.DestinationIDs); err != nil {
+			writeBadRequest(c, err.Error())
+			return
+		}
+	}
+
+	timezone := ""
+	if req.Timezone != nil {
+		timezone = strings.TrimSpace(*req.Timezone)
+	}
+	if err := model.ValidateTimezone(timezone); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	windows, err := validateBlackoutWindows(req.BlackoutWindows)
+	if err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	jitterMinutes, staggerMinutes := intValue(req.JitterMinutes, 0), intValue(req.StaggerMinutes, 0)
+	if err := model.ValidateScheduleSpread(jitterMinutes, staggerMinutes); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	triggerTaskID, triggerOn := normalizeTaskDependency(req.TriggerTaskID, req.TriggerOn)
+	if err := a.validateTaskDependency(0, triggerTaskID, triggerOn); err != nil {
 		writeBadRequest(c, err.Error())
 		return
 	}
 
 	task := &model.BackupTask{
-		Name:             req.Name,
-		SourceServerID:   req.SourceServerID,
-		CronExpression:   req.CronExpression,
-		FilenameTemplate: model.NormalizeFilenameTemplate(req.FilenameTemplate),
-		Enabled:          true,
+		Name:                req.Name,
+		Type:                taskType,
+		SourceServerID:      req.SourceServerID,
+		SourceDestinationID: sourceDestinationID,
+		CronExpression:      req.CronExpression,
+		FilenameTemplate:    model.NormalizeFilenameTemplate(req.FilenameTemplate),
+		Timezone:            timezone,
+		JitterMinutes:       jitterMinutes,
+		StaggerMinutes:      staggerMinutes,
+		TriggerTaskID:       triggerTaskID,
+		TriggerOn:           triggerOn,
+		Enabled:             true,
+		BlackoutWindows:     windows,
 	}
 
 	if err := a.taskService.CreateWithDestinations(task, req.DestinationIDs); err != nil {
@@ -169,30 +348,66 @@ func (a *API) UpdateTask(c *gin.Context) {
 		writeBadRequest(c, err.Error())
 		return
 	}
-	if req.SourceServerID == 0 || len(req.DestinationIDs) == 0 {
-		writeBadRequest(c, "源服务器和备份目标不能为空")
+	existing, err := a.taskService.GetByID(id)
+	if err != nil {
+		writeLookupError(c, "task", "load task for update", err)
+		return
+	}
+	taskType := model.TaskTypeOrDefault(strings.TrimSpace(req.Type))
+	if strings.TrimSpace(req.Type) == "" {
+		taskType = model.TaskTypeOrDefault(existing.Type)
+	}
+	var sourceDestinationID *uint
+	switch taskType {
+	case model.TaskTypeBackup:
+		if req.SourceServerID == 0 || len(req.DestinationIDs) == 0 {
+			writeBadRequest(c, "源服务器和备份目标不能为空")
+			return
+		}
+	case model.TaskTypeReplication:
+		if req.SourceDestinationID == nil {
+			req.SourceDestinationID = existing.SourceDestinationID
+		}
+		sourceDestinationID, err = a.validateReplication(req.SourceDestinationID, req.DestinationIDs)
+		if err != nil {
+			writeBadRequest(c, err.Error())
+			return
+		}
+		req.SourceServerID = 0
+	default:
+		writeBadRequest(c, "任务类型必须是 backup 或 replication")
 		return
 	}
 	if err := validateCronExpression(req.CronExpression); err != nil {
 		writeBadRequest(c, err.Error())
 		return
 	}
-	if err := a.validateSourceDestination(req.SourceServerID, req.DestinationIDs); err != nil {
+	if taskType == model.TaskTypeBackup {
+		if err := a.validateSourceDestination(req.SourceServerID, req.DestinationIDs); err != nil {
+			writeBadRequest(c, err.Error())
+			return
+		}
+	}
+	if req.Timezone != nil {
+		if err := model.ValidateTimezone(*req.Timezone); err != nil {
+			writeBadRequest(c, err.Error())
+			return
+		}
+	}
+	windows, err := validateBlackoutWindows(req.BlackoutWindows)
+	if err != nil {
 		writeBadRequest(c, err.Error())
 		return
 	}
 
 	task := &model.BackupTask{
-		Name:             req.Name,
-		SourceServerID:   req.SourceServerID,
-		CronExpression:   req.CronExpression,
-		FilenameTemplate: model.NormalizeFilenameTemplate(req.FilenameTemplate),
-	}
-
-	existing, err := a.taskService.GetByID(id)
-	if err != nil {
-		writeLookupError(c, "task", "load task for update", err)
-		return
+		Name:                req.Name,
+		Type:                taskType,
+		SourceServerID:      req.SourceServerID,
+		SourceDestinationID: sourceDestinationID,
+		CronExpression:      req.CronExpression,
+		FilenameTemplate:    model.NormalizeFilenameTemplate(req.FilenameTemplate),
+		BlackoutWindows:     windows,
 	}
 	if req.Enabled != nil {
 		task.Enabled = *req.Enabled
@@ -204,6 +419,31 @@ func (a *API) UpdateTask(c *gin.Context) {
 	if strings.TrimSpace(req.FilenameTemplate) == "" {
 		task.FilenameTemplate = model.NormalizeFilenameTemplate(existing.FilenameTemplate)
 	}
+	// The schedule settings follow the same rule: a missing field keeps the
+	// stored value, while an explicit empty value clears it.
+	if req.Timezone != nil {
+		task.Timezone = strings.TrimSpace(*req.Timezone)
+	} else {
+		task.Timezone = existing.Timezone
+	}
+	if req.BlackoutWindows == nil {
+		task.BlackoutWindows = existing.BlackoutWindows
+	}
+	task.JitterMinutes = intValue(req.JitterMinutes, existing.JitterMinutes)
+	task.StaggerMinutes = intValue(req.StaggerMinutes, existing.StaggerMinutes)
+	if err := model.ValidateScheduleSpread(task.JitterMinutes, task.StaggerMinutes); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	if req.TriggerTaskID != nil {
+		task.TriggerTaskID, task.TriggerOn = normalizeTaskDependency(req.TriggerTaskID, req.TriggerOn)
+	} else {
+		task.TriggerTaskID, task.TriggerOn = existing.TriggerTaskID, existing.TriggerOn
+	}
+	if err := a.validateTaskDependency(id, task.TriggerTaskID, task.TriggerOn); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
 
 	if err := a.taskService.UpdateWithDestinations(id, task, req.DestinationIDs); err != nil {
 		writeLookupError(c, "task", "update task", err)
@@ -35,3 +35,21 @@ func (a *API) ExecuteTask(c *gin.Context) {
 
 	c.JSON(http.StatusAccepted, gin.H{"message": "Task execution queued"})
 }
+
+// CancelTaskQueue 取消排队中或正在执行的任务
+func (a *API) CancelTaskQueue(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if a.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scheduler is unavailable"})
+		return
+	}
+
+	if !a.scheduler.CancelTask(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "task is not queued or running"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Task cancellation requested"})
+}
@@ -1,19 +1,47 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net/mail"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 
 	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/provider"
 	"github.com/mingzaily/bitwarden-backup/internal/safety"
+	"github.com/mingzaily/bitwarden-backup/internal/webdav"
 	"github.com/robfig/cron/v3"
+	"golang.org/x/crypto/ssh"
 )
 
 var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
 
+var (
+	azureAccountPattern   = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	azureContainerPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+	gcsBucketPattern      = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,61}[a-z0-9]$`)
+	rcloneRemotePattern   = regexp.MustCompile(`^[\w.+@-]([\w.+@ -]*[\w.+@-])?$`)
+	gitSCPURLPattern      = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^\s]+$`)
+	gitBranchPattern      = regexp.MustCompile(`^[A-Za-z0-9._][A-Za-z0-9._/-]*$`)
+	s3StorageClassPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,39}$`)
+	s3RoleARNPattern      = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]{1,512}$`)
+	s3ExternalIDPattern   = regexp.MustCompile(`^[\w+=,.@:/-]{2,}$`)
+	s3SessionNamePattern  = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+	bitwardenIDPattern    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	localGroupPattern     = regexp.MustCompile(`^([0-9]{1,10}|[A-Za-z0-9_][A-Za-z0-9_.-]{0,31})$`)
+)
+
+// cronParser accepts the 6-field specs the scheduler registers, including
+// the CRON_TZ= prefix. Descriptors such as @every are left out on purpose.
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
 func validateServerRequest(req model.ServerRequest, requireSecrets bool) error {
 	if err := safety.ValidateName(req.Name, "name", 100); err != nil {
 		return err
@@ -59,6 +87,14 @@ func validateDestination(dest model.DestinationRequest) error {
 		if err := validateText(dest.LocalPath, "local_path", 255, false); err != nil {
 			return err
 		}
+		if dest.LocalFileMode != "" {
+			if _, err := provider.ParseLocalFileMode(dest.LocalFileMode); err != nil {
+				return fmt.Errorf("local_file_mode %w", err)
+			}
+		}
+		if dest.LocalGroup != "" && !localGroupPattern.MatchString(dest.LocalGroup) {
+			return fmt.Errorf("local_group must be a group name or numeric GID")
+		}
 	case "webdav":
 		if err := safety.ValidateURL(dest.WebDAVURL, "webdav_url", true); err != nil {
 			return err
@@ -72,6 +108,12 @@ func validateDestination(dest model.DestinationRequest) error {
 		if err := validateText(dest.WebDAVPassword, "webdav_password", 500, false); err != nil {
 			return err
 		}
+		if dest.WebDAVTimeout < 0 || dest.WebDAVTimeout > 3600 {
+			return fmt.Errorf("webdav_timeout must be between 0 and 3600 seconds")
+		}
+		if err := validateWebDAVAuth(dest); err != nil {
+			return err
+		}
 	case "s3":
 		if dest.S3Endpoint != "" {
 			if err := safety.ValidateURL(dest.S3Endpoint, "s3_endpoint", true); err != nil {
@@ -93,10 +135,67 @@ func validateDestination(dest model.DestinationRequest) error {
 		if err := validateText(dest.S3SecretKey, "s3_secret_key", 500, false); err != nil {
 			return err
 		}
+		if err := validateS3Credentials(dest); err != nil {
+			return err
+		}
+		if err := validateS3Options(dest); err != nil {
+			return err
+		}
+	case "sftp":
+		if err := validateSFTPDestination(dest); err != nil {
+			return err
+		}
+	case "ftp":
+		if err := validateFTPDestination(dest); err != nil {
+			return err
+		}
+	case "smb":
+		if err := validateSMBDestination(dest); err != nil {
+			return err
+		}
+	case "azblob":
+		if err := validateAzureBlobDestination(dest); err != nil {
+			return err
+		}
+	case "gcs":
+		if err := validateGCSDestination(dest); err != nil {
+			return err
+		}
+	case "rclone":
+		if err := validateRcloneDestination(dest); err != nil {
+			return err
+		}
+	case "git":
+		if err := validateGitDestination(dest); err != nil {
+			return err
+		}
+	case "email":
+		// A mailbox keeps every copy outside our control and mail often
+		// crosses servers we do not choose, so only an encrypted export may
+		// be sent.
+		if !dest.Encrypted {
+			return fmt.Errorf("email destinations require an encrypted export")
+		}
+		if err := validateEmailDestination(dest); err != nil {
+			return err
+		}
 	case "server":
 		if dest.TargetServerID == nil || *dest.TargetServerID == 0 {
 			return fmt.Errorf("target_server_id is required")
 		}
+		mode := model.ServerSyncModeOrDefault(dest.ServerSyncMode)
+		switch mode {
+		case model.ServerSyncAppend, model.ServerSyncMirror:
+		case model.ServerSyncPurge:
+			if !dest.ServerPurgeConfirmed {
+				return fmt.Errorf("server_purge_confirmed is required to purge the target vault")
+			}
+		default:
+			return fmt.Errorf("server_sync_mode must be append, purge or mirror")
+		}
+		if err := validateTargetOrganization(dest, mode); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unsupported destination type")
 	}
@@ -112,6 +211,32 @@ func validateDestination(dest model.DestinationRequest) error {
 	return nil
 }
 
+// validateTargetOrganization rejects the targets the Bitwarden CLI cannot
+// write: bw import only takes an organization, and bw create needs a
+// collection for every organization item.
+func validateTargetOrganization(dest model.DestinationRequest, mode string) error {
+	if dest.TargetOrganizationID == "" {
+		if dest.TargetCollectionID != "" {
+			return fmt.Errorf("target_collection_id requires target_organization_id")
+		}
+		return nil
+	}
+	if !bitwardenIDPattern.MatchString(dest.TargetOrganizationID) {
+		return fmt.Errorf("target_organization_id must be a Bitwarden organization id")
+	}
+	if dest.TargetCollectionID != "" && !bitwardenIDPattern.MatchString(dest.TargetCollectionID) {
+		return fmt.Errorf("target_collection_id must be a Bitwarden collection id")
+	}
+	if mode == model.ServerSyncMirror {
+		if dest.TargetCollectionID == "" {
+			return fmt.Errorf("mirror sync into an organization requires target_collection_id")
+		}
+	} else if dest.TargetCollectionID != "" {
+		return fmt.Errorf("bw import cannot target a collection; use mirror sync or leave target_collection_id empty")
+	}
+	return nil
+}
+
 func validateText(value, field string, max int, required bool) error {
 	if required && strings.TrimSpace(value) == "" {
 		return fmt.Errorf("%s is required", field)
@@ -127,6 +252,587 @@ func validateText(value, field string, max int, required bool) error {
 	return nil
 }
 
+// validateWebDAVAuth checks the auth mode and the PEM material of the TLS
+// settings. The client key may be omitted on update, so the pair is checked
+// again on the merged destination.
+func validateWebDAVAuth(dest model.DestinationRequest) error {
+	switch model.WebDAVAuthModeOrDefault(dest.WebDAVAuthMode) {
+	case "basic", "digest", "none":
+		if dest.WebDAVBearerToken != "" {
+			return fmt.Errorf("webdav_bearer_token requires bearer auth")
+		}
+	case "bearer":
+		if err := validateText(dest.WebDAVBearerToken, "webdav_bearer_token", 4096, false); err != nil {
+			return err
+		}
+		if strings.ContainsAny(dest.WebDAVBearerToken, " \t") {
+			return fmt.Errorf("webdav_bearer_token must not contain spaces")
+		}
+	default:
+		return fmt.Errorf("webdav_auth_mode must be basic, digest, bearer or none")
+	}
+	if dest.WebDAVClientCert == "" && dest.WebDAVClientKey == "" && dest.WebDAVCACert == "" {
+		return nil
+	}
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(dest.WebDAVURL)), "https://") {
+		return fmt.Errorf("webdav client certificates and CA bundles require an https webdav_url")
+	}
+	if dest.WebDAVClientKey != "" && dest.WebDAVClientCert == "" {
+		return fmt.Errorf("webdav_client_key requires webdav_client_cert")
+	}
+	if dest.WebDAVCACert != "" {
+		if _, err := webdav.NewTLSConfig("", "", dest.WebDAVCACert); err != nil {
+			return fmt.Errorf("webdav_ca_cert is invalid: %w", err)
+		}
+	}
+	if dest.WebDAVClientCert != "" && dest.WebDAVClientKey != "" {
+		if _, err := webdav.NewTLSConfig(dest.WebDAVClientCert, dest.WebDAVClientKey, ""); err != nil {
+			return fmt.Errorf("webdav_client_cert is invalid: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateS3Credentials checks the credential mode and the role settings it
+// needs. Secrets are checked again on the merged destination.
+func validateS3Credentials(dest model.DestinationRequest) error {
+	mode := model.S3CredentialModeOrDefault(dest.S3CredentialMode)
+	switch mode {
+	case "static", "default", "assume_role", "web_identity":
+	default:
+		return fmt.Errorf("s3_credential_mode must be static, default, assume_role or web_identity")
+	}
+	if !model.S3UsesStaticKeys(mode) && (dest.S3AccessKey != "" || dest.S3SecretKey != "") {
+		return fmt.Errorf("s3_access_key and s3_secret_key are not used with %s credentials", mode)
+	}
+	usesRole := mode == "assume_role" || mode == "web_identity"
+	if !usesRole {
+		if dest.S3RoleARN != "" || dest.S3ExternalID != "" || dest.S3RoleSessionName != "" {
+			return fmt.Errorf("s3_role_arn requires assume_role or web_identity")
+		}
+	} else if !s3RoleARNPattern.MatchString(dest.S3RoleARN) {
+		return fmt.Errorf("s3_role_arn must be an IAM role ARN")
+	}
+	// STS only accepts an external ID on AssumeRole.
+	if dest.S3ExternalID != "" {
+		if mode != "assume_role" {
+			return fmt.Errorf("s3_external_id requires assume_role")
+		}
+		if len(dest.S3ExternalID) > 1224 || !s3ExternalIDPattern.MatchString(dest.S3ExternalID) {
+			return fmt.Errorf("s3_external_id is invalid")
+		}
+	}
+	if dest.S3RoleSessionName != "" && !s3SessionNamePattern.MatchString(dest.S3RoleSessionName) {
+		return fmt.Errorf("s3_role_session_name is invalid")
+	}
+	if dest.S3WebIdentityTokenFile != "" {
+		if mode != "web_identity" {
+			return fmt.Errorf("s3_web_identity_token_file requires web_identity")
+		}
+		if err := validateText(dest.S3WebIdentityTokenFile, "s3_web_identity_token_file", 255, false); err != nil {
+			return err
+		}
+		if !filepath.IsAbs(dest.S3WebIdentityTokenFile) {
+			return fmt.Errorf("s3_web_identity_token_file must be an absolute path")
+		}
+	}
+	return nil
+}
+
+// validateS3Options checks the upload options. Storage classes are not limited
+// to the AWS list because S3-compatible services define their own.
+func validateS3Options(dest model.DestinationRequest) error {
+	switch dest.S3AddressingStyle {
+	case "", "path", "virtual":
+	default:
+		return fmt.Errorf("s3_addressing_style must be path or virtual")
+	}
+	if dest.S3StorageClass != "" && !s3StorageClassPattern.MatchString(dest.S3StorageClass) {
+		return fmt.Errorf("s3_storage_class is invalid")
+	}
+	switch dest.S3Encryption {
+	case "", "sse-s3", "sse-kms", "sse-c":
+	default:
+		return fmt.Errorf("s3_encryption must be sse-s3, sse-kms or sse-c")
+	}
+	if dest.S3KMSKeyID != "" && dest.S3Encryption != "sse-kms" {
+		return fmt.Errorf("s3_kms_key_id requires sse-kms")
+	}
+	if err := validateText(dest.S3KMSKeyID, "s3_kms_key_id", 2048, false); err != nil {
+		return err
+	}
+	if dest.S3SSECustomerKey != "" {
+		if dest.S3Encryption != "sse-c" {
+			return fmt.Errorf("s3_sse_customer_key requires sse-c")
+		}
+		if key, err := base64.StdEncoding.DecodeString(dest.S3SSECustomerKey); err != nil || len(key) != 32 {
+			return fmt.Errorf("s3_sse_customer_key must be a base64 encoded 256-bit key")
+		}
+	}
+	switch dest.S3ObjectLockMode {
+	case "":
+		if dest.S3ObjectLockDays != 0 {
+			return fmt.Errorf("s3_object_lock_days requires s3_object_lock_mode")
+		}
+	case "GOVERNANCE", "COMPLIANCE":
+		if dest.S3ObjectLockDays < 1 || dest.S3ObjectLockDays > 36500 {
+			return fmt.Errorf("s3_object_lock_days must be between 1 and 36500")
+		}
+	default:
+		return fmt.Errorf("s3_object_lock_mode must be GOVERNANCE or COMPLIANCE")
+	}
+	switch dest.S3ChecksumAlgorithm {
+	case "", "CRC32", "CRC32C", "CRC64NVME", "SHA1", "SHA256":
+	default:
+		return fmt.Errorf("s3_checksum_algorithm must be CRC32, CRC32C, CRC64NVME, SHA1 or SHA256")
+	}
+	return nil
+}
+
+func validateSFTPDestination(dest model.DestinationRequest) error {
+	host := strings.TrimSpace(dest.SFTPHost)
+	if host == "" {
+		return fmt.Errorf("sftp_host is required")
+	}
+	if err := validateText(host, "sftp_host", 255, true); err != nil {
+		return err
+	}
+	if strings.ContainsAny(host, "/@ ") {
+		return fmt.Errorf("sftp_host must be a host name or IP address")
+	}
+	if dest.SFTPPort < 0 || dest.SFTPPort > 65535 {
+		return fmt.Errorf("sftp_port is invalid")
+	}
+	if err := validateText(dest.SFTPUsername, "sftp_username", 100, true); err != nil {
+		return err
+	}
+	if strings.TrimSpace(dest.SFTPUsername) == "" {
+		return fmt.Errorf("sftp_username is required")
+	}
+	if err := safety.ValidateRemotePath(dest.SFTPPath, "sftp_path"); err != nil {
+		return err
+	}
+	if err := validateText(dest.SFTPPassword, "sftp_password", 500, false); err != nil {
+		return err
+	}
+	if err := validateText(dest.SFTPKeyPassphrase, "sftp_key_passphrase", 500, false); err != nil {
+		return err
+	}
+	if dest.SFTPPassword != "" && dest.SFTPPrivateKey != "" {
+		return fmt.Errorf("use either sftp_password or sftp_private_key, not both")
+	}
+	if len(dest.SFTPPrivateKey) > 16384 {
+		return fmt.Errorf("sftp_private_key is too long")
+	}
+	if dest.SFTPPrivateKey != "" {
+		var err error
+		if dest.SFTPKeyPassphrase != "" {
+			_, err = ssh.ParsePrivateKeyWithPassphrase([]byte(dest.SFTPPrivateKey), []byte(dest.SFTPKeyPassphrase))
+		} else {
+			_, err = ssh.ParsePrivateKey([]byte(dest.SFTPPrivateKey))
+		}
+		if err != nil {
+			return fmt.Errorf("sftp_private_key is invalid or the passphrase is wrong")
+		}
+	}
+	// A pinned host key is mandatory: accepting any key would let an
+	// attacker on the path collect the vault export.
+	hostKey := strings.TrimSpace(dest.SFTPHostKey)
+	if hostKey == "" {
+		return fmt.Errorf("sftp_host_key is required")
+	}
+	if len(hostKey) > 1000 {
+		return fmt.Errorf("sftp_host_key is too long")
+	}
+	if !strings.HasPrefix(hostKey, "SHA256:") {
+		if _, err := provider.ParseSFTPHostKey(hostKey); err != nil {
+			return fmt.Errorf("sftp_host_key must be a public key or a SHA256 fingerprint")
+		}
+	}
+	return nil
+}
+
+func validateFTPDestination(dest model.DestinationRequest) error {
+	host := strings.TrimSpace(dest.FTPHost)
+	if err := validateText(host, "ftp_host", 255, true); err != nil {
+		return err
+	}
+	if strings.ContainsAny(host, "/@ ") {
+		return fmt.Errorf("ftp_host must be a host name or IP address")
+	}
+	if dest.FTPPort < 0 || dest.FTPPort > 65535 {
+		return fmt.Errorf("ftp_port is invalid")
+	}
+	if err := validateText(dest.FTPUsername, "ftp_username", 100, true); err != nil {
+		return err
+	}
+	if err := validateText(dest.FTPPassword, "ftp_password", 500, false); err != nil {
+		return err
+	}
+	if err := safety.ValidateRemotePath(dest.FTPPath, "ftp_path"); err != nil {
+		return err
+	}
+	switch dest.FTPTLSMode {
+	case "", "none":
+		// Plain FTP carries the login and the file in clear text, so only an
+		// encrypted export may travel over it.
+		if !dest.Encrypted {
+			return fmt.Errorf("plain FTP requires an encrypted export; enable encryption or use FTPS")
+		}
+		if dest.FTPTLSFingerprint != "" {
+			return fmt.Errorf("ftp_tls_fingerprint requires FTPS")
+		}
+	case "explicit", "implicit":
+	default:
+		return fmt.Errorf("ftp_tls_mode must be none, explicit or implicit")
+	}
+	if dest.FTPTLSFingerprint != "" {
+		fingerprint := provider.NormalizeCertificateFingerprint(dest.FTPTLSFingerprint)
+		if _, err := hex.DecodeString(fingerprint); err != nil || len(fingerprint) != sha256.Size*2 {
+			return fmt.Errorf("ftp_tls_fingerprint must be a SHA-256 certificate fingerprint")
+		}
+	}
+	return nil
+}
+
+func validateSMBDestination(dest model.DestinationRequest) error {
+	host := strings.TrimSpace(dest.SMBHost)
+	if err := validateText(host, "smb_host", 255, true); err != nil {
+		return err
+	}
+	if strings.ContainsAny(host, "/\\@ ") {
+		return fmt.Errorf("smb_host must be a host name or IP address")
+	}
+	if dest.SMBPort < 0 || dest.SMBPort > 65535 {
+		return fmt.Errorf("smb_port is invalid")
+	}
+	if err := validateText(dest.SMBShare, "smb_share", 80, true); err != nil {
+		return err
+	}
+	if strings.ContainsAny(dest.SMBShare, "/\\") {
+		return fmt.Errorf("smb_share must be a share name, not a path")
+	}
+	// go-smb2 does not support anonymous sessions; a guest account still
+	// needs a user name.
+	if err := validateText(dest.SMBUsername, "smb_username", 100, true); err != nil {
+		return err
+	}
+	if err := validateText(dest.SMBDomain, "smb_domain", 100, false); err != nil {
+		return err
+	}
+	if err := validateText(dest.SMBPassword, "smb_password", 500, false); err != nil {
+		return err
+	}
+	return safety.ValidateRemotePath(dest.SMBPath, "smb_path")
+}
+
+func validateAzureBlobDestination(dest model.DestinationRequest) error {
+	if !azureAccountPattern.MatchString(dest.AzureAccountName) {
+		return fmt.Errorf("azure_account_name must be 3-24 lowercase letters or digits")
+	}
+	if !azureContainerPattern.MatchString(dest.AzureContainer) || strings.Contains(dest.AzureContainer, "--") {
+		return fmt.Errorf("azure_container is invalid")
+	}
+	if dest.AzureEndpoint != "" {
+		if err := safety.ValidateURL(dest.AzureEndpoint, "azure_endpoint", true); err != nil {
+			return err
+		}
+	}
+	if err := safety.ValidateRemotePath(dest.AzurePrefix, "azure_prefix"); err != nil {
+		return err
+	}
+	switch dest.AzureAccessTier {
+	case "", "Hot", "Cool", "Cold", "Archive":
+	default:
+		return fmt.Errorf("azure_access_tier must be Hot, Cool, Cold or Archive")
+	}
+	if dest.AzureAccountKey != "" && dest.AzureSASToken != "" {
+		return fmt.Errorf("use either azure_account_key or azure_sas_token, not both")
+	}
+	if dest.AzureAccountKey != "" {
+		if _, err := base64.StdEncoding.DecodeString(dest.AzureAccountKey); err != nil {
+			return fmt.Errorf("azure_account_key must be base64 encoded")
+		}
+	}
+	if err := validateText(dest.AzureSASToken, "azure_sas_token", 2000, false); err != nil {
+		return err
+	}
+	if dest.AzureSASToken != "" && !strings.Contains(dest.AzureSASToken, "sig=") {
+		return fmt.Errorf("azure_sas_token must be a SAS query string containing sig=")
+	}
+	return nil
+}
+
+func validateGCSDestination(dest model.DestinationRequest) error {
+	if !gcsBucketPattern.MatchString(dest.GCSBucket) || strings.Contains(dest.GCSBucket, "..") {
+		return fmt.Errorf("gcs_bucket is invalid")
+	}
+	if dest.GCSEndpoint != "" {
+		if err := safety.ValidateURL(dest.GCSEndpoint, "gcs_endpoint", true); err != nil {
+			return err
+		}
+	}
+	if err := safety.ValidateRemotePath(dest.GCSPrefix, "gcs_prefix"); err != nil {
+		return err
+	}
+	if dest.GCSCredentials == "" {
+		return nil
+	}
+	if len(dest.GCSCredentials) > 16384 {
+		return fmt.Errorf("gcs_credentials is too long")
+	}
+	if _, err := model.ParseGCSServiceAccount(dest.GCSCredentials); err != nil {
+		return fmt.Errorf("gcs_credentials: %w", err)
+	}
+	return nil
+}
+
+func validateRcloneDestination(dest model.DestinationRequest) error {
+	if len(dest.RcloneRemote) > 100 || !rcloneRemotePattern.MatchString(dest.RcloneRemote) || strings.HasPrefix(dest.RcloneRemote, "-") {
+		return fmt.Errorf("rclone_remote is invalid")
+	}
+	if err := safety.ValidateRemotePath(dest.RclonePath, "rclone_path"); err != nil {
+		return err
+	}
+	if dest.RcloneConfig == "" {
+		return nil
+	}
+	if len(dest.RcloneConfig) > 65536 {
+		return fmt.Errorf("rclone_config is too long")
+	}
+	if _, err := model.ParseRcloneConfig(dest.RcloneConfig); err != nil {
+		return fmt.Errorf("rclone_config: %w", err)
+	}
+	return nil
+}
+
+func validateGitDestination(dest model.DestinationRequest) error {
+	if err := validateGitURL(dest.GitURL); err != nil {
+		return err
+	}
+	branch := dest.GitBranch
+	if branch != "" && (len(branch) > 255 || !gitBranchPattern.MatchString(branch) || strings.Contains(branch, "..") ||
+		strings.Contains(branch, "//") || strings.HasSuffix(branch, "/") || strings.HasSuffix(branch, ".lock")) {
+		return fmt.Errorf("git_branch is not a valid branch name")
+	}
+	if err := safety.ValidateRemotePath(dest.GitPath, "git_path"); err != nil {
+		return err
+	}
+	if err := validateGitFilename(dest.GitFilename); err != nil {
+		return err
+	}
+	// History is the retention: every run is a commit and old versions
+	// stay reachable, so deleting files would not remove anything.
+	if dest.MaxBackupCount > 0 {
+		return fmt.Errorf("git destinations keep every backup in history; max_backup_count is not supported")
+	}
+	if !dest.Encrypted {
+		return fmt.Errorf("git destinations require an encrypted export")
+	}
+	if err := validateText(dest.GitAuthorName, "git_author_name", 100, false); err != nil {
+		return err
+	}
+	if err := validateText(dest.GitAuthorEmail, "git_author_email", 255, false); err != nil {
+		return err
+	}
+
+	if model.IsGitHTTPSURL(dest.GitURL) {
+		if err := validateText(dest.GitUsername, "git_username", 100, false); err != nil {
+			return err
+		}
+		return validateText(dest.GitPassword, "git_password", 500, false)
+	}
+	if len(dest.GitPrivateKey) > 16384 {
+		return fmt.Errorf("git_private_key is too long")
+	}
+	if dest.GitPrivateKey != "" {
+		if _, err := ssh.ParsePrivateKey([]byte(dest.GitPrivateKey)); err != nil {
+			return fmt.Errorf("git_private_key must be an unencrypted private key")
+		}
+	}
+	// As for SFTP, the server key is pinned; git runs ssh in batch mode with
+	// strict host key checking.
+	hostKey := strings.TrimSpace(dest.GitHostKey)
+	if hostKey == "" {
+		return fmt.Errorf("git_host_key is required for SSH remotes")
+	}
+	if len(hostKey) > 1000 {
+		return fmt.Errorf("git_host_key is too long")
+	}
+	if _, err := provider.ParseSFTPHostKey(hostKey); err != nil {
+		return fmt.Errorf("git_host_key must be a public key")
+	}
+	return nil
+}
+
+func validateEmailDestination(dest model.DestinationRequest) error {
+	host := strings.TrimSpace(dest.EmailHost)
+	if err := validateText(host, "email_host", 255, true); err != nil {
+		return err
+	}
+	if strings.ContainsAny(host, "/@ ") {
+		return fmt.Errorf("email_host must be a host name or IP address")
+	}
+	if dest.EmailPort < 0 || dest.EmailPort > 65535 {
+		return fmt.Errorf("email_port is invalid")
+	}
+	switch dest.EmailTLSMode {
+	case "", "starttls", "implicit":
+	default:
+		return fmt.Errorf("email_tls_mode must be starttls or implicit")
+	}
+	if err := validateText(dest.EmailUsername, "email_username", 255, false); err != nil {
+		return err
+	}
+	if err := validateText(dest.EmailPassword, "email_password", 500, false); err != nil {
+		return err
+	}
+	if err := validateText(dest.EmailFrom, "email_from", 255, true); err != nil {
+		return err
+	}
+	if _, err := mail.ParseAddress(dest.EmailFrom); err != nil {
+		return fmt.Errorf("email_from must be an email address")
+	}
+	if err := validateText(dest.EmailRecipients, "email_recipients", 2000, true); err != nil {
+		return err
+	}
+	recipients, err := model.ParseEmailRecipients(dest.EmailRecipients)
+	if err != nil {
+		return fmt.Errorf("email_recipients: %w", err)
+	}
+	if len(recipients) > 20 {
+		return fmt.Errorf("email_recipients supports at most 20 addresses")
+	}
+	if dest.EmailMaxSizeMB < 0 || dest.EmailMaxSizeMB > 50 {
+		return fmt.Errorf("email_max_size_mb must be between 0 and 50")
+	}
+	// Sent mail cannot be deleted again.
+	if dest.MaxBackupCount > 0 {
+		return fmt.Errorf("email destinations cannot apply max_backup_count")
+	}
+	return nil
+}
+
+// validateGitURL accepts https://, ssh:// and scp-like user@host:path
+// remotes. Local paths and other transports (file://, ext::) are rejected.
+func validateGitURL(raw string) error {
+	if err := validateText(raw, "git_url", 500, true); err != nil {
+		return err
+	}
+	if strings.HasPrefix(raw, "-") || strings.ContainsAny(raw, " \t") {
+		return fmt.Errorf("git_url is invalid")
+	}
+	lower := strings.ToLower(raw)
+	if !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "ssh://") {
+		if !gitSCPURLPattern.MatchString(raw) {
+			return fmt.Errorf("git_url must be an https://, ssh:// or user@host:path remote")
+		}
+		return nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || strings.Trim(parsed.Path, "/") == "" {
+		return fmt.Errorf("git_url is invalid")
+	}
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		return fmt.Errorf("git_url must not contain a password; use git_password")
+	}
+	return nil
+}
+
+// validateGitFilename checks the optional stable artifact name. Unlike the
+// task template it does not need {time}.
+func validateGitFilename(name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := validateText(name, "git_filename", 200, false); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".json") {
+		return fmt.Errorf("git_filename must end with .json")
+	}
+	if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("git_filename must be a single safe filename")
+	}
+	rest := strings.NewReplacer("{time}", "", "{task_name}", "", "{medium}", "").Replace(name)
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("git_filename only supports {time}, {task_name} and {medium}")
+	}
+	return nil
+}
+
+// validateStoredCredentials checks the merged destination, because an update
+// may omit secrets that are already stored.
+func validateStoredCredentials(dest *model.BackupDestination) error {
+	if dest.Type == "webdav" {
+		switch model.WebDAVAuthModeOrDefault(dest.WebDAVAuthMode) {
+		case "bearer":
+			if dest.WebDAVBearerToken == "" {
+				return fmt.Errorf("webdav_bearer_token is required for bearer auth")
+			}
+		case "digest":
+			if dest.WebDAVUsername == "" || dest.WebDAVPassword == "" {
+				return fmt.Errorf("webdav_username and webdav_password are required for digest auth")
+			}
+		}
+		if dest.WebDAVClientCert != "" {
+			if dest.WebDAVClientKey == "" {
+				return fmt.Errorf("webdav_client_key is required with webdav_client_cert")
+			}
+			if _, err := webdav.NewTLSConfig(dest.WebDAVClientCert, dest.WebDAVClientKey, ""); err != nil {
+				return fmt.Errorf("webdav_client_cert is invalid: %w", err)
+			}
+		}
+	}
+	if dest.Type == "sftp" && dest.SFTPPassword == "" && dest.SFTPPrivateKey == "" {
+		return fmt.Errorf("sftp_password or sftp_private_key is required")
+	}
+	if dest.Type == "s3" {
+		mode := model.S3CredentialModeOrDefault(dest.S3CredentialMode)
+		if mode == "static" && (dest.S3AccessKey == "" || dest.S3SecretKey == "") {
+			return fmt.Errorf("s3_access_key and s3_secret_key are required for static credentials")
+		}
+		// AssumeRole falls back to the default chain without a key pair.
+		if mode == "assume_role" && (dest.S3AccessKey == "") != (dest.S3SecretKey == "") {
+			return fmt.Errorf("s3_access_key and s3_secret_key must be set together")
+		}
+	}
+	if dest.Type == "s3" && dest.S3Encryption == "sse-c" && dest.S3SSECustomerKey == "" {
+		return fmt.Errorf("s3_sse_customer_key is required for sse-c")
+	}
+	if dest.Type == "azblob" && dest.AzureAccountKey == "" && dest.AzureSASToken == "" {
+		return fmt.Errorf("azure_account_key or azure_sas_token is required")
+	}
+	if dest.Type == "gcs" && dest.GCSCredentials == "" {
+		return fmt.Errorf("gcs_credentials is required")
+	}
+	if dest.Type == "email" && dest.EmailUsername != "" && dest.EmailPassword == "" {
+		return fmt.Errorf("email_password is required when email_username is set")
+	}
+	if dest.Type == "git" {
+		if model.IsGitHTTPSURL(dest.GitURL) && (dest.GitUsername == "" || dest.GitPassword == "") {
+			return fmt.Errorf("git_username and git_password are required for HTTPS remotes")
+		}
+		if !model.IsGitHTTPSURL(dest.GitURL) && dest.GitPrivateKey == "" {
+			return fmt.Errorf("git_private_key is required for SSH remotes")
+		}
+	}
+	if dest.Type == "rclone" {
+		if dest.RcloneConfig == "" {
+			return fmt.Errorf("rclone_config is required")
+		}
+		// The remote may change without re-entering the stored config.
+		remotes, err := model.ParseRcloneConfig(dest.RcloneConfig)
+		if err != nil {
+			return fmt.Errorf("rclone_config: %w", err)
+		}
+		if !slices.Contains(remotes, dest.RcloneRemote) {
+			return fmt.Errorf("rclone_config does not define remote %q", dest.RcloneRemote)
+		}
+	}
+	return nil
+}
+
 func validateCronExpression(expr string) error {
 	expr = strings.TrimSpace(expr)
 	if expr == "" {
@@ -142,8 +848,7 @@ func validateCronExpression(expr string) error {
 	if len(parts) == 5 {
 		expr = "0 " + expr
 	}
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	if _, err := parser.Parse(expr); err != nil {
+	if _, err := cronParser.Parse(expr); err != nil {
 		return fmt.Errorf("Cron 表达式无效: %w", err)
 	}
 	return nil
@@ -0,0 +1,187 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Blackout actions decide what happens to a scheduled run that falls inside
+// a blackout window. Manual executions are never affected.
+const (
+	BlackoutActionSkip  = "skip"
+	BlackoutActionDefer = "defer"
+)
+
+const blackoutDateLayout = "2006-01-02"
+
+// BlackoutWindow 禁止定时备份的时间窗口。TaskID 为空时对所有任务生效。
+// 时间按任务时区解释；未配置时区时使用进程本地时间。
+type BlackoutWindow struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	TaskID *uint  `gorm:"index" json:"task_id"`
+	Name   string `gorm:"size:100" json:"name"`
+	// Weekdays is a comma-separated list of 0-6 (Sunday = 0). Empty means
+	// every day.
+	Weekdays string `gorm:"size:20" json:"weekdays"`
+	// Date restricts the window to one calendar day (YYYY-MM-DD), which is
+	// how holidays are expressed.
+	Date string `gorm:"size:10" json:"date"`
+	// StartTime and EndTime use HH:MM. An end at or before the start wraps
+	// past midnight into the following day.
+	StartTime string    `gorm:"size:5;not null" json:"start_time"`
+	EndTime   string    `gorm:"size:5;not null" json:"end_time"`
+	Action    string    `gorm:"size:10;not null;default:skip" json:"action"`
+	Enabled   bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BlackoutWindowRequest is the API representation of a blackout window.
+type BlackoutWindowRequest struct {
+	Name      string `json:"name"`
+	Weekdays  string `json:"weekdays"`
+	Date      string `json:"date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Action    string `json:"action"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// ToBlackoutWindow converts a request into a persistence model.
+func (r BlackoutWindowRequest) ToBlackoutWindow() BlackoutWindow {
+	window := BlackoutWindow{Enabled: true}
+	r.ApplyTo(&window)
+	return window
+}
+
+// ApplyTo copies the updateable fields onto an existing window.
+func (r BlackoutWindowRequest) ApplyTo(window *BlackoutWindow) {
+	window.Name = strings.TrimSpace(r.Name)
+	window.Weekdays = strings.ReplaceAll(strings.TrimSpace(r.Weekdays), " ", "")
+	window.Date = strings.TrimSpace(r.Date)
+	window.StartTime = strings.TrimSpace(r.StartTime)
+	window.EndTime = strings.TrimSpace(r.EndTime)
+	window.Action = strings.TrimSpace(r.Action)
+	if window.Action == "" {
+		window.Action = BlackoutActionSkip
+	}
+	if r.Enabled != nil {
+		window.Enabled = *r.Enabled
+	}
+}
+
+// Validate checks the window definition without evaluating it.
+func (r BlackoutWindowRequest) Validate() error {
+	window := r.ToBlackoutWindow()
+	return window.Validate()
+}
+
+// Validate checks that the stored window can be evaluated.
+func (w BlackoutWindow) Validate() error {
+	if len([]rune(w.Name)) > 100 {
+		return fmt.Errorf("blackout window name is too long")
+	}
+	if _, err := parseBlackoutWeekdays(w.Weekdays); err != nil {
+		return err
+	}
+	if w.Date != "" {
+		if _, err := time.Parse(blackoutDateLayout, w.Date); err != nil {
+			return fmt.Errorf("blackout window date must use YYYY-MM-DD")
+		}
+	}
+	if _, err := parseClock(w.StartTime); err != nil {
+		return fmt.Errorf("blackout window start_time: %w", err)
+	}
+	if _, err := parseClock(w.EndTime); err != nil {
+		return fmt.Errorf("blackout window end_time: %w", err)
+	}
+	if w.Action != BlackoutActionSkip && w.Action != BlackoutActionDefer {
+		return fmt.Errorf("blackout window action must be skip or defer")
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the window and, if so, when the
+// window ends. t must already be in the location the window applies to.
+func (w BlackoutWindow) Contains(t time.Time) (bool, time.Time) {
+	start, err := parseClock(w.StartTime)
+	if err != nil {
+		return false, time.Time{}
+	}
+	end, err := parseClock(w.EndTime)
+	if err != nil {
+		return false, time.Time{}
+	}
+
+	// A window is anchored to the day it starts on. Check today's window and,
+	// for windows that wrap past midnight, the one that started yesterday.
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for _, day := range []time.Time{today, today.AddDate(0, 0, -1)} {
+		if !w.appliesOn(day) {
+			continue
+		}
+		windowStart := atClock(day, start)
+		windowEnd := atClock(day, end)
+		if end <= start {
+			windowEnd = atClock(day.AddDate(0, 0, 1), end)
+		}
+		if !t.Before(windowStart) && t.Before(windowEnd) {
+			return true, windowEnd
+		}
+	}
+	return false, time.Time{}
+}
+
+func (w BlackoutWindow) appliesOn(day time.Time) bool {
+	if w.Date != "" && day.Format(blackoutDateLayout) != w.Date {
+		return false
+	}
+	weekdays, err := parseBlackoutWeekdays(w.Weekdays)
+	if err != nil {
+		return false
+	}
+	if len(weekdays) == 0 {
+		return true
+	}
+	return weekdays[day.Weekday()]
+}
+
+// Describe returns a short human-readable label used in run logs.
+func (w BlackoutWindow) Describe() string {
+	label := w.Name
+	if label == "" {
+		label = "blackout window"
+	}
+	return fmt.Sprintf("%s (%s-%s)", label, w.StartTime, w.EndTime)
+}
+
+// atClock builds the wall-clock time on day. time.Date normalizes the minute
+// offset, which keeps the result correct on daylight-saving transition days.
+func atClock(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, int(clock/time.Minute), 0, 0, day.Location())
+}
+
+func parseBlackoutWeekdays(raw string) (map[time.Weekday]bool, error) {
+	weekdays := make(map[time.Weekday]bool)
+	if raw == "" {
+		return weekdays, nil
+	}
+	for _, part := range strings.Split(raw, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || value < 0 || value > 6 {
+			return nil, fmt.Errorf("blackout window weekdays must be a comma-separated list of 0-6")
+		}
+		weekdays[time.Weekday(value)] = true
+	}
+	return weekdays, nil
+}
+
+func parseClock(raw string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", raw)
+	if err != nil || len(raw) != len("15:04") {
+		return 0, fmt.Errorf("time must use HH:MM")
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlackoutWindowContainsSameDayRange(t *testing.T) {
+	window := BlackoutWindow{Weekdays: "6", StartTime: "02:00", EndTime: "04:00", Action: BlackoutActionSkip}
+	saturday := time.Date(2025, 12, 6, 3, 0, 0, 0, time.UTC)
+
+	inside, end := window.Contains(saturday)
+	if !inside {
+		t.Fatal("Saturday 03:00 should be inside the maintenance window")
+	}
+	if want := time.Date(2025, 12, 6, 4, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Fatalf("window end = %s, want %s", end, want)
+	}
+	if inside, _ := window.Contains(saturday.Add(time.Hour)); inside {
+		t.Fatal("window end should be exclusive")
+	}
+	if inside, _ := window.Contains(saturday.AddDate(0, 0, 1)); inside {
+		t.Fatal("Sunday should not match a Saturday window")
+	}
+}
+
+func TestBlackoutWindowWrapsPastMidnight(t *testing.T) {
+	window := BlackoutWindow{Weekdays: "5", StartTime: "22:00", EndTime: "02:00", Action: BlackoutActionDefer}
+	saturdayMorning := time.Date(2025, 12, 6, 1, 30, 0, 0, time.UTC)
+
+	inside, end := window.Contains(saturdayMorning)
+	if !inside {
+		t.Fatal("a Friday night window should cover early Saturday")
+	}
+	if want := time.Date(2025, 12, 6, 2, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Fatalf("window end = %s, want %s", end, want)
+	}
+}
+
+func TestBlackoutWindowDateRestrictsToHoliday(t *testing.T) {
+	window := BlackoutWindow{Date: "2025-12-25", StartTime: "00:00", EndTime: "00:00", Action: BlackoutActionSkip}
+
+	if inside, _ := window.Contains(time.Date(2025, 12, 25, 12, 0, 0, 0, time.UTC)); !inside {
+		t.Fatal("holiday should be blacked out all day")
+	}
+	if inside, _ := window.Contains(time.Date(2025, 12, 26, 12, 0, 0, 0, time.UTC)); inside {
+		t.Fatal("the day after the holiday should not be blacked out")
+	}
+}
+
+func TestBlackoutWindowRequestValidation(t *testing.T) {
+	for _, request := range []BlackoutWindowRequest{
+		{StartTime: "2:00", EndTime: "04:00"},
+		{StartTime: "02:00", EndTime: "24:00"},
+		{StartTime: "02:00", EndTime: "04:00", Weekdays: "7"},
+		{StartTime: "02:00", EndTime: "04:00", Date: "2025/12/25"},
+		{StartTime: "02:00", EndTime: "04:00", Action: "retry"},
+	} {
+		if err := request.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid window", request)
+		}
+	}
+	if err := (BlackoutWindowRequest{StartTime: "02:00", EndTime: "04:00", Weekdays: "0, 6"}).Validate(); err != nil {
+		t.Fatalf("valid window rejected: %v", err)
+	}
+}
@@ -1,6 +1,13 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mingzaily/bitwarden-backup/internal/crypto"
@@ -14,13 +21,27 @@ type BackupDestination struct {
 	Type string `gorm:"size:20;not null" json:"type"`
 
 	// 本地存储配置
-	LocalPath string `gorm:"size:255" json:"local_path"`
+	LocalPath     string `gorm:"size:255" json:"local_path"`
+	LocalFileMode string `gorm:"size:4" json:"local_file_mode"` // 八进制文件权限，空值为 0600
+	LocalGroup    string `gorm:"size:64" json:"local_group"`    // 属组名称或 GID，空值不修改
+	LocalDedup    bool   `gorm:"default:false" json:"local_dedup"`
 
 	// WebDAV 配置
 	WebDAVURL      string `gorm:"size:255" json:"webdav_url"`
 	WebDAVUsername string `gorm:"size:100" json:"webdav_username"`
 	WebDAVPassword string `gorm:"size:500" json:"webdav_password"`
 	WebDAVPath     string `gorm:"size:255" json:"webdav_path"`
+	// WebDAVTimeout bounds each request in seconds, including the transfer
+	// of one upload or chunk; 0 uses webdav.DefaultTimeout.
+	WebDAVTimeout int `gorm:"default:0" json:"webdav_timeout"`
+	// WebDAVAuthMode is basic, digest, bearer or none; empty means basic.
+	WebDAVAuthMode    string `gorm:"size:20" json:"webdav_auth_mode"`
+	WebDAVBearerToken string `gorm:"type:text" json:"webdav_bearer_token"`
+	// Optional PEM client certificate and key for mutual TLS, and a PEM CA
+	// bundle trusted in addition to the system roots.
+	WebDAVClientCert string `gorm:"type:text" json:"webdav_client_cert"`
+	WebDAVClientKey  string `gorm:"type:text" json:"webdav_client_key"`
+	WebDAVCACert     string `gorm:"type:text" json:"webdav_ca_cert"`
 
 	// S3 配置
 	S3Endpoint  string `gorm:"size:255" json:"s3_endpoint"`
@@ -29,10 +50,120 @@ type BackupDestination struct {
 	S3AccessKey string `gorm:"size:500" json:"s3_access_key"`
 	S3SecretKey string `gorm:"size:500" json:"s3_secret_key"`
 	S3Path      string `gorm:"size:255" json:"s3_path"`
+	// S3CredentialMode is static, default (environment, shared profile or
+	// instance metadata), assume_role or web_identity; empty means static.
+	S3CredentialMode       string `gorm:"size:20" json:"s3_credential_mode"`
+	S3RoleARN              string `gorm:"size:2048" json:"s3_role_arn"`
+	S3ExternalID           string `gorm:"size:1224" json:"s3_external_id"`
+	S3RoleSessionName      string `gorm:"size:64" json:"s3_role_session_name"`
+	S3WebIdentityTokenFile string `gorm:"size:255" json:"s3_web_identity_token_file"`
+	// S3AddressingStyle is path or virtual; empty uses path-style for a
+	// custom endpoint and virtual-hosted style for AWS.
+	S3AddressingStyle string `gorm:"size:20" json:"s3_addressing_style"`
+	// Upload options. Empty values leave the bucket defaults in place.
+	S3StorageClass string `gorm:"size:40" json:"s3_storage_class"`
+	S3Encryption   string `gorm:"size:20" json:"s3_encryption"` // sse-s3, sse-kms or sse-c
+	S3KMSKeyID     string `gorm:"size:2048" json:"s3_kms_key_id"`
+	// S3SSECustomerKey is the base64 encoded 256-bit key for SSE-C. Objects
+	// written with it cannot be read without it.
+	S3SSECustomerKey    string `gorm:"size:500" json:"s3_sse_customer_key"`
+	S3ObjectLockMode    string `gorm:"size:20" json:"s3_object_lock_mode"` // GOVERNANCE or COMPLIANCE
+	S3ObjectLockDays    int    `gorm:"default:0" json:"s3_object_lock_days"`
+	S3LegalHold         bool   `gorm:"default:false" json:"s3_legal_hold"`
+	S3ChecksumAlgorithm string `gorm:"size:20" json:"s3_checksum_algorithm"`
+
+	// SFTP 配置
+	SFTPHost          string `gorm:"size:255" json:"sftp_host"`
+	SFTPPort          int    `gorm:"default:22" json:"sftp_port"`
+	SFTPUsername      string `gorm:"size:100" json:"sftp_username"`
+	SFTPPassword      string `gorm:"size:500" json:"sftp_password"`
+	SFTPPrivateKey    string `gorm:"type:text" json:"sftp_private_key"`
+	SFTPKeyPassphrase string `gorm:"size:500" json:"sftp_key_passphrase"`
+	// SFTPHostKey pins the server key, either as an authorized_keys style
+	// public key or as a SHA256 fingerprint. Unknown hosts are never trusted.
+	SFTPHostKey string `gorm:"size:1000" json:"sftp_host_key"`
+	SFTPPath    string `gorm:"size:255" json:"sftp_path"`
+
+	// FTP 配置
+	FTPHost     string `gorm:"size:255" json:"ftp_host"`
+	FTPPort     int    `json:"ftp_port"`
+	FTPUsername string `gorm:"size:100" json:"ftp_username"`
+	FTPPassword string `gorm:"size:500" json:"ftp_password"`
+	FTPTLSMode  string `gorm:"size:20" json:"ftp_tls_mode"` // none, explicit or implicit
+	// FTPTLSFingerprint pins the server certificate by its SHA-256 digest,
+	// which lets self-signed NAS certificates be trusted explicitly.
+	FTPTLSFingerprint string `gorm:"size:100" json:"ftp_tls_fingerprint"`
+	FTPDisableEPSV    bool   `gorm:"default:false" json:"ftp_disable_epsv"`
+	FTPPath           string `gorm:"size:255" json:"ftp_path"`
+
+	// SMB 配置
+	SMBHost     string `gorm:"size:255" json:"smb_host"`
+	SMBPort     int    `json:"smb_port"`
+	SMBShare    string `gorm:"size:100" json:"smb_share"`
+	SMBDomain   string `gorm:"size:100" json:"smb_domain"`
+	SMBUsername string `gorm:"size:100" json:"smb_username"`
+	SMBPassword string `gorm:"size:500" json:"smb_password"`
+	SMBPath     string `gorm:"size:255" json:"smb_path"`
+
+	// Azure Blob 配置
+	AzureAccountName string `gorm:"size:100" json:"azure_account_name"`
+	AzureAccountKey  string `gorm:"size:500" json:"azure_account_key"`
+	AzureSASToken    string `gorm:"size:2000" json:"azure_sas_token"`
+	AzureEndpoint    string `gorm:"size:255" json:"azure_endpoint"` // optional, e.g. Azurite
+	AzureContainer   string `gorm:"size:63" json:"azure_container"`
+	AzurePrefix      string `gorm:"size:255" json:"azure_prefix"`
+	AzureAccessTier  string `gorm:"size:20" json:"azure_access_tier"`
+
+	// GCS 配置
+	GCSBucket      string `gorm:"size:222" json:"gcs_bucket"`
+	GCSPrefix      string `gorm:"size:255" json:"gcs_prefix"`
+	GCSCredentials string `gorm:"type:text" json:"gcs_credentials"` // service account JSON key
+	GCSEndpoint    string `gorm:"size:255" json:"gcs_endpoint"`     // optional, e.g. fake-gcs-server
+
+	// rclone 配置
+	RcloneRemote string `gorm:"size:100" json:"rclone_remote"`
+	RclonePath   string `gorm:"size:255" json:"rclone_path"`
+	RcloneConfig string `gorm:"type:text" json:"rclone_config"` // inline rclone.conf
+
+	// Git 配置
+	GitURL        string `gorm:"size:500" json:"git_url"` // https://, ssh:// or scp-like git@host:repo
+	GitBranch     string `gorm:"size:255" json:"git_branch"`
+	GitPath       string `gorm:"size:255" json:"git_path"`
+	GitFilename   string `gorm:"size:255" json:"git_filename"` // stable name; empty uses the task template
+	GitUsername   string `gorm:"size:100" json:"git_username"`
+	GitPassword   string `gorm:"size:500" json:"git_password"` // HTTPS password or access token
+	GitPrivateKey string `gorm:"type:text" json:"git_private_key"`
+	// GitHostKey pins the SSH server key as an authorized_keys style public
+	// key. Unknown hosts are never trusted.
+	GitHostKey     string `gorm:"size:1000" json:"git_host_key"`
+	GitAuthorName  string `gorm:"size:100" json:"git_author_name"`
+	GitAuthorEmail string `gorm:"size:255" json:"git_author_email"`
+
+	// 邮件配置
+	EmailHost     string `gorm:"size:255" json:"email_host"`
+	EmailPort     int    `json:"email_port"`
+	EmailTLSMode  string `gorm:"size:20" json:"email_tls_mode"`  // starttls or implicit
+	EmailUsername string `gorm:"size:255" json:"email_username"` // empty sends without SMTP AUTH
+	EmailPassword string `gorm:"size:500" json:"email_password"`
+	EmailFrom     string `gorm:"size:255" json:"email_from"`
+	// EmailRecipients holds one or more addresses separated by commas,
+	// semicolons or newlines.
+	EmailRecipients string `gorm:"size:2000" json:"email_recipients"`
+	// EmailMaxSizeMB caps the export attached to one message; 0 uses
+	// DefaultEmailMaxSizeMB.
+	EmailMaxSizeMB int `gorm:"default:0" json:"email_max_size_mb"`
 
 	// 目标服务器配置
 	TargetServerID *uint         `json:"target_server_id"`
 	TargetServer   *ServerConfig `gorm:"foreignKey:TargetServerID" json:"target_server,omitempty"`
+	// ServerSyncMode is append, purge or mirror; empty appends like an
+	// import always did. Purge only runs with ServerPurgeConfirmed set.
+	ServerSyncMode       string `gorm:"size:20" json:"server_sync_mode"`
+	ServerPurgeConfirmed bool   `gorm:"default:false" json:"server_purge_confirmed"`
+	// TargetOrganizationID imports into an organization instead of the
+	// personal vault; TargetCollectionID narrows a mirror to one collection.
+	TargetOrganizationID string `gorm:"size:36" json:"target_organization_id"`
+	TargetCollectionID   string `gorm:"size:36" json:"target_collection_id"`
 
 	// 加密选项
 	Encrypted          bool   `gorm:"default:false" json:"encrypted"`
@@ -48,87 +179,168 @@ type BackupDestination struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// SensitiveField names one credential column of a destination.
+type SensitiveField struct {
+	Name  string
+	Value *string
+}
+
+// SensitiveFields lists every credential stored on a destination. The GORM
+// hooks, the startup encryption migration and type changes all use this list,
+// so a new provider only has to register its secrets here.
+func (d *BackupDestination) SensitiveFields() []SensitiveField {
+	return []SensitiveField{
+		{"WebDAVPassword", &d.WebDAVPassword},
+		{"WebDAVBearerToken", &d.WebDAVBearerToken},
+		{"WebDAVClientKey", &d.WebDAVClientKey},
+		{"S3AccessKey", &d.S3AccessKey},
+		{"S3SecretKey", &d.S3SecretKey},
+		{"S3SSECustomerKey", &d.S3SSECustomerKey},
+		{"SFTPPassword", &d.SFTPPassword},
+		{"SFTPPrivateKey", &d.SFTPPrivateKey},
+		{"SFTPKeyPassphrase", &d.SFTPKeyPassphrase},
+		{"FTPPassword", &d.FTPPassword},
+		{"SMBPassword", &d.SMBPassword},
+		{"AzureAccountKey", &d.AzureAccountKey},
+		{"AzureSASToken", &d.AzureSASToken},
+		{"GCSCredentials", &d.GCSCredentials},
+		{"RcloneConfig", &d.RcloneConfig},
+		{"GitPassword", &d.GitPassword},
+		{"GitPrivateKey", &d.GitPrivateKey},
+		{"EmailPassword", &d.EmailPassword},
+		{"EncryptionPassword", &d.EncryptionPassword},
+	}
+}
+
 // BeforeSave GORM 钩子：保存前加密敏感字段（防止双重加密）
 func (d *BackupDestination) BeforeSave(tx *gorm.DB) error {
-	if d.WebDAVPassword != "" && !crypto.IsEncrypted(d.WebDAVPassword) {
-		encrypted, err := crypto.Encrypt(d.WebDAVPassword)
-		if err != nil {
-			return err
+	for _, field := range d.SensitiveFields() {
+		if *field.Value == "" || crypto.IsEncrypted(*field.Value) {
+			continue
 		}
-		d.WebDAVPassword = encrypted
-	}
-	if d.S3AccessKey != "" && !crypto.IsEncrypted(d.S3AccessKey) {
-		encrypted, err := crypto.Encrypt(d.S3AccessKey)
-		if err != nil {
-			return err
-		}
-		d.S3AccessKey = encrypted
-	}
-	if d.S3SecretKey != "" && !crypto.IsEncrypted(d.S3SecretKey) {
-		encrypted, err := crypto.Encrypt(d.S3SecretKey)
-		if err != nil {
-			return err
-		}
-		d.S3SecretKey = encrypted
-	}
-	if d.EncryptionPassword != "" && !crypto.IsEncrypted(d.EncryptionPassword) {
-		encrypted, err := crypto.Encrypt(d.EncryptionPassword)
+		encrypted, err := crypto.Encrypt(*field.Value)
 		if err != nil {
 			return err
 		}
-		d.EncryptionPassword = encrypted
+		*field.Value = encrypted
 	}
 	return nil
 }
 
 // AfterFind GORM 钩子：查询后解密敏感字段
 func (d *BackupDestination) AfterFind(tx *gorm.DB) error {
-	if d.WebDAVPassword != "" {
-		decrypted, err := decryptSensitive(d.WebDAVPassword)
-		if err != nil {
-			return err
-		}
-		d.WebDAVPassword = decrypted
-	}
-	if d.S3AccessKey != "" {
-		decrypted, err := decryptSensitive(d.S3AccessKey)
-		if err != nil {
-			return err
-		}
-		d.S3AccessKey = decrypted
-	}
-	if d.S3SecretKey != "" {
-		decrypted, err := decryptSensitive(d.S3SecretKey)
-		if err != nil {
-			return err
+	for _, field := range d.SensitiveFields() {
+		if *field.Value == "" {
+			continue
 		}
-		d.S3SecretKey = decrypted
-	}
-	if d.EncryptionPassword != "" {
-		decrypted, err := decryptSensitive(d.EncryptionPassword)
+		decrypted, err := decryptSensitive(*field.Value)
 		if err != nil {
 			return err
 		}
-		d.EncryptionPassword = decrypted
+		*field.Value = decrypted
 	}
 	return nil
 }
 
+// StoresFiles reports whether the destination receives the exported file
+// itself and therefore supports encrypted exports and retention. A server
+// destination imports the plain export instead.
+func (d *BackupDestination) StoresFiles() bool {
+	switch d.Type {
+	case "local", "webdav", "s3", "sftp", "ftp", "smb", "azblob", "gcs", "rclone", "git", "email":
+		return true
+	default:
+		return false
+	}
+}
+
 // DestinationResponse 备份目标响应 DTO（隐藏敏感数据）
 type DestinationResponse struct {
 	ID             uint      `json:"id"`
 	Name           string    `json:"name"`
 	Type           string    `json:"type"`
 	LocalPath      string    `json:"local_path,omitempty"`
+	LocalFileMode  string    `json:"local_file_mode,omitempty"`
+	LocalGroup     string    `json:"local_group,omitempty"`
+	LocalDedup     bool      `json:"local_dedup,omitempty"`
 	WebDAVURL      string    `json:"webdav_url,omitempty"`
 	WebDAVUsername string    `json:"webdav_username,omitempty"`
 	WebDAVPath     string    `json:"webdav_path,omitempty"`
+	WebDAVTimeout  int       `json:"webdav_timeout,omitempty"`
+	WebDAVAuthMode string    `json:"webdav_auth_mode,omitempty"`
+	WebDAVCert     string    `json:"webdav_client_cert,omitempty"`
+	WebDAVCACert   string    `json:"webdav_ca_cert,omitempty"`
 	S3Endpoint     string    `json:"s3_endpoint,omitempty"`
 	S3Region       string    `json:"s3_region,omitempty"`
 	S3Bucket       string    `json:"s3_bucket,omitempty"`
 	S3AccessKey    string    `json:"s3_access_key,omitempty"`
 	S3Path         string    `json:"s3_path,omitempty"`
+	S3Credential   string    `json:"s3_credential_mode,omitempty"`
+	S3RoleARN      string    `json:"s3_role_arn,omitempty"`
+	S3ExternalID   string    `json:"s3_external_id,omitempty"`
+	S3SessionName  string    `json:"s3_role_session_name,omitempty"`
+	S3TokenFile    string    `json:"s3_web_identity_token_file,omitempty"`
+	S3Addressing   string    `json:"s3_addressing_style,omitempty"`
+	S3StorageClass string    `json:"s3_storage_class,omitempty"`
+	S3Encryption   string    `json:"s3_encryption,omitempty"`
+	S3KMSKeyID     string    `json:"s3_kms_key_id,omitempty"`
+	S3LockMode     string    `json:"s3_object_lock_mode,omitempty"`
+	S3LockDays     int       `json:"s3_object_lock_days,omitempty"`
+	S3LegalHold    bool      `json:"s3_legal_hold,omitempty"`
+	S3Checksum     string    `json:"s3_checksum_algorithm,omitempty"`
+	SFTPHost       string    `json:"sftp_host,omitempty"`
+	SFTPPort       int       `json:"sftp_port,omitempty"`
+	SFTPUsername   string    `json:"sftp_username,omitempty"`
+	SFTPHostKey    string    `json:"sftp_host_key,omitempty"`
+	SFTPPath       string    `json:"sftp_path,omitempty"`
+	SFTPAuth       string    `json:"sftp_auth,omitempty"`
+	FTPHost        string    `json:"ftp_host,omitempty"`
+	FTPPort        int       `json:"ftp_port,omitempty"`
+	FTPUsername    string    `json:"ftp_username,omitempty"`
+	FTPTLSMode     string    `json:"ftp_tls_mode,omitempty"`
+	FTPFingerprint string    `json:"ftp_tls_fingerprint,omitempty"`
+	FTPDisableEPSV bool      `json:"ftp_disable_epsv,omitempty"`
+	FTPPath        string    `json:"ftp_path,omitempty"`
+	SMBHost        string    `json:"smb_host,omitempty"`
+	SMBPort        int       `json:"smb_port,omitempty"`
+	SMBShare       string    `json:"smb_share,omitempty"`
+	SMBDomain      string    `json:"smb_domain,omitempty"`
+	SMBUsername    string    `json:"smb_username,omitempty"`
+	SMBPath        string    `json:"smb_path,omitempty"`
+	AzureAccount   string    `json:"azure_account_name,omitempty"`
+	AzureEndpoint  string    `json:"azure_endpoint,omitempty"`
+	AzureContainer string    `json:"azure_container,omitempty"`
+	AzurePrefix    string    `json:"azure_prefix,omitempty"`
+	AzureTier      string    `json:"azure_access_tier,omitempty"`
+	AzureAuth      string    `json:"azure_auth,omitempty"`
+	GCSBucket      string    `json:"gcs_bucket,omitempty"`
+	GCSPrefix      string    `json:"gcs_prefix,omitempty"`
+	GCSEndpoint    string    `json:"gcs_endpoint,omitempty"`
+	GCSClientEmail string    `json:"gcs_client_email,omitempty"`
+	RcloneRemote   string    `json:"rclone_remote,omitempty"`
+	RclonePath     string    `json:"rclone_path,omitempty"`
+	RcloneRemotes  []string  `json:"rclone_remotes,omitempty"`
+	GitURL         string    `json:"git_url,omitempty"`
+	GitBranch      string    `json:"git_branch,omitempty"`
+	GitPath        string    `json:"git_path,omitempty"`
+	GitFilename    string    `json:"git_filename,omitempty"`
+	GitUsername    string    `json:"git_username,omitempty"`
+	GitHostKey     string    `json:"git_host_key,omitempty"`
+	GitAuthorName  string    `json:"git_author_name,omitempty"`
+	GitAuthorEmail string    `json:"git_author_email,omitempty"`
+	GitAuth        string    `json:"git_auth,omitempty"`
+	EmailHost      string    `json:"email_host,omitempty"`
+	EmailPort      int       `json:"email_port,omitempty"`
+	EmailTLSMode   string    `json:"email_tls_mode,omitempty"`
+	EmailUsername  string    `json:"email_username,omitempty"`
+	EmailFrom      string    `json:"email_from,omitempty"`
+	EmailTo        string    `json:"email_recipients,omitempty"`
+	EmailMaxSizeMB int       `json:"email_max_size_mb,omitempty"`
 	TargetServerID *uint     `json:"target_server_id,omitempty"`
+	ServerSyncMode string    `json:"server_sync_mode,omitempty"`
+	ServerPurge    bool      `json:"server_purge_confirmed,omitempty"`
+	TargetOrgID    string    `json:"target_organization_id,omitempty"`
+	TargetCollID   string    `json:"target_collection_id,omitempty"`
 	Encrypted      bool      `json:"encrypted"`
 	MaxBackupCount int       `json:"max_backup_count"`
 	Enabled        bool      `json:"enabled"`
@@ -159,15 +371,87 @@ func (d *BackupDestination) ToResponse() DestinationResponse {
 		Name:           d.Name,
 		Type:           d.Type,
 		LocalPath:      d.LocalPath,
+		LocalFileMode:  d.LocalFileMode,
+		LocalGroup:     d.LocalGroup,
+		LocalDedup:     d.LocalDedup,
 		WebDAVURL:      d.WebDAVURL,
 		WebDAVUsername: d.WebDAVUsername,
 		WebDAVPath:     d.WebDAVPath,
+		WebDAVTimeout:  d.WebDAVTimeout,
+		WebDAVAuthMode: d.webdavAuthMode(),
+		WebDAVCert:     d.WebDAVClientCert,
+		WebDAVCACert:   d.WebDAVCACert,
 		S3Endpoint:     d.S3Endpoint,
 		S3Region:       d.S3Region,
 		S3Bucket:       d.S3Bucket,
 		S3AccessKey:    maskedS3AccessKey,
 		S3Path:         d.S3Path,
+		S3Credential:   d.s3CredentialMode(),
+		S3RoleARN:      d.S3RoleARN,
+		S3ExternalID:   d.S3ExternalID,
+		S3SessionName:  d.S3RoleSessionName,
+		S3TokenFile:    d.S3WebIdentityTokenFile,
+		S3Addressing:   d.S3AddressingStyle,
+		S3StorageClass: d.S3StorageClass,
+		S3Encryption:   d.S3Encryption,
+		S3KMSKeyID:     d.S3KMSKeyID,
+		S3LockMode:     d.S3ObjectLockMode,
+		S3LockDays:     d.S3ObjectLockDays,
+		S3LegalHold:    d.S3LegalHold,
+		S3Checksum:     d.S3ChecksumAlgorithm,
+		SFTPHost:       d.SFTPHost,
+		SFTPPort:       d.SFTPPort,
+		SFTPUsername:   d.SFTPUsername,
+		SFTPHostKey:    d.SFTPHostKey,
+		SFTPPath:       d.SFTPPath,
+		SFTPAuth:       d.sftpAuthMode(),
+		FTPHost:        d.FTPHost,
+		FTPPort:        d.FTPPort,
+		FTPUsername:    d.FTPUsername,
+		FTPTLSMode:     d.FTPTLSMode,
+		FTPFingerprint: d.FTPTLSFingerprint,
+		FTPDisableEPSV: d.FTPDisableEPSV,
+		FTPPath:        d.FTPPath,
+		SMBHost:        d.SMBHost,
+		SMBPort:        d.SMBPort,
+		SMBShare:       d.SMBShare,
+		SMBDomain:      d.SMBDomain,
+		SMBUsername:    d.SMBUsername,
+		SMBPath:        d.SMBPath,
+		AzureAccount:   d.AzureAccountName,
+		AzureEndpoint:  d.AzureEndpoint,
+		AzureContainer: d.AzureContainer,
+		AzurePrefix:    d.AzurePrefix,
+		AzureTier:      d.AzureAccessTier,
+		AzureAuth:      d.azureAuthMode(),
+		GCSBucket:      d.GCSBucket,
+		GCSPrefix:      d.GCSPrefix,
+		GCSEndpoint:    d.GCSEndpoint,
+		GCSClientEmail: d.gcsClientEmail(),
+		RcloneRemote:   d.RcloneRemote,
+		RclonePath:     d.RclonePath,
+		RcloneRemotes:  d.rcloneRemotes(),
+		GitURL:         d.GitURL,
+		GitBranch:      d.GitBranch,
+		GitPath:        d.GitPath,
+		GitFilename:    d.GitFilename,
+		GitUsername:    d.GitUsername,
+		GitHostKey:     d.GitHostKey,
+		GitAuthorName:  d.GitAuthorName,
+		GitAuthorEmail: d.GitAuthorEmail,
+		GitAuth:        d.gitAuthMode(),
+		EmailHost:      d.EmailHost,
+		EmailPort:      d.EmailPort,
+		EmailTLSMode:   d.EmailTLSMode,
+		EmailUsername:  d.EmailUsername,
+		EmailFrom:      d.EmailFrom,
+		EmailTo:        d.EmailRecipients,
+		EmailMaxSizeMB: d.EmailMaxSizeMB,
 		TargetServerID: d.TargetServerID,
+		ServerSyncMode: d.ServerSyncMode,
+		ServerPurge:    d.ServerPurgeConfirmed,
+		TargetOrgID:    d.TargetOrganizationID,
+		TargetCollID:   d.TargetCollectionID,
 		Encrypted:      d.Encrypted,
 		MaxBackupCount: d.MaxBackupCount,
 		Enabled:        d.Enabled,
@@ -187,6 +471,27 @@ func (d *BackupDestination) GetDisplayPath() string {
 		return d.WebDAVURL + d.WebDAVPath
 	case "s3":
 		return "s3://" + d.S3Bucket + d.S3Path
+	case "sftp":
+		return fmt.Sprintf("sftp://%s@%s%s", d.SFTPUsername, net.JoinHostPort(d.SFTPHost, strconv.Itoa(d.SFTPPort)), d.SFTPPath)
+	case "ftp":
+		scheme := "ftp"
+		if d.FTPTLSMode != "" && d.FTPTLSMode != "none" {
+			scheme = "ftps"
+		}
+		return fmt.Sprintf("%s://%s@%s%s", scheme, d.FTPUsername, net.JoinHostPort(d.FTPHost, strconv.Itoa(d.FTPPort)), d.FTPPath)
+	case "smb":
+		return fmt.Sprintf("//%s/%s%s", d.SMBHost, d.SMBShare, d.SMBPath)
+	case "azblob":
+		return "azblob://" + d.AzureAccountName + "/" + d.AzureContainer + d.AzurePrefix
+	case "gcs":
+		return "gs://" + d.GCSBucket + d.GCSPrefix
+	case "rclone":
+		return d.RcloneRemote + ":" + d.RclonePath
+	case "git":
+		return d.GitURL + "#" + GitBranchOrDefault(d.GitBranch) + d.GitPath
+	case "email":
+		recipients, _ := ParseEmailRecipients(d.EmailRecipients)
+		return "mailto:" + strings.Join(recipients, ",")
 	case "server":
 		if d.TargetServer != nil {
 			return d.TargetServer.Name + " · " + d.TargetServer.ServerURL
@@ -203,6 +508,14 @@ func (d *BackupDestination) GetTypeLabel() string {
 		"local":  "本地存储",
 		"webdav": "WebDAV",
 		"s3":     "S3",
+		"sftp":   "SFTP",
+		"ftp":    "FTP",
+		"smb":    "SMB",
+		"azblob": "Azure Blob",
+		"gcs":    "GCS",
+		"rclone": "rclone",
+		"git":    "Git",
+		"email":  "邮件",
 		"server": "服务器",
 	}
 	if label, ok := labels[d.Type]; ok {
@@ -210,3 +523,214 @@ func (d *BackupDestination) GetTypeLabel() string {
 	}
 	return d.Type
 }
+
+// DefaultFTPPort returns the well-known port for a FTP TLS mode. Implicit
+// FTPS listens on its own port; plain and explicit FTPS share port 21.
+func DefaultFTPPort(tlsMode string) int {
+	if tlsMode == "implicit" {
+		return 990
+	}
+	return 21
+}
+
+// Server destination sync modes.
+const (
+	ServerSyncAppend = "append" // import the export, duplicating existing items
+	ServerSyncPurge  = "purge"  // delete the target's items, then import
+	ServerSyncMirror = "mirror" // create, update and delete items to match
+)
+
+// ServerSyncModeOrDefault returns the sync mode, defaulting to the plain
+// import every server destination used before modes existed.
+func ServerSyncModeOrDefault(mode string) string {
+	if mode == "" {
+		return ServerSyncAppend
+	}
+	return mode
+}
+
+// S3CredentialModeOrDefault returns the credential mode, defaulting to the
+// static keys every destination used before modes existed.
+func S3CredentialModeOrDefault(mode string) string {
+	if mode == "" {
+		return "static"
+	}
+	return mode
+}
+
+// S3UsesStaticKeys reports whether a credential mode signs with, or starts
+// from, the stored access key pair.
+func S3UsesStaticKeys(mode string) bool {
+	mode = S3CredentialModeOrDefault(mode)
+	return mode == "static" || mode == "assume_role"
+}
+
+func (d *BackupDestination) s3CredentialMode() string {
+	if d.Type != "s3" {
+		return ""
+	}
+	return S3CredentialModeOrDefault(d.S3CredentialMode)
+}
+
+// DefaultEmailMaxSizeMB is the attachment cap used when none is configured.
+// Most providers reject messages above 20-25 MB, and base64 adds a third.
+const DefaultEmailMaxSizeMB = 10
+
+// DefaultEmailPort returns the submission port for a SMTP TLS mode.
+func DefaultEmailPort(tlsMode string) int {
+	if tlsMode == "implicit" {
+		return 465
+	}
+	return 587
+}
+
+// ParseEmailRecipients splits a recipient list on commas, semicolons and
+// newlines and returns the bare addresses without duplicates.
+func ParseEmailRecipients(raw string) ([]string, error) {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r'
+	})
+	var recipients []string
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		address, err := mail.ParseAddress(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q", field)
+		}
+		if !slices.Contains(recipients, address.Address) {
+			recipients = append(recipients, address.Address)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	return recipients, nil
+}
+
+// azureAuthMode tells the UI whether an account key or a SAS token is stored
+// without revealing it.
+func (d *BackupDestination) azureAuthMode() string {
+	if d.Type != "azblob" {
+		return ""
+	}
+	if d.AzureAccountKey != "" {
+		return "shared_key"
+	}
+	return "sas"
+}
+
+// gcsClientEmail shows which service account is configured; the key itself
+// never leaves the server.
+func (d *BackupDestination) gcsClientEmail() string {
+	if d.Type != "gcs" || d.GCSCredentials == "" {
+		return ""
+	}
+	email, _ := ParseGCSServiceAccount(d.GCSCredentials)
+	return email
+}
+
+// ParseGCSServiceAccount checks that raw is a service account JSON key and
+// returns its client email.
+func ParseGCSServiceAccount(raw string) (string, error) {
+	var key struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal([]byte(raw), &key); err != nil {
+		return "", fmt.Errorf("service account key must be JSON")
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return "", fmt.Errorf("service account key must contain type service_account, client_email and private_key")
+	}
+	return key.ClientEmail, nil
+}
+
+// GitBranchOrDefault returns the configured branch, defaulting to main.
+func GitBranchOrDefault(branch string) string {
+	if branch == "" {
+		return "main"
+	}
+	return branch
+}
+
+// IsGitHTTPSURL reports whether a git remote uses HTTPS; every other
+// accepted form (ssh:// or scp-like) authenticates with an SSH key.
+func IsGitHTTPSURL(raw string) bool {
+	return strings.HasPrefix(strings.ToLower(raw), "https://")
+}
+
+// gitAuthMode tells the UI whether the remote authenticates over HTTPS or
+// SSH without revealing the stored credential.
+func (d *BackupDestination) gitAuthMode() string {
+	if d.Type != "git" {
+		return ""
+	}
+	if IsGitHTTPSURL(d.GitURL) {
+		return "https"
+	}
+	return "ssh"
+}
+
+// rcloneRemotes lists the remote names defined by the stored config so the UI
+// can show them without returning the config itself.
+func (d *BackupDestination) rcloneRemotes() []string {
+	if d.Type != "rclone" || d.RcloneConfig == "" {
+		return nil
+	}
+	remotes, _ := ParseRcloneConfig(d.RcloneConfig)
+	return remotes
+}
+
+// ParseRcloneConfig returns the remote names ([section] headers) of an
+// rclone.conf. An encrypted config cannot be used non-interactively and is
+// rejected.
+func ParseRcloneConfig(raw string) ([]string, error) {
+	var remotes []string
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "RCLONE_ENCRYPT_V0:") {
+			return nil, fmt.Errorf("encrypted rclone config is not supported")
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if name := strings.TrimSpace(line[1 : len(line)-1]); name != "" {
+				remotes = append(remotes, name)
+			}
+		}
+	}
+	if len(remotes) == 0 {
+		return nil, fmt.Errorf("rclone config must define at least one [remote] section")
+	}
+	return remotes, nil
+}
+
+// WebDAVAuthModeOrDefault returns the WebDAV auth mode, defaulting to the
+// Basic auth every destination used before modes existed.
+func WebDAVAuthModeOrDefault(mode string) string {
+	if mode == "" {
+		return "basic"
+	}
+	return mode
+}
+
+func (d *BackupDestination) webdavAuthMode() string {
+	if d.Type != "webdav" {
+		return ""
+	}
+	return WebDAVAuthModeOrDefault(d.WebDAVAuthMode)
+}
+
+// sftpAuthMode tells the UI which SFTP credential is stored without
+// revealing it.
+func (d *BackupDestination) sftpAuthMode() string {
+	if d.Type != "sftp" {
+		return ""
+	}
+	if d.SFTPPrivateKey != "" {
+		return "key"
+	}
+	return "password"
+}
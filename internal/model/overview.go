@@ -32,6 +32,11 @@ type OverviewTaskSummary struct {
 	SourceServerName string    `json:"source_server_name"`
 	DestinationCount int       `json:"destination_count"`
 	CreatedAt        time.Time `json:"created_at"`
+	// LastRunAt and LastRunStatus come from the latest run record. NextRunAt
+	// is filled from the live scheduler entry and is nil for manual tasks.
+	LastRunAt     *time.Time `json:"last_run_at" gorm:"-"`
+	LastRunStatus string     `json:"last_run_status" gorm:"-"`
+	NextRunAt     *time.Time `json:"next_run_at" gorm:"-"`
 }
 
 // OverviewLogSummary is the non-sensitive log data used by the dashboard.
@@ -10,24 +10,106 @@ type EnabledRequest struct {
 // backup destination. It deliberately excludes database IDs, timestamps and
 // preloaded associations from the request surface.
 type DestinationRequest struct {
-	Name               string `json:"name"`
-	Type               string `json:"type"`
-	LocalPath          string `json:"local_path"`
-	WebDAVURL          string `json:"webdav_url"`
-	WebDAVUsername     string `json:"webdav_username"`
-	WebDAVPassword     string `json:"webdav_password"`
-	WebDAVPath         string `json:"webdav_path"`
-	S3Endpoint         string `json:"s3_endpoint"`
-	S3Region           string `json:"s3_region"`
-	S3Bucket           string `json:"s3_bucket"`
-	S3AccessKey        string `json:"s3_access_key"`
-	S3SecretKey        string `json:"s3_secret_key"`
-	S3Path             string `json:"s3_path"`
-	TargetServerID     *uint  `json:"target_server_id"`
-	Encrypted          bool   `json:"encrypted"`
-	EncryptionPassword string `json:"encryption_password"`
-	MaxBackupCount     int    `json:"max_backup_count"`
-	Enabled            *bool  `json:"enabled"`
+	Name                   string `json:"name"`
+	Type                   string `json:"type"`
+	LocalPath              string `json:"local_path"`
+	LocalFileMode          string `json:"local_file_mode"`
+	LocalGroup             string `json:"local_group"`
+	LocalDedup             bool   `json:"local_dedup"`
+	WebDAVURL              string `json:"webdav_url"`
+	WebDAVUsername         string `json:"webdav_username"`
+	WebDAVPassword         string `json:"webdav_password"`
+	WebDAVPath             string `json:"webdav_path"`
+	WebDAVTimeout          int    `json:"webdav_timeout"`
+	WebDAVAuthMode         string `json:"webdav_auth_mode"`
+	WebDAVBearerToken      string `json:"webdav_bearer_token"`
+	WebDAVClientCert       string `json:"webdav_client_cert"`
+	WebDAVClientKey        string `json:"webdav_client_key"`
+	WebDAVCACert           string `json:"webdav_ca_cert"`
+	S3Endpoint             string `json:"s3_endpoint"`
+	S3Region               string `json:"s3_region"`
+	S3Bucket               string `json:"s3_bucket"`
+	S3AccessKey            string `json:"s3_access_key"`
+	S3SecretKey            string `json:"s3_secret_key"`
+	S3Path                 string `json:"s3_path"`
+	S3CredentialMode       string `json:"s3_credential_mode"`
+	S3RoleARN              string `json:"s3_role_arn"`
+	S3ExternalID           string `json:"s3_external_id"`
+	S3RoleSessionName      string `json:"s3_role_session_name"`
+	S3WebIdentityTokenFile string `json:"s3_web_identity_token_file"`
+	S3AddressingStyle      string `json:"s3_addressing_style"`
+	S3StorageClass         string `json:"s3_storage_class"`
+	S3Encryption           string `json:"s3_encryption"`
+	S3KMSKeyID             string `json:"s3_kms_key_id"`
+	S3SSECustomerKey       string `json:"s3_sse_customer_key"`
+	S3ObjectLockMode       string `json:"s3_object_lock_mode"`
+	S3ObjectLockDays       int    `json:"s3_object_lock_days"`
+	S3LegalHold            bool   `json:"s3_legal_hold"`
+	S3ChecksumAlgorithm    string `json:"s3_checksum_algorithm"`
+	SFTPHost               string `json:"sftp_host"`
+	SFTPPort               int    `json:"sftp_port"`
+	SFTPUsername           string `json:"sftp_username"`
+	SFTPPassword           string `json:"sftp_password"`
+	SFTPPrivateKey         string `json:"sftp_private_key"`
+	SFTPKeyPassphrase      string `json:"sftp_key_passphrase"`
+	SFTPHostKey            string `json:"sftp_host_key"`
+	SFTPPath               string `json:"sftp_path"`
+	FTPHost                string `json:"ftp_host"`
+	FTPPort                int    `json:"ftp_port"`
+	FTPUsername            string `json:"ftp_username"`
+	FTPPassword            string `json:"ftp_password"`
+	FTPTLSMode             string `json:"ftp_tls_mode"`
+	FTPTLSFingerprint      string `json:"ftp_tls_fingerprint"`
+	FTPDisableEPSV         bool   `json:"ftp_disable_epsv"`
+	FTPPath                string `json:"ftp_path"`
+	SMBHost                string `json:"smb_host"`
+	SMBPort                int    `json:"smb_port"`
+	SMBShare               string `json:"smb_share"`
+	SMBDomain              string `json:"smb_domain"`
+	SMBUsername            string `json:"smb_username"`
+	SMBPassword            string `json:"smb_password"`
+	SMBPath                string `json:"smb_path"`
+	AzureAccountName       string `json:"azure_account_name"`
+	AzureAccountKey        string `json:"azure_account_key"`
+	AzureSASToken          string `json:"azure_sas_token"`
+	AzureEndpoint          string `json:"azure_endpoint"`
+	AzureContainer         string `json:"azure_container"`
+	AzurePrefix            string `json:"azure_prefix"`
+	AzureAccessTier        string `json:"azure_access_tier"`
+	GCSBucket              string `json:"gcs_bucket"`
+	GCSPrefix              string `json:"gcs_prefix"`
+	GCSCredentials         string `json:"gcs_credentials"`
+	GCSEndpoint            string `json:"gcs_endpoint"`
+	RcloneRemote           string `json:"rclone_remote"`
+	RclonePath             string `json:"rclone_path"`
+	RcloneConfig           string `json:"rclone_config"`
+	GitURL                 string `json:"git_url"`
+	GitBranch              string `json:"git_branch"`
+	GitPath                string `json:"git_path"`
+	GitFilename            string `json:"git_filename"`
+	GitUsername            string `json:"git_username"`
+	GitPassword            string `json:"git_password"`
+	GitPrivateKey          string `json:"git_private_key"`
+	GitHostKey             string `json:"git_host_key"`
+	GitAuthorName          string `json:"git_author_name"`
+	GitAuthorEmail         string `json:"git_author_email"`
+	EmailHost              string `json:"email_host"`
+	EmailPort              int    `json:"email_port"`
+	EmailTLSMode           string `json:"email_tls_mode"`
+	EmailUsername          string `json:"email_username"`
+	EmailPassword          string `json:"email_password"`
+	EmailFrom              string `json:"email_from"`
+	EmailRecipients        string `json:"email_recipients"`
+	EmailMaxSizeMB         int    `json:"email_max_size_mb"`
+	TargetServerID         *uint  `json:"target_server_id"`
+	ServerSyncMode         string `json:"server_sync_mode"`
+	ServerPurgeConfirmed   bool   `json:"server_purge_confirmed"`
+	TargetOrganizationID   string `json:"target_organization_id"`
+	TargetCollectionID     string `json:"target_collection_id"`
+	Encrypted              bool   `json:"encrypted"`
+	EncryptionPassword     string `json:"encryption_password"`
+	MaxBackupCount         int    `json:"max_backup_count"`
+	Enabled                *bool  `json:"enabled"`
 }
 
 // ToDestination converts a create request into a persistence model.
@@ -55,23 +137,112 @@ func (r DestinationRequest) applyTo(destination *BackupDestination) {
 	previousType := destination.Type
 	if previousType != "" && previousType != r.Type {
 		// A type change must not retain credentials for the old provider.
-		destination.WebDAVPassword = ""
-		destination.S3AccessKey = ""
-		destination.S3SecretKey = ""
-		destination.EncryptionPassword = ""
+		for _, field := range destination.SensitiveFields() {
+			*field.Value = ""
+		}
 	}
 
 	destination.Name = r.Name
 	destination.Type = r.Type
 	destination.LocalPath = r.LocalPath
+	destination.LocalFileMode = r.LocalFileMode
+	destination.LocalGroup = r.LocalGroup
+	destination.LocalDedup = r.LocalDedup
 	destination.WebDAVURL = r.WebDAVURL
 	destination.WebDAVUsername = r.WebDAVUsername
 	destination.WebDAVPath = r.WebDAVPath
+	destination.WebDAVTimeout = r.WebDAVTimeout
+	destination.WebDAVAuthMode = r.WebDAVAuthMode
+	destination.WebDAVClientCert = r.WebDAVClientCert
+	destination.WebDAVCACert = r.WebDAVCACert
 	destination.S3Endpoint = r.S3Endpoint
 	destination.S3Region = r.S3Region
 	destination.S3Bucket = r.S3Bucket
 	destination.S3Path = r.S3Path
+	destination.S3CredentialMode = r.S3CredentialMode
+	destination.S3RoleARN = r.S3RoleARN
+	destination.S3ExternalID = r.S3ExternalID
+	destination.S3RoleSessionName = r.S3RoleSessionName
+	destination.S3WebIdentityTokenFile = r.S3WebIdentityTokenFile
+	destination.S3AddressingStyle = r.S3AddressingStyle
+	destination.S3StorageClass = r.S3StorageClass
+	destination.S3Encryption = r.S3Encryption
+	destination.S3KMSKeyID = r.S3KMSKeyID
+	destination.S3ObjectLockMode = r.S3ObjectLockMode
+	destination.S3ObjectLockDays = r.S3ObjectLockDays
+	destination.S3LegalHold = r.S3LegalHold
+	destination.S3ChecksumAlgorithm = r.S3ChecksumAlgorithm
+	destination.SFTPHost = r.SFTPHost
+	destination.SFTPPort = r.SFTPPort
+	destination.SFTPUsername = r.SFTPUsername
+	destination.SFTPHostKey = r.SFTPHostKey
+	destination.SFTPPath = r.SFTPPath
+	if destination.Type == "sftp" && destination.SFTPPort == 0 {
+		destination.SFTPPort = 22
+	}
+	destination.FTPHost = r.FTPHost
+	destination.FTPPort = r.FTPPort
+	destination.FTPUsername = r.FTPUsername
+	destination.FTPTLSMode = r.FTPTLSMode
+	destination.FTPTLSFingerprint = r.FTPTLSFingerprint
+	destination.FTPDisableEPSV = r.FTPDisableEPSV
+	destination.FTPPath = r.FTPPath
+	if destination.Type == "ftp" {
+		if destination.FTPTLSMode == "" {
+			destination.FTPTLSMode = "none"
+		}
+		if destination.FTPPort == 0 {
+			destination.FTPPort = DefaultFTPPort(destination.FTPTLSMode)
+		}
+	}
+	destination.SMBHost = r.SMBHost
+	destination.SMBPort = r.SMBPort
+	destination.SMBShare = r.SMBShare
+	destination.SMBDomain = r.SMBDomain
+	destination.SMBUsername = r.SMBUsername
+	destination.SMBPath = r.SMBPath
+	if destination.Type == "smb" && destination.SMBPort == 0 {
+		destination.SMBPort = 445
+	}
+	destination.AzureAccountName = r.AzureAccountName
+	destination.AzureEndpoint = r.AzureEndpoint
+	destination.AzureContainer = r.AzureContainer
+	destination.AzurePrefix = r.AzurePrefix
+	destination.AzureAccessTier = r.AzureAccessTier
+	destination.GCSBucket = r.GCSBucket
+	destination.GCSPrefix = r.GCSPrefix
+	destination.GCSEndpoint = r.GCSEndpoint
+	destination.RcloneRemote = r.RcloneRemote
+	destination.RclonePath = r.RclonePath
+	destination.GitURL = r.GitURL
+	destination.GitBranch = r.GitBranch
+	destination.GitPath = r.GitPath
+	destination.GitFilename = r.GitFilename
+	destination.GitUsername = r.GitUsername
+	destination.GitHostKey = r.GitHostKey
+	destination.GitAuthorName = r.GitAuthorName
+	destination.GitAuthorEmail = r.GitAuthorEmail
+	destination.EmailHost = r.EmailHost
+	destination.EmailPort = r.EmailPort
+	destination.EmailTLSMode = r.EmailTLSMode
+	destination.EmailUsername = r.EmailUsername
+	destination.EmailFrom = r.EmailFrom
+	destination.EmailRecipients = r.EmailRecipients
+	destination.EmailMaxSizeMB = r.EmailMaxSizeMB
+	if destination.Type == "email" {
+		if destination.EmailTLSMode == "" {
+			destination.EmailTLSMode = "starttls"
+		}
+		if destination.EmailPort == 0 {
+			destination.EmailPort = DefaultEmailPort(destination.EmailTLSMode)
+		}
+	}
 	destination.TargetServerID = r.TargetServerID
+	destination.ServerSyncMode = r.ServerSyncMode
+	// The confirmation belongs to purge mode; switching away has to ask again.
+	destination.ServerPurgeConfirmed = r.ServerPurgeConfirmed && r.ServerSyncMode == ServerSyncPurge
+	destination.TargetOrganizationID = r.TargetOrganizationID
+	destination.TargetCollectionID = r.TargetCollectionID
 	destination.Encrypted = r.Encrypted
 	destination.MaxBackupCount = r.MaxBackupCount
 	if !r.Encrypted {
@@ -81,12 +252,94 @@ func (r DestinationRequest) applyTo(destination *BackupDestination) {
 	if r.WebDAVPassword != "" {
 		destination.WebDAVPassword = r.WebDAVPassword
 	}
+	if r.WebDAVBearerToken != "" {
+		destination.WebDAVBearerToken = r.WebDAVBearerToken
+	}
+	if r.WebDAVClientKey != "" {
+		destination.WebDAVClientKey = r.WebDAVClientKey
+	}
+	// Drop credentials the selected mode no longer uses.
+	if destination.Type == "webdav" {
+		switch WebDAVAuthModeOrDefault(destination.WebDAVAuthMode) {
+		case "bearer":
+			destination.WebDAVPassword = ""
+		case "basic", "digest":
+			destination.WebDAVBearerToken = ""
+		case "none":
+			destination.WebDAVPassword = ""
+			destination.WebDAVBearerToken = ""
+		}
+		if destination.WebDAVClientCert == "" {
+			destination.WebDAVClientKey = ""
+		}
+	}
 	if r.S3AccessKey != "" {
 		destination.S3AccessKey = r.S3AccessKey
 	}
 	if r.S3SecretKey != "" {
 		destination.S3SecretKey = r.S3SecretKey
 	}
+	// Modes without an access key pair must not keep one around.
+	if destination.Type == "s3" && !S3UsesStaticKeys(destination.S3CredentialMode) {
+		destination.S3AccessKey = ""
+		destination.S3SecretKey = ""
+	}
+	if r.S3SSECustomerKey != "" {
+		destination.S3SSECustomerKey = r.S3SSECustomerKey
+	}
+	if destination.S3Encryption != "sse-c" {
+		destination.S3SSECustomerKey = ""
+	}
+	// SFTP uses either a password or a private key. Supplying one replaces
+	// the other so a switched authentication method does not linger.
+	if r.SFTPPassword != "" {
+		destination.SFTPPassword = r.SFTPPassword
+		destination.SFTPPrivateKey = ""
+		destination.SFTPKeyPassphrase = ""
+	}
+	if r.SFTPPrivateKey != "" {
+		destination.SFTPPrivateKey = r.SFTPPrivateKey
+		destination.SFTPKeyPassphrase = r.SFTPKeyPassphrase
+		destination.SFTPPassword = ""
+	} else if r.SFTPKeyPassphrase != "" && destination.SFTPPrivateKey != "" {
+		destination.SFTPKeyPassphrase = r.SFTPKeyPassphrase
+	}
+	if r.FTPPassword != "" {
+		destination.FTPPassword = r.FTPPassword
+	}
+	if r.SMBPassword != "" {
+		destination.SMBPassword = r.SMBPassword
+	}
+	// Azure uses either the account key or a SAS token; supplying one drops
+	// the other.
+	if r.AzureAccountKey != "" {
+		destination.AzureAccountKey = r.AzureAccountKey
+		destination.AzureSASToken = ""
+	}
+	if r.AzureSASToken != "" {
+		destination.AzureSASToken = r.AzureSASToken
+		destination.AzureAccountKey = ""
+	}
+	if r.GCSCredentials != "" {
+		destination.GCSCredentials = r.GCSCredentials
+	}
+	if r.RcloneConfig != "" {
+		destination.RcloneConfig = r.RcloneConfig
+	}
+	if r.GitPassword != "" {
+		destination.GitPassword = r.GitPassword
+	}
+	if r.GitPrivateKey != "" {
+		destination.GitPrivateKey = r.GitPrivateKey
+	}
+	// Without a username the relay is used unauthenticated, so a stored
+	// password would only linger.
+	if r.EmailPassword != "" {
+		destination.EmailPassword = r.EmailPassword
+	}
+	if destination.EmailUsername == "" {
+		destination.EmailPassword = ""
+	}
 	if r.EncryptionPassword != "" {
 		destination.EncryptionPassword = r.EncryptionPassword
 	}
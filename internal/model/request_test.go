@@ -32,6 +32,20 @@ func TestDestinationRequestKeepsOmittedCredentialsOnSameType(t *testing.T) {
 	}
 }
 
+func TestDestinationRequestClearsPurgeConfirmationOutsidePurgeMode(t *testing.T) {
+	destination := &BackupDestination{Type: "server", ServerSyncMode: ServerSyncPurge, ServerPurgeConfirmed: true}
+
+	DestinationRequest{Type: "server", ServerSyncMode: ServerSyncMirror, ServerPurgeConfirmed: true}.ApplyTo(destination)
+	if destination.ServerPurgeConfirmed {
+		t.Fatal("purge confirmation should be cleared when the mode is not purge")
+	}
+
+	DestinationRequest{Type: "server", ServerSyncMode: ServerSyncPurge, ServerPurgeConfirmed: true}.ApplyTo(destination)
+	if !destination.ServerPurgeConfirmed {
+		t.Fatal("purge confirmation should be kept in purge mode")
+	}
+}
+
 func TestDestinationRequestClearsDisabledEncryptionPassword(t *testing.T) {
 	destination := &BackupDestination{Type: "local", Encrypted: true, EncryptionPassword: "existing"}
 
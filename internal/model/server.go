@@ -101,6 +101,20 @@ type ServerResponse struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+// OrganizationResponse is an organization of a server's account with the
+// collections the account can access.
+type OrganizationResponse struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Collections []CollectionResponse `json:"collections"`
+}
+
+// CollectionResponse 组织中的集合
+type CollectionResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 // ServerRequest 服务器请求 DTO
 type ServerRequest struct {
 	Name           string `json:"name"`
@@ -1,45 +1,128 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // BackupTask 备份任务配置
 type BackupTask struct {
-	ID               uint      `gorm:"primaryKey" json:"id"`
-	Name             string    `gorm:"size:100;not null" json:"name"`
-	SourceServerID   uint      `gorm:"not null" json:"source_server_id"`
-	CronExpression   string    `gorm:"size:100" json:"cron_expression"`
-	FilenameTemplate string    `gorm:"size:255" json:"filename_template"`
-	Enabled          bool      `gorm:"default:true" json:"enabled"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	Name                string    `gorm:"size:100;not null" json:"name"`
+	Type                string    `gorm:"size:20" json:"type"` // backup 或 replication，空值为 backup
+	SourceServerID      uint      `gorm:"not null" json:"source_server_id"`
+	SourceDestinationID *uint     `gorm:"index" json:"source_destination_id"` // 复制任务读取备份文件的目标
+	CronExpression      string    `gorm:"size:100" json:"cron_expression"`
+	FilenameTemplate    string    `gorm:"size:255" json:"filename_template"`
+	Timezone            string    `gorm:"size:64" json:"timezone"`          // IANA 时区，空值使用进程本地时间
+	JitterMinutes       int       `gorm:"default:0" json:"jitter_minutes"`  // 定时触发后随机延迟的上限（分钟）
+	StaggerMinutes      int       `gorm:"default:0" json:"stagger_minutes"` // 相同 Cron 的任务在此窗口内错峰（分钟）
+	TriggerTaskID       *uint     `gorm:"index" json:"trigger_task_id"`     // 上游任务，结束后按 TriggerOn 触发本任务
+	TriggerOn           string    `gorm:"size:20" json:"trigger_on"`
+	Enabled             bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
 
 	// 关联
-	SourceServer ServerConfig        `json:"source_server"`
-	Destinations []BackupDestination `gorm:"many2many:task_destinations;" json:"destinations"`
+	SourceServer      ServerConfig        `json:"source_server"`
+	SourceDestination *BackupDestination  `gorm:"foreignKey:SourceDestinationID" json:"source_destination"`
+	Destinations      []BackupDestination `gorm:"many2many:task_destinations;" json:"destinations"`
+	BlackoutWindows   []BlackoutWindow    `gorm:"foreignKey:TaskID" json:"blackout_windows"`
+}
+
+// Task types. A replication task copies the backup files that other tasks
+// wrote to SourceDestinationID into its destinations instead of exporting.
+const (
+	TaskTypeBackup      = "backup"
+	TaskTypeReplication = "replication"
+)
+
+// TaskTypeOrDefault treats the empty type of tasks created before replication
+// existed as a backup task.
+func TaskTypeOrDefault(taskType string) string {
+	if taskType == "" {
+		return TaskTypeBackup
+	}
+	return taskType
+}
+
+// IsReplication reports whether the task copies existing backup files.
+func (t *BackupTask) IsReplication() bool {
+	return TaskTypeOrDefault(t.Type) == TaskTypeReplication
+}
+
+// Location returns the timezone used for the task's schedule and blackout
+// windows. An invalid stored name falls back to the process local time.
+func (t *BackupTask) Location() *time.Location {
+	if t.Timezone == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// ValidateTimezone 校验任务时区名称，空值表示使用进程本地时间
+func ValidateTimezone(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("时区名称过长")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("时区无效: %s", name)
+	}
+	return nil
 }
 
 // TaskRequest 任务请求 DTO
 type TaskRequest struct {
-	Name             string `json:"name"`
-	SourceServerID   uint   `json:"source_server_id"`
-	CronExpression   string `json:"cron_expression"`
-	FilenameTemplate string `json:"filename_template"`
-	Enabled          *bool  `json:"enabled"`
-	DestinationIDs   []uint `json:"destination_ids"`
+	Name           string `json:"name"`
+	SourceServerID uint   `json:"source_server_id"`
+	// Type is optional; omitting it on update keeps the stored type.
+	Type                string `json:"type"`
+	SourceDestinationID *uint  `json:"source_destination_id"`
+	CronExpression      string `json:"cron_expression"`
+	FilenameTemplate    string `json:"filename_template"`
+	Enabled             *bool  `json:"enabled"`
+	DestinationIDs      []uint `json:"destination_ids"`
+	// Timezone and BlackoutWindows are optional; omitting them on update
+	// keeps the stored values so older clients do not reset them.
+	Timezone        *string                 `json:"timezone"`
+	BlackoutWindows []BlackoutWindowRequest `json:"blackout_windows"`
+	JitterMinutes   *int                    `json:"jitter_minutes"`
+	StaggerMinutes  *int                    `json:"stagger_minutes"`
+	// TriggerTaskID 0 clears the dependency; omitting it keeps the stored one.
+	TriggerTaskID *uint  `json:"trigger_task_id"`
+	TriggerOn     string `json:"trigger_on"`
 }
 
 // TaskResponse 任务响应 DTO（隐藏敏感数据）
 type TaskResponse struct {
-	ID               uint                  `json:"id"`
-	Name             string                `json:"name"`
-	SourceServerID   uint                  `json:"source_server_id"`
-	CronExpression   string                `json:"cron_expression"`
-	FilenameTemplate string                `json:"filename_template"`
-	Enabled          bool                  `json:"enabled"`
-	CreatedAt        time.Time             `json:"created_at"`
-	UpdatedAt        time.Time             `json:"updated_at"`
-	SourceServer     ServerResponse        `json:"source_server"`
-	Destinations     []DestinationResponse `json:"destinations"`
+	ID                  uint                  `json:"id"`
+	Name                string                `json:"name"`
+	Type                string                `json:"type"`
+	SourceServerID      uint                  `json:"source_server_id"`
+	SourceDestinationID *uint                 `json:"source_destination_id"`
+	CronExpression      string                `json:"cron_expression"`
+	FilenameTemplate    string                `json:"filename_template"`
+	Timezone            string                `json:"timezone"`
+	JitterMinutes       int                   `json:"jitter_minutes"`
+	StaggerMinutes      int                   `json:"stagger_minutes"`
+	TriggerTaskID       *uint                 `json:"trigger_task_id"`
+	TriggerOn           string                `json:"trigger_on"`
+	Enabled             bool                  `json:"enabled"`
+	CreatedAt           time.Time             `json:"created_at"`
+	UpdatedAt           time.Time             `json:"updated_at"`
+	SourceServer        ServerResponse        `json:"source_server"`
+	SourceDestination   *DestinationResponse  `json:"source_destination"`
+	Destinations        []DestinationResponse `json:"destinations"`
+	BlackoutWindows     []BlackoutWindow      `json:"blackout_windows"`
 }
 
 // ToResponse 转换为响应结构
@@ -48,16 +131,95 @@ func (t *BackupTask) ToResponse() TaskResponse {
 	for i, d := range t.Destinations {
 		dests[i] = d.ToResponse()
 	}
+	windows := t.BlackoutWindows
+	if windows == nil {
+		windows = []BlackoutWindow{}
+	}
+	var sourceDestination *DestinationResponse
+	if t.SourceDestination != nil {
+		response := t.SourceDestination.ToResponse()
+		sourceDestination = &response
+	}
 	return TaskResponse{
-		ID:               t.ID,
-		Name:             t.Name,
-		SourceServerID:   t.SourceServerID,
-		CronExpression:   t.CronExpression,
-		FilenameTemplate: NormalizeFilenameTemplate(t.FilenameTemplate),
-		Enabled:          t.Enabled,
-		CreatedAt:        t.CreatedAt,
-		UpdatedAt:        t.UpdatedAt,
-		SourceServer:     t.SourceServer.ToResponse(),
-		Destinations:     dests,
+		ID:                  t.ID,
+		Name:                t.Name,
+		Type:                TaskTypeOrDefault(t.Type),
+		SourceServerID:      t.SourceServerID,
+		SourceDestinationID: t.SourceDestinationID,
+		CronExpression:      t.CronExpression,
+		FilenameTemplate:    NormalizeFilenameTemplate(t.FilenameTemplate),
+		Timezone:            t.Timezone,
+		JitterMinutes:       t.JitterMinutes,
+		StaggerMinutes:      t.StaggerMinutes,
+		TriggerTaskID:       t.TriggerTaskID,
+		TriggerOn:           t.TriggerOn,
+		Enabled:             t.Enabled,
+		CreatedAt:           t.CreatedAt,
+		UpdatedAt:           t.UpdatedAt,
+		SourceServer:        t.SourceServer.ToResponse(),
+		SourceDestination:   sourceDestination,
+		Destinations:        dests,
+		BlackoutWindows:     windows,
 	}
 }
+
+// MaxScheduleSpreadMinutes 限制抖动和错峰窗口，避免延后到下一次触发之后
+const MaxScheduleSpreadMinutes = 180
+
+// ValidateScheduleSpread 校验抖动与错峰分钟数
+func ValidateScheduleSpread(jitterMinutes, staggerMinutes int) error {
+	if jitterMinutes < 0 || jitterMinutes > MaxScheduleSpreadMinutes {
+		return fmt.Errorf("随机延迟必须在 0 到 %d 分钟之间", MaxScheduleSpreadMinutes)
+	}
+	if staggerMinutes < 0 || staggerMinutes > MaxScheduleSpreadMinutes {
+		return fmt.Errorf("错峰窗口必须在 0 到 %d 分钟之间", MaxScheduleSpreadMinutes)
+	}
+	return nil
+}
+
+// Dependency triggers decide which upstream results start a downstream task.
+const (
+	TriggerOnSuccess    = "success"
+	TriggerOnFailure    = "failure"
+	TriggerOnCompletion = "completion"
+)
+
+// ValidateTriggerOn 校验依赖触发条件
+func ValidateTriggerOn(triggerOn string) error {
+	switch triggerOn {
+	case TriggerOnSuccess, TriggerOnFailure, TriggerOnCompletion:
+		return nil
+	default:
+		return fmt.Errorf("触发条件必须是 success、failure 或 completion")
+	}
+}
+
+// TriggeredBy reports whether an upstream run that ended with status starts
+// this task. Cancelled runs never trigger dependents: stopping the upstream
+// is taken as stopping the chain.
+func (t *BackupTask) TriggeredBy(status string) bool {
+	switch t.TriggerOn {
+	case TriggerOnSuccess:
+		return status == "success"
+	case TriggerOnFailure:
+		return status == "failed"
+	case TriggerOnCompletion:
+		return status == "success" || status == "failed"
+	default:
+		return false
+	}
+}
+
+// CronPreviewRequest 预览 Cron 表达式触发时间的请求
+type CronPreviewRequest struct {
+	CronExpression string `json:"cron_expression"`
+	Timezone       string `json:"timezone"`
+	Count          int    `json:"count"`
+}
+
+// SchedulePreview lists upcoming fire times in the server timezone.
+type SchedulePreview struct {
+	Timezone  string      `json:"timezone"`
+	Scheduled bool        `json:"scheduled"`
+	NextRuns  []time.Time `json:"next_runs"`
+}
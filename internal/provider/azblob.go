@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/safety"
+)
+
+// AzureBlobProvider Azure Blob 存储提供者
+type AzureBlobProvider struct{}
+
+// NewAzureBlobProvider 创建 Azure Blob 存储提供者
+func NewAzureBlobProvider() *AzureBlobProvider {
+	return &AzureBlobProvider{}
+}
+
+// Type 返回提供者类型
+func (p *AzureBlobProvider) Type() string {
+	return "azblob"
+}
+
+// Backup 执行 Azure Blob 上传，返回最终存储路径
+func (p *AzureBlobProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	fail := func(err error) (string, error) {
+		ctx.AddLog("azblob", "Azure Blob 上传失败: "+err.Error())
+		return "", err
+	}
+	ctx.AddLog("azblob", fmt.Sprintf("开始 Azure Blob 上传: %s", dest.AzureContainer))
+
+	client, err := newAzureBlobClient(dest)
+	if err != nil {
+		return fail(err)
+	}
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 5*time.Minute)
+	defer cancel()
+
+	file, err := os.Open(ctx.SourceFile)
+	if err != nil {
+		return fail(fmt.Errorf("failed to open source file: %w", err))
+	}
+	defer file.Close()
+
+	name := azureBlobPrefix(dest) + renderBackupFilename(ctx)
+	options := &azblob.UploadFileOptions{}
+	if dest.AzureAccessTier != "" {
+		options.AccessTier = to.Ptr(blob.AccessTier(dest.AzureAccessTier))
+	}
+	if _, err := client.UploadFile(requestCtx, dest.AzureContainer, name, file, options); err != nil {
+		return fail(fmt.Errorf("failed to upload to Azure Blob: %w", err))
+	}
+
+	ctx.AddLog("azblob", fmt.Sprintf("Azure Blob 上传完成: %s", name))
+	return fmt.Sprintf("azblob://%s/%s", dest.AzureContainer, name), nil
+}
+
+// Test verifies the credentials by listing the configured prefix. Listing is
+// the permission retention needs, and works with container-scoped SAS tokens.
+func (p *AzureBlobProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	client, err := newAzureBlobClient(dest)
+	if err != nil {
+		return fmt.Errorf("Azure Blob connection test failed: %w", err)
+	}
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx), 30*time.Second)
+	defer cancel()
+
+	pager := client.NewListBlobsFlatPager(dest.AzureContainer, &azblob.ListBlobsFlatOptions{
+		Prefix:     to.Ptr(azureBlobPrefix(dest)),
+		MaxResults: to.Ptr(int32(1)),
+	})
+	if _, err := pager.NextPage(requestCtx); err != nil {
+		return fmt.Errorf("Azure Blob connection test failed: %w", err)
+	}
+	return nil
+}
+
+// Cleanup 清理超出保留数量的旧备份
+func (p *AzureBlobProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
+	if maxCount <= 0 {
+		return 0, nil
+	}
+
+	dest := ctx.Destination
+	client, err := newAzureBlobClient(dest)
+	if err != nil {
+		return 0, err
+	}
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 2*time.Minute)
+	defer cancel()
+
+	prefix := azureBlobPrefix(dest)
+	var backups []*container.BlobItem
+	pager := client.NewListBlobsFlatPager(dest.AzureContainer, &azblob.ListBlobsFlatOptions{Prefix: to.Ptr(prefix)})
+	for pager.More() {
+		page, err := pager.NextPage(requestCtx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list blobs: %w", err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			relative := strings.TrimPrefix(*item.Name, prefix)
+			// A flat listing is recursive. Retention is scoped to the configured
+			// prefix, so never delete matching blobs in nested "directories".
+			if strings.Contains(relative, "/") || !matchesBackupFilename(relative, ctx) {
+				continue
+			}
+			backups = append(backups, item)
+		}
+	}
+	if len(backups) <= maxCount {
+		return 0, nil
+	}
+
+	// 按修改时间降序排序（处理 nil 情况）
+	sort.Slice(backups, func(i, j int) bool {
+		left, right := azureBlobModified(backups[i]), azureBlobModified(backups[j])
+		if left == nil {
+			return false
+		}
+		if right == nil {
+			return true
+		}
+		return left.After(*right)
+	})
+
+	deleted := 0
+	var deleteErrors []error
+	for i := maxCount; i < len(backups); i++ {
+		name := *backups[i].Name
+		_, err := client.DeleteBlob(requestCtx, dest.AzureContainer, name, &azblob.DeleteBlobOptions{
+			DeleteSnapshots: to.Ptr(blob.DeleteSnapshotsOptionTypeInclude),
+		})
+		if err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		deleted++
+	}
+	if len(deleteErrors) > 0 {
+		return deleted, fmt.Errorf("failed to delete some Azure blobs: %w", errors.Join(deleteErrors...))
+	}
+	return deleted, nil
+}
+
+// newAzureBlobClient authenticates with the account key when one is stored,
+// otherwise with the SAS token appended to the service URL.
+func newAzureBlobClient(dest model.BackupDestination) (*azblob.Client, error) {
+	serviceURL, err := azureBlobServiceURL(dest)
+	if err != nil {
+		return nil, err
+	}
+	if dest.AzureAccountKey != "" {
+		credential, err := azblob.NewSharedKeyCredential(dest.AzureAccountName, dest.AzureAccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Azure account key: %w", err)
+		}
+		return azblob.NewClientWithSharedKeyCredential(serviceURL, credential, nil)
+	}
+	if dest.AzureSASToken != "" {
+		return azblob.NewClientWithNoCredential(serviceURL+"?"+strings.TrimPrefix(dest.AzureSASToken, "?"), nil)
+	}
+	return nil, fmt.Errorf("Azure account key or SAS token is required")
+}
+
+// azureBlobServiceURL returns the blob endpoint. A custom endpoint covers
+// Azurite, sovereign clouds and private endpoints.
+func azureBlobServiceURL(dest model.BackupDestination) (string, error) {
+	if dest.AzureEndpoint == "" {
+		return fmt.Sprintf("https://%s.blob.core.windows.net/", dest.AzureAccountName), nil
+	}
+	if err := safety.ValidateURL(dest.AzureEndpoint, "azure_endpoint", true); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(dest.AzureEndpoint, "/") + "/", nil
+}
+
+func azureBlobPrefix(dest model.BackupDestination) string {
+	prefix := strings.Trim(dest.AzurePrefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+func azureBlobModified(item *container.BlobItem) *time.Time {
+	if item.Properties == nil {
+		return nil
+	}
+	return item.Properties.LastModified
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
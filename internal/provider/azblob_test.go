@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// Azurite's well-known development account key.
+const azuriteAccountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+type stubBlob struct {
+	body     []byte
+	tier     string
+	modified time.Time
+}
+
+// azureBlobStub emulates the Put Blob, List Blobs and Delete Blob operations
+// of a path-style (Azurite) endpoint for the account "devstoreaccount1".
+type azureBlobStub struct {
+	mu    sync.Mutex
+	blobs map[string]*stubBlob
+	auth  []string
+}
+
+func newAzureBlobStub(t *testing.T) (*azureBlobStub, *httptest.Server) {
+	t.Helper()
+	stub := &azureBlobStub{blobs: make(map[string]*stubBlob)}
+	server := httptest.NewServer(http.HandlerFunc(stub.serveHTTP))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (s *azureBlobStub) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey devstoreaccount1:"):
+		s.auth = append(s.auth, "shared_key")
+	case r.URL.Query().Get("sig") == "signature":
+		s.auth = append(s.auth, "sas")
+	default:
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Path layout: /devstoreaccount1/<container>/<blob name>
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/devstoreaccount1/"), "/", 2)
+	if parts[0] != "vault" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut && len(parts) == 2:
+		body, _ := io.ReadAll(r.Body)
+		s.blobs[parts[1]] = &stubBlob{body: body, tier: r.Header.Get("x-ms-access-tier"), modified: time.Now().UTC()}
+		w.Header().Set("ETag", `"0x1"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && len(parts) == 2:
+		if _, ok := s.blobs[parts[1]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.blobs, parts[1])
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodGet && r.URL.Query().Get("comp") == "list":
+		s.writeList(w, r.URL.Query().Get("prefix"))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (s *azureBlobStub) writeList(w http.ResponseWriter, prefix string) {
+	type properties struct {
+		LastModified  string `xml:"Last-Modified"`
+		ContentLength int    `xml:"Content-Length"`
+		BlobType      string `xml:"BlobType"`
+	}
+	type blobItem struct {
+		Name       string     `xml:"Name"`
+		Properties properties `xml:"Properties"`
+	}
+	type enumeration struct {
+		XMLName    xml.Name   `xml:"EnumerationResults"`
+		Prefix     string     `xml:"Prefix"`
+		Blobs      []blobItem `xml:"Blobs>Blob"`
+		NextMarker string     `xml:"NextMarker"`
+	}
+
+	result := enumeration{Prefix: prefix}
+	var names []string
+	for name := range s.blobs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result.Blobs = append(result.Blobs, blobItem{Name: name, Properties: properties{
+			LastModified:  s.blobs[name].modified.Format(http.TimeFormat),
+			ContentLength: len(s.blobs[name].body),
+			BlobType:      "BlockBlob",
+		}})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (s *azureBlobStub) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.blobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func testAzureBlobDestination(server *httptest.Server) model.BackupDestination {
+	return model.BackupDestination{
+		Type:             "azblob",
+		AzureAccountName: "devstoreaccount1",
+		AzureAccountKey:  azuriteAccountKey,
+		AzureEndpoint:    server.URL + "/devstoreaccount1",
+		AzureContainer:   "vault",
+		AzurePrefix:      "/nightly/",
+		AzureAccessTier:  "Cool",
+	}
+}
+
+func TestAzureBlobProviderUploadsAndAppliesRetention(t *testing.T) {
+	stub, server := newAzureBlobStub(t)
+	dest := testAzureBlobDestination(server)
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	provider := NewAzureBlobProvider()
+	for _, timestamp := range []string{"20251204020000", "20251205020000", "20251206020000"} {
+		remotePath, err := provider.Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   timestamp,
+			Destination: dest,
+		})
+		if err != nil {
+			t.Fatalf("backup %s: %v", timestamp, err)
+		}
+		want := "azblob://vault/nightly/bitwarden_encrypted_export_" + timestamp + ".json"
+		if remotePath != want {
+			t.Fatalf("remote path = %q, want %q", remotePath, want)
+		}
+		stub.mu.Lock()
+		blob := stub.blobs["nightly/bitwarden_encrypted_export_"+timestamp+".json"]
+		blob.modified, _ = time.Parse("20060102150405", timestamp)
+		stub.mu.Unlock()
+		if blob.tier != "Cool" || string(blob.body) != `{"items":[]}` {
+			t.Fatalf("unexpected stored blob: tier=%q body=%q", blob.tier, blob.body)
+		}
+	}
+	// A matching blob in a nested prefix is outside the retention scope.
+	stub.mu.Lock()
+	stub.blobs["nightly/archive/bitwarden_encrypted_export_20250101020000.json"] = &stubBlob{modified: time.Unix(0, 0)}
+	stub.mu.Unlock()
+
+	deleted, err := provider.Cleanup(BackupContext{Context: context.Background(), TaskName: "Nightly", Destination: dest}, 2)
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+	want := "nightly/archive/bitwarden_encrypted_export_20250101020000.json,nightly/bitwarden_encrypted_export_20251205020000.json,nightly/bitwarden_encrypted_export_20251206020000.json"
+	if got := strings.Join(stub.names(), ","); got != want {
+		t.Fatalf("remaining blobs = %s", got)
+	}
+}
+
+func TestAzureBlobProviderTestUsesSASToken(t *testing.T) {
+	stub, server := newAzureBlobStub(t)
+	dest := testAzureBlobDestination(server)
+	dest.AzureAccountKey = ""
+	dest.AzureSASToken = "?sv=2022-11-02&sp=rwdl&sig=signature"
+
+	if err := NewAzureBlobProvider().Test(context.Background(), dest); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	if len(stub.auth) != 1 || stub.auth[0] != "sas" {
+		t.Fatalf("requests authenticated with %v, want one SAS request", stub.auth)
+	}
+}
+
+func TestAzureBlobProviderTestReportsRejectedCredentials(t *testing.T) {
+	_, server := newAzureBlobStub(t)
+	dest := testAzureBlobDestination(server)
+	dest.AzureAccountKey = ""
+	dest.AzureSASToken = "sv=2022-11-02&sig=wrong"
+
+	err := NewAzureBlobProvider().Test(context.Background(), dest)
+	if err == nil || !strings.Contains(err.Error(), "Azure Blob connection test failed") {
+		t.Fatalf("Test() error = %v, want connection failure", err)
+	}
+}
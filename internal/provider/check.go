@@ -0,0 +1,28 @@
+package provider
+
+import "fmt"
+
+// connectionChecks collects the steps of a connection test. Tests stop at the
+// first failed step because the later ones depend on it.
+type connectionChecks []ConnectionCheck
+
+// run records the outcome of one step and reports whether it passed.
+func (c *connectionChecks) run(name string, step func() (string, error)) bool {
+	detail, err := step()
+	if err != nil {
+		*c = append(*c, ConnectionCheck{Name: name, Detail: err.Error()})
+		return false
+	}
+	*c = append(*c, ConnectionCheck{Name: name, OK: true, Detail: detail})
+	return true
+}
+
+// err turns the first failed step into the error ConnectionTester returns.
+func (c connectionChecks) err(label string) error {
+	for _, check := range c {
+		if !check.OK {
+			return fmt.Errorf("%s connection test failed: %s: %s", label, check.Name, check.Detail)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Helpers shared by the providers that drive an external CLI (rclone, git).
+
+var (
+	commandANSIRegex = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	// Lines that echo configuration values, e.g. from -vv or a config dump.
+	commandSensitiveLineRegex = regexp.MustCompile(`(?i)(?:pass(?:word)?\d?|token|secret|private_key|credentials?)\s*[:=]`)
+	commandTokenRegex         = regexp.MustCompile(`[a-zA-Z0-9+/_-]{64,}`)
+)
+
+// sanitizeCommandOutput 统一脱敏外部命令输出，规则与 sanitizeBWOutput 一致：
+// 移除 ANSI 序列和疑似回显凭据的整行，掩码长 token；此外替换已知的凭据值。
+func sanitizeCommandOutput(s string, secrets []string) string {
+	s = commandANSIRegex.ReplaceAllString(s, "")
+	var cleaned []string
+	for _, line := range strings.Split(s, "\n") {
+		if !commandSensitiveLineRegex.MatchString(line) {
+			cleaned = append(cleaned, line)
+		}
+	}
+	s = strings.Join(cleaned, "\n")
+	// Longest first so a secret containing another one is masked as a whole.
+	secrets = append([]string(nil), secrets...)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "***")
+		}
+	}
+	s = commandTokenRegex.ReplaceAllString(s, "***")
+	return strings.TrimSpace(s)
+}
+
+// commandEnv returns the process environment without application secrets and
+// without variables matching dropPrefixes, which a CLI would otherwise treat
+// as configuration overrides.
+func commandEnv(dropPrefixes ...string) []string {
+	env := make([]string, 0, len(os.Environ()))
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(key, "BW_") || strings.HasPrefix(key, "BITWARDEN_BACKUP_") {
+			continue
+		}
+		dropped := false
+		for _, prefix := range dropPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			env = append(env, entry)
+		}
+	}
+	return env
+}
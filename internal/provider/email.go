@@ -0,0 +1,331 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+const emailDialTimeout = 30 * time.Second
+
+// EmailProvider 通过 SMTP 以附件形式发送加密导出
+type EmailProvider struct {
+	// rootCAs replaces the system roots when set; tests use it to trust
+	// their own server.
+	rootCAs *x509.CertPool
+}
+
+// NewEmailProvider 创建邮件存储提供者
+func NewEmailProvider() *EmailProvider {
+	return &EmailProvider{}
+}
+
+// Type 返回提供者类型
+func (p *EmailProvider) Type() string {
+	return "email"
+}
+
+// Backup 发送带附件的邮件，返回收件人和附件名
+func (p *EmailProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	fail := func(err error) (string, error) {
+		ctx.AddLog("email", "邮件发送失败: "+err.Error())
+		return "", err
+	}
+	// validateDestination already refuses plain exports; check again so a
+	// destination saved before that rule never mails a readable vault.
+	if !dest.Encrypted {
+		return fail(fmt.Errorf("email destinations only send encrypted exports"))
+	}
+
+	from, recipients, err := emailAddresses(dest)
+	if err != nil {
+		return fail(err)
+	}
+	filename := renderBackupFilename(ctx)
+	attachment, err := readEmailAttachment(ctx.SourceFile, emailMaxSize(dest))
+	if err != nil {
+		return fail(err)
+	}
+	message, err := buildEmailMessage(ctx, from, recipients, filename, attachment)
+	if err != nil {
+		return fail(err)
+	}
+	ctx.AddLog("email", fmt.Sprintf("开始发送邮件: %s（附件 %d 字节）", strings.Join(recipients, ", "), len(attachment)))
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 5*time.Minute)
+	defer cancel()
+	client, closeClient, err := p.dial(requestCtx, dest)
+	if err != nil {
+		return fail(err)
+	}
+	defer closeClient()
+
+	if err := emailEnvelope(client, from, recipients); err != nil {
+		return fail(err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fail(fmt.Errorf("SMTP DATA failed: %w", err))
+	}
+	if _, err := writer.Write(message); err != nil {
+		_ = writer.Close()
+		return fail(fmt.Errorf("failed to send message: %w", err))
+	}
+	// The server only accepts the message once it answers the final dot.
+	if err := writer.Close(); err != nil {
+		return fail(fmt.Errorf("SMTP server rejected the message: %w", err))
+	}
+	_ = client.Quit()
+
+	ctx.AddLog("email", fmt.Sprintf("邮件发送完成: %s", filename))
+	return "mailto:" + strings.Join(recipients, ",") + "#" + filename, nil
+}
+
+// Test verifies TLS, login and that the server accepts the sender and every
+// recipient, then resets the transaction so no message is sent.
+func (p *EmailProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	from, recipients, err := emailAddresses(dest)
+	if err != nil {
+		return fmt.Errorf("email connection test failed: %w", err)
+	}
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx), 30*time.Second)
+	defer cancel()
+	client, closeClient, err := p.dial(requestCtx, dest)
+	if err != nil {
+		return fmt.Errorf("email connection test failed: %w", err)
+	}
+	defer closeClient()
+
+	if err := emailEnvelope(client, from, recipients); err != nil {
+		return fmt.Errorf("email connection test failed: %w", err)
+	}
+	if err := client.Reset(); err != nil {
+		return fmt.Errorf("email connection test failed: %w", err)
+	}
+	_ = client.Quit()
+	return nil
+}
+
+// Cleanup 邮件无法撤回，不支持保留清理
+func (p *EmailProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
+	return 0, nil
+}
+
+// dial connects, upgrades to TLS and authenticates. Plain SMTP is never used:
+// STARTTLS is required rather than opportunistic. Cancelling ctx closes the
+// socket so a stalled server cannot outlive the run.
+func (p *EmailProvider) dial(ctx context.Context, dest model.BackupDestination) (*smtp.Client, func(), error) {
+	host := strings.TrimSpace(dest.EmailHost)
+	if host == "" {
+		return nil, nil, fmt.Errorf("SMTP host is required")
+	}
+	tlsMode := emailTLSMode(dest)
+	address := net.JoinHostPort(host, strconv.Itoa(emailPort(dest)))
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12, RootCAs: p.rootCAs}
+
+	dialer := net.Dialer{Timeout: emailDialTimeout}
+	rawConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = rawConn.Close() })
+	conn := rawConn
+	if tlsMode == "implicit" {
+		conn = tls.Client(rawConn, tlsConfig)
+	}
+	abort := func(err error) (*smtp.Client, func(), error) {
+		stop()
+		_ = rawConn.Close()
+		return nil, nil, err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return abort(fmt.Errorf("failed to start SMTP session with %s: %w", address, err))
+	}
+	if tlsMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return abort(fmt.Errorf("SMTP server does not offer STARTTLS"))
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return abort(fmt.Errorf("STARTTLS failed: %w", err))
+		}
+	}
+	if dest.EmailUsername != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return abort(fmt.Errorf("SMTP server does not offer AUTH"))
+		}
+		if err := client.Auth(smtp.PlainAuth("", dest.EmailUsername, dest.EmailPassword, host)); err != nil {
+			return abort(fmt.Errorf("SMTP login failed: %w", err))
+		}
+	}
+
+	closeClient := func() {
+		stop()
+		_ = client.Close()
+	}
+	return client, closeClient, nil
+}
+
+func emailEnvelope(client *smtp.Client, from *mail.Address, recipients []string) error {
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %w", from.Address, err)
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", recipient, err)
+		}
+	}
+	return nil
+}
+
+func emailAddresses(dest model.BackupDestination) (*mail.Address, []string, error) {
+	from, err := mail.ParseAddress(dest.EmailFrom)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	recipients, err := model.ParseEmailRecipients(dest.EmailRecipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, recipients, nil
+}
+
+// readEmailAttachment loads the export, refusing files above the cap before
+// anything is sent.
+func readEmailAttachment(sourceFile string, maxSize int64) ([]byte, error) {
+	info, err := os.Stat(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat source file: %w", err)
+	}
+	if info.Size() > maxSize {
+		return nil, fmt.Errorf("export is %d bytes, above the %d MB email size cap", info.Size(), maxSize>>20)
+	}
+	data, err := os.ReadFile(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+	return data, nil
+}
+
+// buildEmailMessage renders a multipart/mixed message with a short text part
+// and the export as a base64 attachment.
+func buildEmailMessage(ctx BackupContext, from *mail.Address, recipients []string, filename string, attachment []byte) ([]byte, error) {
+	taskName := emailHeaderText(ctx.TaskName)
+	subject := "Bitwarden Backup: " + taskName + " " + ctx.Timestamp
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	textPart, err := parts.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf("Bitwarden 加密导出，需使用导出密码解密。\r\n\r\n任务: %s\r\n时间: %s\r\n文件: %s\r\n", taskName, ctx.Timestamp, filename)
+	if ctx.RunID != 0 {
+		text += fmt.Sprintf("运行记录: %d\r\n", ctx.RunID)
+	}
+	qp := quotedprintable.NewWriter(textPart)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	header = textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("application/json", map[string]string{"name": filename}))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	filePart, err := parts.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(attachment)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := filePart.Write([]byte(encoded[:n] + "\r\n")); err != nil {
+			return nil, err
+		}
+		encoded = encoded[n:]
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: %s\r\n", emailMessageID(from.Address))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", parts.Boundary())
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// emailHeaderText drops control characters so a task name cannot inject
+// headers.
+func emailHeaderText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func emailMessageID(from string) string {
+	domain := "localhost"
+	if _, host, ok := strings.Cut(from, "@"); ok && host != "" {
+		domain = host
+	}
+	random := make([]byte, 12)
+	_, _ = rand.Read(random)
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">"
+}
+
+func emailTLSMode(dest model.BackupDestination) string {
+	if dest.EmailTLSMode == "implicit" {
+		return "implicit"
+	}
+	return "starttls"
+}
+
+func emailPort(dest model.BackupDestination) int {
+	if dest.EmailPort == 0 {
+		return model.DefaultEmailPort(emailTLSMode(dest))
+	}
+	return dest.EmailPort
+}
+
+func emailMaxSize(dest model.BackupDestination) int64 {
+	size := dest.EmailMaxSizeMB
+	if size <= 0 {
+		size = model.DefaultEmailMaxSizeMB
+	}
+	return int64(size) << 20
+}
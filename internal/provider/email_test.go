@@ -0,0 +1,274 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// testSMTPServer implements the subset of SMTP the provider uses: EHLO,
+// STARTTLS, AUTH PLAIN, MAIL, RCPT, DATA, RSET and QUIT.
+type testSMTPServer struct {
+	tlsConfig *tls.Config
+	implicit  bool
+
+	mu         sync.Mutex
+	reject     string // recipient answered with 550
+	envelope   []string
+	messages   [][]byte
+	plainLogin bool
+}
+
+func startSMTPServer(t *testing.T, implicit bool) (*testSMTPServer, model.BackupDestination, *EmailProvider) {
+	t.Helper()
+	cert, _ := generateTestCertificate(t)
+	server := &testSMTPServer{tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}}, implicit: implicit}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	roots.AddCert(leaf)
+
+	tlsMode := "starttls"
+	if implicit {
+		tlsMode = "implicit"
+	}
+	dest := model.BackupDestination{
+		Type:            "email",
+		EmailHost:       "127.0.0.1",
+		EmailPort:       listener.Addr().(*net.TCPAddr).Port,
+		EmailTLSMode:    tlsMode,
+		EmailUsername:   "backup",
+		EmailPassword:   "secret",
+		EmailFrom:       "Vault Backup <backup@example.com>",
+		EmailRecipients: "cold@example.com;\nArchive <archive@example.net>",
+		Encrypted:       true,
+	}
+	return server, dest, &EmailProvider{rootCAs: roots}
+}
+
+func (s *testSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	secure := s.implicit
+	if secure {
+		conn = tls.Server(conn, s.tlsConfig)
+	}
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 smtp.test ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			lines := []string{"250-smtp.test"}
+			if !secure {
+				lines = append(lines, "250-STARTTLS")
+			} else {
+				lines = append(lines, "250-AUTH PLAIN")
+			}
+			lines = append(lines, "250 8BITMIME")
+			_ = text.PrintfLine("%s", strings.Join(lines, "\r\n"))
+		case "STARTTLS":
+			_ = text.PrintfLine("220 ready")
+			conn = tls.Server(conn, s.tlsConfig)
+			text = textproto.NewConn(conn)
+			secure = true
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			if string(decoded) != "\x00backup\x00secret" {
+				_ = text.PrintfLine("535 authentication failed")
+				continue
+			}
+			s.mu.Lock()
+			s.plainLogin = !secure
+			s.mu.Unlock()
+			_ = text.PrintfLine("235 ok")
+		case "MAIL", "RCPT":
+			address := arg[strings.Index(arg, "<")+1 : strings.LastIndex(arg, ">")]
+			s.mu.Lock()
+			rejected := s.reject != "" && address == s.reject
+			s.mu.Unlock()
+			if rejected {
+				_ = text.PrintfLine("550 no such user")
+				continue
+			}
+			s.mu.Lock()
+			s.envelope = append(s.envelope, address)
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 ok")
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data)
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 queued")
+		case "RSET":
+			_ = text.PrintfLine("250 ok")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestEmailProviderSendsAttachmentOverSTARTTLS(t *testing.T) {
+	server, dest, provider := startSMTPServer(t, false)
+	content := `{"encrypted":true,"data":"vault"}`
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(content), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	location, err := provider.Backup(BackupContext{
+		Context:     context.Background(),
+		SourceFile:  source,
+		TaskName:    "Nightly\r\nBcc: attacker@example.com",
+		Timestamp:   "20251204020000",
+		Destination: dest,
+	})
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if location != "mailto:cold@example.com,archive@example.net#bitwarden_encrypted_export_20251204020000.json" {
+		t.Fatalf("location = %q", location)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if got := strings.Join(server.envelope, ","); got != "backup@example.com,cold@example.com,archive@example.net" {
+		t.Fatalf("envelope = %s", got)
+	}
+	if server.plainLogin {
+		t.Fatalf("credentials were sent before STARTTLS")
+	}
+	if len(server.messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(server.messages))
+	}
+	message, err := mail.ReadMessage(strings.NewReader(string(server.messages[0])))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if bcc := message.Header.Get("Bcc"); bcc != "" {
+		t.Fatalf("task name injected a header: Bcc=%q", bcc)
+	}
+	_, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("content type: %v", err)
+	}
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	var attachment string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		if part.FileName() == "bitwarden_encrypted_export_20251204020000.json" {
+			data, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+			if err != nil {
+				t.Fatalf("decode attachment: %v", err)
+			}
+			attachment = string(data)
+		}
+	}
+	if attachment != content {
+		t.Fatalf("attachment = %q, want %q", attachment, content)
+	}
+}
+
+func TestEmailProviderRefusesOversizedAndPlainExports(t *testing.T) {
+	server, dest, provider := startSMTPServer(t, false)
+	dest.EmailMaxSizeMB = 1
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, make([]byte, 1<<20+1), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	ctx := BackupContext{Context: context.Background(), SourceFile: source, Timestamp: "20251204020000", Destination: dest}
+	if _, err := provider.Backup(ctx); err == nil || !strings.Contains(err.Error(), "email size cap") {
+		t.Fatalf("Backup() error = %v, want size cap error", err)
+	}
+
+	ctx.Destination.EmailMaxSizeMB = 0
+	ctx.Destination.Encrypted = false
+	if _, err := provider.Backup(ctx); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Fatalf("Backup() error = %v, want encryption error", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.envelope) != 0 {
+		t.Fatalf("a refused export still opened a transaction: %v", server.envelope)
+	}
+}
+
+func TestEmailProviderTestOverImplicitTLS(t *testing.T) {
+	server, dest, provider := startSMTPServer(t, true)
+	if err := provider.Test(context.Background(), dest); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	server.mu.Lock()
+	sent := len(server.messages)
+	server.mu.Unlock()
+	if sent != 0 {
+		t.Fatalf("Test() sent %d messages", sent)
+	}
+
+	wrongPassword := dest
+	wrongPassword.EmailPassword = "wrong"
+	if err := provider.Test(context.Background(), wrongPassword); err == nil || !strings.Contains(err.Error(), "login failed") {
+		t.Fatalf("Test() error = %v, want login failure", err)
+	}
+
+	server.mu.Lock()
+	server.reject = "archive@example.net"
+	server.mu.Unlock()
+	if err := provider.Test(context.Background(), dest); err == nil || !strings.Contains(err.Error(), "archive@example.net") {
+		t.Fatalf("Test() error = %v, want rejected recipient", err)
+	}
+
+	// A server certificate that is not trusted fails the handshake.
+	if err := NewEmailProvider().Test(context.Background(), dest); err == nil {
+		t.Fatalf("Test() trusted an unknown certificate")
+	}
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -18,6 +19,22 @@ func destinationMedium(providerType string) string {
 		return "webdav"
 	case "s3":
 		return "oss"
+	case "sftp":
+		return "sftp"
+	case "ftp":
+		return "ftp"
+	case "smb":
+		return "smb"
+	case "azblob":
+		return "azblob"
+	case "gcs":
+		return "gcs"
+	case "rclone":
+		return "rclone"
+	case "git":
+		return "git"
+	case "email":
+		return "email"
 	default:
 		return ""
 	}
@@ -37,6 +54,12 @@ func renderBackupFilename(ctx BackupContext) string {
 	return filename
 }
 
+// BackupFilename returns the name a backup with the timestamp in ctx gets on
+// ctx.Destination.
+func BackupFilename(ctx BackupContext) string {
+	return renderBackupFilename(ctx)
+}
+
 // backupFilenamePattern returns the exact filename shape generated by the
 // current task/template. The only variable part is the required 14-digit
 // timestamp. Matching against this shape prevents retention from deleting an
@@ -53,7 +76,7 @@ func backupFilenamePattern(ctx BackupContext) *regexp.Regexp {
 		return nil
 	}
 	pattern := regexp.QuoteMeta(filename)
-	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(timeToken), `\d{14}`)
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(timeToken), `(\d{14})`)
 	return regexp.MustCompile("^" + pattern + "$")
 }
 
@@ -75,3 +98,40 @@ func matchesBackupFilename(name string, ctx BackupContext) bool {
 	stem := strings.TrimSuffix(name[len(legacyPrefix):], ".json")
 	return legacyBackupTimestampPattern.MatchString(stem)
 }
+
+// BackupTimestamp returns the {time} value of a filename generated by the
+// task/template in ctx. Legacy names carry no such timestamp.
+func BackupTimestamp(name string, ctx BackupContext) (string, bool) {
+	pattern := backupFilenamePattern(ctx)
+	if pattern == nil {
+		return "", false
+	}
+	match := pattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", false
+	}
+	// A template may contain {time} more than once; every copy must agree.
+	for _, value := range match[2:] {
+		if value != match[1] {
+			return "", false
+		}
+	}
+	return match[1], true
+}
+
+// checkArtifactName rejects names that were not listed for the task in ctx, so
+// a download can never address a path outside the destination directory.
+func checkArtifactName(name string, ctx BackupContext) error {
+	if !matchesBackupFilename(name, ctx) {
+		return fmt.Errorf("invalid backup file name: %q", name)
+	}
+	return nil
+}
+
+// remotePartName is the name a remote upload is written to before it is
+// renamed to remoteFile. A dropped connection then leaves at most a .part
+// file, never a truncated file that matches the backup name and would be
+// counted by retention or copied by replication.
+func remotePartName(remoteFile string) string {
+	return remoteFile + ".part"
+}
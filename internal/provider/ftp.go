@@ -0,0 +1,372 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+const ftpDialTimeout = 30 * time.Second
+
+// FTPProvider FTP/FTPS 存储提供者
+type FTPProvider struct{}
+
+// NewFTPProvider 创建 FTP 存储提供者
+func NewFTPProvider() *FTPProvider {
+	return &FTPProvider{}
+}
+
+// Type 返回提供者类型
+func (p *FTPProvider) Type() string {
+	return "ftp"
+}
+
+// Backup 执行 FTP 上传，返回最终存储路径
+func (p *FTPProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	remoteFile := path.Join(ftpDirectory(dest), renderBackupFilename(ctx))
+	ctx.AddLog("ftp", fmt.Sprintf("开始 FTP 上传: %s", remoteFile))
+	fail := func(err error) (string, error) {
+		ctx.AddLog("ftp", "FTP 上传失败: "+err.Error())
+		return "", err
+	}
+
+	conn, closeConn, err := dialFTP(ctx.Context, dest)
+	if err != nil {
+		return fail(err)
+	}
+	defer closeConn()
+
+	if err := ftpMkdirAll(conn, ftpDirectory(dest)); err != nil {
+		return fail(fmt.Errorf("failed to create remote directory: %w", err))
+	}
+	if err := uploadFTPFile(conn, ctx.SourceFile, remoteFile); err != nil {
+		return fail(err)
+	}
+
+	ctx.AddLog("ftp", fmt.Sprintf("FTP 上传完成: %s", remoteFile))
+	return ftpLocation(dest, remoteFile), nil
+}
+
+// ftpLocation builds the recorded artifact URL. An empty or relative
+// directory resolves against the login directory and has no leading slash.
+func ftpLocation(dest model.BackupDestination, remoteFile string) string {
+	return ftpScheme(dest) + "://" + net.JoinHostPort(dest.FTPHost, strconv.Itoa(ftpPort(dest))) + "/" + strings.TrimPrefix(path.Clean(remoteFile), "/")
+}
+
+// Test verifies the TLS handshake, login and that a passive data connection
+// can list the target directory, without uploading anything.
+func (p *FTPProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	conn, closeConn, err := dialFTP(ctx, dest)
+	if err != nil {
+		return fmt.Errorf("FTP connection test failed: %w", err)
+	}
+	defer closeConn()
+
+	if _, err := conn.List(ftpDirectory(dest)); err != nil && !isFTPNotFound(err) {
+		return fmt.Errorf("FTP connection test failed: %w", err)
+	}
+	return nil
+}
+
+// Cleanup 清理超出保留数量的旧备份
+func (p *FTPProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
+	if maxCount <= 0 {
+		return 0, nil
+	}
+
+	dest := ctx.Destination
+	conn, closeConn, err := dialFTP(ctx.Context, dest)
+	if err != nil {
+		return 0, err
+	}
+	defer closeConn()
+
+	entries, err := conn.List(ftpDirectory(dest))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var backups []*ftp.Entry
+	for _, entry := range entries {
+		if entry.Type != ftp.EntryTypeFile || !matchesBackupFilename(entry.Name, ctx) {
+			continue
+		}
+		backups = append(backups, entry)
+	}
+	if len(backups) <= maxCount {
+		return 0, nil
+	}
+
+	// 按修改时间降序排序。LIST 时间常常只精确到分钟，同一时间的文件按
+	// 文件名中的时间戳排序。
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.After(backups[j].Time)
+		}
+		return backups[i].Name > backups[j].Name
+	})
+
+	deleted := 0
+	var deleteErrors []error
+	for i := maxCount; i < len(backups); i++ {
+		remotePath := path.Join(ftpDirectory(dest), backups[i].Name)
+		if err := conn.Delete(remotePath); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("%s: %w", backups[i].Name, err))
+			continue
+		}
+		deleted++
+	}
+	if len(deleteErrors) > 0 {
+		return deleted, fmt.Errorf("failed to remove old FTP backups: %w", errors.Join(deleteErrors...))
+	}
+	return deleted, nil
+}
+
+// ftpConnTracker dials the control and data connections itself so every
+// socket can be closed when the run context is cancelled; the FTP library
+// only honours a context while dialing the control connection.
+type ftpConnTracker struct {
+	ctx       context.Context
+	tlsMode   string
+	tlsConfig *tls.Config
+	dialer    net.Dialer
+
+	mu     sync.Mutex
+	conns  []net.Conn
+	dialed int
+	closed bool
+}
+
+func (t *ftpConnTracker) dial(network, address string) (net.Conn, error) {
+	conn, err := t.dialer.DialContext(t.ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		conn.Close()
+		return nil, context.Canceled
+	}
+	t.conns = append(t.conns, conn)
+	control := t.dialed == 0
+	t.dialed++
+
+	// The first connection is the control channel: implicit FTPS wraps it
+	// immediately and explicit FTPS is upgraded by AUTH TLS later. Data
+	// channels are protected (PROT P) in both TLS modes.
+	switch {
+	case t.tlsMode == "implicit", t.tlsMode == "explicit" && !control:
+		return tls.Client(conn, t.tlsConfig), nil
+	default:
+		return conn, nil
+	}
+}
+
+func (t *ftpConnTracker) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for _, conn := range t.conns {
+		conn.Close()
+	}
+}
+
+// dialFTP connects and logs in. The returned close function quits the
+// session; cancelling ctx tears down all sockets so a stalled transfer cannot
+// outlive the run.
+func dialFTP(ctx context.Context, dest model.BackupDestination) (*ftp.ServerConn, func(), error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tlsMode := ftpTLSMode(dest)
+	tracker := &ftpConnTracker{
+		ctx:     ctx,
+		tlsMode: tlsMode,
+		dialer:  net.Dialer{Timeout: ftpDialTimeout},
+	}
+
+	options := []ftp.DialOption{
+		ftp.DialWithContext(ctx),
+		ftp.DialWithTimeout(ftpDialTimeout),
+		ftp.DialWithDisabledEPSV(dest.FTPDisableEPSV),
+		ftp.DialWithDialFunc(tracker.dial),
+	}
+	if tlsMode != "none" {
+		config, err := ftpTLSConfig(dest)
+		if err != nil {
+			return nil, nil, err
+		}
+		tracker.tlsConfig = config
+		if tlsMode == "explicit" {
+			options = append(options, ftp.DialWithExplicitTLS(config))
+		} else {
+			options = append(options, ftp.DialWithTLS(config))
+		}
+	}
+
+	address := net.JoinHostPort(dest.FTPHost, strconv.Itoa(ftpPort(dest)))
+	stop := context.AfterFunc(ctx, tracker.closeAll)
+	conn, err := ftp.Dial(address, options...)
+	if err != nil {
+		stop()
+		tracker.closeAll()
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	if err := conn.Login(dest.FTPUsername, dest.FTPPassword); err != nil {
+		stop()
+		_ = conn.Quit()
+		tracker.closeAll()
+		return nil, nil, fmt.Errorf("FTP login failed: %w", err)
+	}
+
+	closeConn := func() {
+		stop()
+		_ = conn.Quit()
+		tracker.closeAll()
+	}
+	return conn, closeConn, nil
+}
+
+// ftpTLSConfig verifies the server certificate against the system roots, or
+// against the pinned SHA-256 fingerprint when one is configured.
+func ftpTLSConfig(dest model.BackupDestination) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: dest.FTPHost,
+		MinVersion: tls.VersionTLS12,
+		// Many servers require the data channel to resume the control
+		// channel's TLS session.
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+
+	pin := NormalizeCertificateFingerprint(dest.FTPTLSFingerprint)
+	if pin == "" {
+		return config, nil
+	}
+	if len(pin) != sha256.Size*2 {
+		return nil, fmt.Errorf("FTP TLS fingerprint must be a SHA-256 digest")
+	}
+	// The pin replaces chain verification, so the check has to run on every
+	// handshake including resumed data connections.
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("FTP server did not present a certificate")
+		}
+		sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+		got := hex.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(got), []byte(pin)) != 1 {
+			return fmt.Errorf("certificate fingerprint mismatch: got %s", got)
+		}
+		return nil
+	}
+	return config, nil
+}
+
+// NormalizeCertificateFingerprint lower-cases a SHA-256 certificate
+// fingerprint and strips the colons and "SHA256:" prefix tools commonly print.
+func NormalizeCertificateFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimSpace(fingerprint)
+	if len(fingerprint) > len("sha256:") && strings.EqualFold(fingerprint[:len("sha256:")], "sha256:") {
+		fingerprint = fingerprint[len("sha256:"):]
+	}
+	fingerprint = strings.ReplaceAll(fingerprint, ":", "")
+	return strings.ToLower(fingerprint)
+}
+
+// ftpMkdirAll creates each missing path segment. FTP has no recursive mkdir,
+// and servers disagree on the error for an existing directory, so a failed
+// MKD is only fatal when the directory still cannot be entered.
+func ftpMkdirAll(conn *ftp.ServerConn, directory string) error {
+	if directory == "." || directory == "/" {
+		return nil
+	}
+	current := ""
+	if strings.HasPrefix(directory, "/") {
+		current = "/"
+	}
+	for _, segment := range strings.Split(strings.Trim(directory, "/"), "/") {
+		current = path.Join(current, segment)
+		if err := conn.MakeDir(current); err != nil {
+			if cdErr := conn.ChangeDir(current); cdErr != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// uploadFTPFile STORs the export as the remotePartName file and moves it with
+// RNFR/RNTO once the data connection has completed.
+func uploadFTPFile(conn *ftp.ServerConn, sourceFile, remoteFile string) error {
+	source, err := os.Open(sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer source.Close()
+
+	tempFile := remotePartName(remoteFile)
+	if err := conn.Stor(tempFile, source); err != nil {
+		_ = conn.Delete(tempFile)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := conn.Rename(tempFile, remoteFile); err != nil {
+		_ = conn.Delete(tempFile)
+		return fmt.Errorf("failed to move remote file into place: %w", err)
+	}
+	return nil
+}
+
+func isFTPNotFound(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code == ftp.StatusFileUnavailable
+}
+
+func ftpTLSMode(dest model.BackupDestination) string {
+	switch dest.FTPTLSMode {
+	case "explicit", "implicit":
+		return dest.FTPTLSMode
+	default:
+		return "none"
+	}
+}
+
+func ftpScheme(dest model.BackupDestination) string {
+	if ftpTLSMode(dest) == "none" {
+		return "ftp"
+	}
+	return "ftps"
+}
+
+func ftpDirectory(dest model.BackupDestination) string {
+	directory := strings.TrimSpace(dest.FTPPath)
+	if directory == "" {
+		return "."
+	}
+	return path.Clean(directory)
+}
+
+func ftpPort(dest model.BackupDestination) int {
+	if dest.FTPPort == 0 {
+		return model.DefaultFTPPort(ftpTLSMode(dest))
+	}
+	return dest.FTPPort
+}
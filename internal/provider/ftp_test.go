@@ -0,0 +1,379 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// testFTPServer is a minimal passive-mode FTP server backed by a directory.
+// It implements just the commands the provider issues, with explicit or
+// implicit TLS.
+type testFTPServer struct {
+	root      string
+	tlsMode   string
+	tlsConfig *tls.Config
+}
+
+func startFTPServer(t *testing.T, tlsMode string) (model.BackupDestination, string) {
+	t.Helper()
+
+	cert, fingerprint := generateTestCertificate(t)
+	server := &testFTPServer{
+		root:      t.TempDir(),
+		tlsMode:   tlsMode,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	dest := model.BackupDestination{
+		Type:              "ftp",
+		FTPHost:           address.IP.String(),
+		FTPPort:           address.Port,
+		FTPUsername:       "backup",
+		FTPPassword:       "secret",
+		FTPTLSMode:        tlsMode,
+		FTPTLSFingerprint: fingerprint,
+		FTPPath:           "/nested/backups",
+	}
+	return dest, server.root
+}
+
+func generateTestCertificate(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ftp.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func (s *testFTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	if s.tlsMode == "implicit" {
+		conn = tls.Server(conn, s.tlsConfig)
+	}
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	var (
+		loggedIn   bool
+		protected  bool
+		passive    net.Listener
+		renameFrom string
+	)
+	defer func() {
+		if passive != nil {
+			passive.Close()
+		}
+	}()
+	// acceptData hands out the connection for the pending passive listener.
+	acceptData := func() (net.Conn, error) {
+		if passive == nil {
+			return nil, fmt.Errorf("no passive listener")
+		}
+		defer func() {
+			passive.Close()
+			passive = nil
+		}()
+		data, err := passive.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if protected {
+			data = tls.Server(data, s.tlsConfig)
+		}
+		return data, nil
+	}
+
+	reply("220 test server ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command, argument, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		command = strings.ToUpper(command)
+		if !loggedIn && command != "USER" && command != "PASS" && command != "AUTH" && command != "QUIT" {
+			reply("530 not logged in")
+			continue
+		}
+
+		switch command {
+		case "AUTH":
+			if s.tlsMode != "explicit" {
+				reply("502 not supported")
+				continue
+			}
+			reply("234 proceed")
+			conn = tls.Server(conn, s.tlsConfig)
+			reader = bufio.NewReader(conn)
+		case "USER":
+			reply("331 password required")
+		case "PASS":
+			if argument != "secret" {
+				reply("530 login incorrect")
+				continue
+			}
+			loggedIn = true
+			reply("230 logged in")
+		case "FEAT":
+			reply("211-Features:\r\n MLST type*;size*;modify*;\r\n UTF8\r\n211 End")
+		case "TYPE", "OPTS", "PBSZ":
+			reply("200 ok")
+		case "PROT":
+			protected = argument == "P"
+			reply("200 ok")
+		case "EPSV", "PASV":
+			if passive != nil {
+				passive.Close()
+			}
+			passive, err = net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				reply("425 cannot open data connection")
+				continue
+			}
+			port := passive.Addr().(*net.TCPAddr).Port
+			if command == "EPSV" {
+				reply("229 Entering Extended Passive Mode (|||%d|)", port)
+			} else {
+				reply("227 Entering Passive Mode (127,0,0,1,%d,%d)", port/256, port%256)
+			}
+		case "MKD":
+			if err := os.Mkdir(s.path(argument), 0700); err != nil {
+				reply("550 %v", err)
+				continue
+			}
+			reply("257 created")
+		case "CWD":
+			if info, err := os.Stat(s.path(argument)); err != nil || !info.IsDir() {
+				reply("550 no such directory")
+				continue
+			}
+			reply("250 ok")
+		case "STOR":
+			data, err := acceptData()
+			if err != nil {
+				reply("425 %v", err)
+				continue
+			}
+			reply("150 opening data connection")
+			file, err := os.Create(s.path(argument))
+			if err == nil {
+				_, err = io.Copy(file, data)
+				file.Close()
+			}
+			data.Close()
+			if err != nil {
+				reply("451 %v", err)
+				continue
+			}
+			reply("226 transfer complete")
+		case "MLSD":
+			entries, err := os.ReadDir(s.path(argument))
+			if err != nil {
+				reply("550 no such directory")
+				continue
+			}
+			data, err := acceptData()
+			if err != nil {
+				reply("425 %v", err)
+				continue
+			}
+			reply("150 opening data connection")
+			for _, entry := range entries {
+				info, err := entry.Info()
+				if err != nil {
+					continue
+				}
+				kind := "file"
+				if entry.IsDir() {
+					kind = "dir"
+				}
+				fmt.Fprintf(data, "type=%s;size=%d;modify=%s; %s\r\n", kind, info.Size(), info.ModTime().UTC().Format("20060102150405"), entry.Name())
+			}
+			data.Close()
+			reply("226 transfer complete")
+		case "RNFR":
+			renameFrom = argument
+			reply("350 ready for RNTO")
+		case "RNTO":
+			if err := os.Rename(s.path(renameFrom), s.path(argument)); err != nil {
+				reply("550 %v", err)
+				continue
+			}
+			reply("250 renamed")
+		case "DELE":
+			if err := os.Remove(s.path(argument)); err != nil {
+				reply("550 %v", err)
+				continue
+			}
+			reply("250 deleted")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func (s *testFTPServer) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(filepath.Clean("/"+name)))
+}
+
+func TestFTPProviderUploadsAndAppliesRetentionOverExplicitTLS(t *testing.T) {
+	dest, root := startFTPServer(t, "explicit")
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	provider := NewFTPProvider()
+	remoteDir := filepath.Join(root, "nested", "backups")
+	for _, timestamp := range []string{"20251204020000", "20251205020000", "20251206020000"} {
+		remotePath, err := provider.Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   timestamp,
+			Destination: dest,
+		})
+		if err != nil {
+			t.Fatalf("backup %s: %v", timestamp, err)
+		}
+		if !strings.HasPrefix(remotePath, "ftps://") || !strings.HasSuffix(remotePath, timestamp+".json") {
+			t.Fatalf("unexpected remote path %q", remotePath)
+		}
+		written := filepath.Join(remoteDir, "bitwarden_encrypted_export_"+timestamp+".json")
+		modTime, _ := time.Parse("20060102150405", timestamp)
+		if err := os.Chtimes(written, modTime, modTime); err != nil {
+			t.Fatalf("set modification time: %v", err)
+		}
+	}
+
+	deleted, err := provider.Cleanup(BackupContext{Context: context.Background(), TaskName: "Nightly", Destination: dest}, 2)
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+	entries, err := os.ReadDir(remoteDir)
+	if err != nil {
+		t.Fatalf("read remote directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "bitwarden_encrypted_export_20251205020000.json,bitwarden_encrypted_export_20251206020000.json" {
+		t.Fatalf("unexpected remaining files: %v", names)
+	}
+}
+
+func TestFTPProviderLocationForLoginRelativePaths(t *testing.T) {
+	dest, root := startFTPServer(t, "none")
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	host := net.JoinHostPort(dest.FTPHost, fmt.Sprint(dest.FTPPort))
+
+	for _, tc := range []struct{ path, dir string }{{"", ""}, {"exports/vault", "exports/vault/"}} {
+		dest.FTPPath = tc.path
+		location, err := NewFTPProvider().Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   "20251204020000",
+			Destination: dest,
+		})
+		if err != nil {
+			t.Fatalf("Backup(%q) error = %v", tc.path, err)
+		}
+		want := "ftp://" + host + "/" + tc.dir + "bitwarden_encrypted_export_20251204020000.json"
+		if location != want {
+			t.Errorf("Backup(%q) location = %q, want %q", tc.path, location, want)
+		}
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(tc.dir), "bitwarden_encrypted_export_20251204020000.json")); err != nil {
+			t.Errorf("Backup(%q) did not write below the login directory: %v", tc.path, err)
+		}
+	}
+}
+
+func TestFTPProviderTestOverImplicitTLSWithPASV(t *testing.T) {
+	dest, _ := startFTPServer(t, "implicit")
+	dest.FTPDisableEPSV = true
+
+	if err := NewFTPProvider().Test(context.Background(), dest); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+}
+
+func TestFTPProviderRejectsCertificateFingerprintMismatch(t *testing.T) {
+	dest, _ := startFTPServer(t, "explicit")
+	dest.FTPTLSFingerprint = "SHA256:" + strings.Repeat("ab:", 31) + "ab"
+
+	err := NewFTPProvider().Test(context.Background(), dest)
+	if err == nil || !strings.Contains(err.Error(), "fingerprint mismatch") {
+		t.Fatalf("Test() error = %v, want fingerprint mismatch", err)
+	}
+}
+
+func TestFTPProviderRejectsWrongPassword(t *testing.T) {
+	dest, _ := startFTPServer(t, "none")
+	dest.FTPPassword = "wrong"
+
+	err := NewFTPProvider().Test(context.Background(), dest)
+	if err == nil || !strings.Contains(err.Error(), "login failed") {
+		t.Fatalf("Test() error = %v, want login failure", err)
+	}
+}
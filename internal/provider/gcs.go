@@ -0,0 +1,334 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/safety"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcsScope           = "https://www.googleapis.com/auth/devstorage.read_write"
+	defaultGCSEndpoint = "https://storage.googleapis.com"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// GCSProvider Google Cloud Storage 存储提供者
+type GCSProvider struct{}
+
+// NewGCSProvider 创建 GCS 存储提供者
+func NewGCSProvider() *GCSProvider {
+	return &GCSProvider{}
+}
+
+// Type 返回提供者类型
+func (p *GCSProvider) Type() string {
+	return "gcs"
+}
+
+// Backup 执行 GCS 上传，返回最终存储路径
+func (p *GCSProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	fail := func(err error) (string, error) {
+		ctx.AddLog("gcs", "GCS 上传失败: "+err.Error())
+		return "", err
+	}
+	ctx.AddLog("gcs", fmt.Sprintf("开始 GCS 上传: %s", dest.GCSBucket))
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 5*time.Minute)
+	defer cancel()
+	client, err := newGCSClient(requestCtx, dest)
+	if err != nil {
+		return fail(err)
+	}
+
+	name := gcsPrefix(dest) + renderBackupFilename(ctx)
+	if err := client.upload(requestCtx, name, ctx.SourceFile); err != nil {
+		return fail(err)
+	}
+
+	ctx.AddLog("gcs", fmt.Sprintf("GCS 上传完成，CRC32C 校验通过: %s", name))
+	return fmt.Sprintf("gs://%s/%s", dest.GCSBucket, name), nil
+}
+
+// Test verifies the service account and bucket access by listing the
+// configured prefix; objects.list is also what retention needs.
+func (p *GCSProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx), 30*time.Second)
+	defer cancel()
+	client, err := newGCSClient(requestCtx, dest)
+	if err != nil {
+		return fmt.Errorf("GCS connection test failed: %w", err)
+	}
+	if _, _, err := client.listPage(requestCtx, gcsPrefix(dest), "", 1); err != nil {
+		return fmt.Errorf("GCS connection test failed: %w", err)
+	}
+	return nil
+}
+
+// Cleanup 清理超出保留数量的旧备份
+//
+// Objects still protected by a bucket retention policy or a hold cannot be
+// deleted yet. They are skipped instead of failing the run and will be
+// removed by a later cleanup once the lock expires.
+func (p *GCSProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
+	if maxCount <= 0 {
+		return 0, nil
+	}
+
+	dest := ctx.Destination
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 2*time.Minute)
+	defer cancel()
+	client, err := newGCSClient(requestCtx, dest)
+	if err != nil {
+		return 0, err
+	}
+
+	prefix := gcsPrefix(dest)
+	var backups []gcsObject
+	pageToken := ""
+	for {
+		objects, next, err := client.listPage(requestCtx, prefix, pageToken, 0)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, object := range objects {
+			if matchesBackupFilename(strings.TrimPrefix(object.Name, prefix), ctx) {
+				backups = append(backups, object)
+			}
+		}
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+	if len(backups) <= maxCount {
+		return 0, nil
+	}
+
+	// 按修改时间降序排序
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Updated.After(backups[j].Updated)
+	})
+
+	now := time.Now()
+	deleted := 0
+	var deleteErrors []error
+	for i := maxCount; i < len(backups); i++ {
+		if backups[i].locked(now) {
+			ctx.AddLog("gcs", fmt.Sprintf("对象仍受保留策略保护，暂不删除: %s", backups[i].Name))
+			continue
+		}
+		if err := client.delete(requestCtx, backups[i].Name); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("%s: %w", backups[i].Name, err))
+			continue
+		}
+		deleted++
+	}
+	if len(deleteErrors) > 0 {
+		return deleted, fmt.Errorf("failed to delete some GCS objects: %w", errors.Join(deleteErrors...))
+	}
+	return deleted, nil
+}
+
+type gcsObject struct {
+	Name                    string     `json:"name"`
+	CRC32C                  string     `json:"crc32c"`
+	Updated                 time.Time  `json:"updated"`
+	RetentionExpirationTime *time.Time `json:"retentionExpirationTime"`
+	TemporaryHold           bool       `json:"temporaryHold"`
+	EventBasedHold          bool       `json:"eventBasedHold"`
+}
+
+func (o gcsObject) locked(now time.Time) bool {
+	return o.TemporaryHold || o.EventBasedHold || (o.RetentionExpirationTime != nil && o.RetentionExpirationTime.After(now))
+}
+
+// gcsClient is a minimal client for the GCS JSON API. The official SDK pulls
+// in gRPC and telemetry dependencies for the three calls this provider needs.
+type gcsClient struct {
+	http     *http.Client
+	endpoint string
+	bucket   string
+}
+
+func newGCSClient(ctx context.Context, dest model.BackupDestination) (*gcsClient, error) {
+	if dest.GCSCredentials == "" {
+		return nil, fmt.Errorf("GCS service account key is required")
+	}
+	config, err := google.JWTConfigFromJSON([]byte(dest.GCSCredentials), gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GCS service account key: %w", err)
+	}
+	endpoint := defaultGCSEndpoint
+	if dest.GCSEndpoint != "" {
+		if err := safety.ValidateURL(dest.GCSEndpoint, "gcs_endpoint", true); err != nil {
+			return nil, err
+		}
+		endpoint = strings.TrimSuffix(dest.GCSEndpoint, "/")
+	}
+	return &gcsClient{http: config.Client(ctx), endpoint: endpoint, bucket: dest.GCSBucket}, nil
+}
+
+// upload sends the object with its CRC32C in the metadata part, so GCS
+// rejects a body corrupted in transit, and then compares the checksum the
+// service reports for the stored object.
+func (c *gcsClient) upload(ctx context.Context, name, sourceFile string) error {
+	file, err := os.Open(sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer file.Close()
+
+	checksum, err := fileCRC32C(file)
+	if err != nil {
+		return fmt.Errorf("failed to checksum source file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind source file: %w", err)
+	}
+
+	body, contentType := gcsMultipartBody(map[string]string{"name": name, "crc32c": checksum}, file)
+	uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=multipart", c.endpoint, url.PathEscape(c.bucket))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+
+	var object gcsObject
+	if err := c.do(request, &object); err != nil {
+		return fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+	if object.CRC32C != checksum {
+		_ = c.delete(context.WithoutCancel(ctx), name)
+		return fmt.Errorf("GCS CRC32C mismatch: local %s, stored %s", checksum, object.CRC32C)
+	}
+	return nil
+}
+
+// listPage lists objects directly under prefix; the delimiter keeps nested
+// "directories" out of the retention scope.
+func (c *gcsClient) listPage(ctx context.Context, prefix, pageToken string, maxResults int) ([]gcsObject, string, error) {
+	query := url.Values{"prefix": {prefix}, "delimiter": {"/"}}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	if maxResults > 0 {
+		query.Set("maxResults", fmt.Sprint(maxResults))
+	}
+	listURL := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", c.endpoint, url.PathEscape(c.bucket), query.Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	var result struct {
+		Items         []gcsObject `json:"items"`
+		NextPageToken string      `json:"nextPageToken"`
+	}
+	if err := c.do(request, &result); err != nil {
+		return nil, "", err
+	}
+	return result.Items, result.NextPageToken, nil
+}
+
+func (c *gcsClient) delete(ctx context.Context, name string) error {
+	deleteURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", c.endpoint, url.PathEscape(c.bucket), url.PathEscape(name))
+	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(request, nil)
+}
+
+func (c *gcsClient) do(request *http.Request, out any) error {
+	response, err := c.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		if json.Unmarshal(data, &apiError) == nil && apiError.Error.Message != "" {
+			return fmt.Errorf("GCS returned %d: %s", response.StatusCode, apiError.Error.Message)
+		}
+		return fmt.Errorf("GCS returned %d", response.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid GCS response: %w", err)
+	}
+	return nil
+}
+
+// gcsMultipartBody streams a multipart/related upload: JSON metadata first,
+// then the object data.
+func gcsMultipartBody(metadata map[string]string, data io.Reader) (io.Reader, string) {
+	reader, writer := io.Pipe()
+	parts := multipart.NewWriter(writer)
+	go func() {
+		err := func() error {
+			metadataPart, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+			if err != nil {
+				return err
+			}
+			if err := json.NewEncoder(metadataPart).Encode(metadata); err != nil {
+				return err
+			}
+			dataPart, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(dataPart, data); err != nil {
+				return err
+			}
+			return parts.Close()
+		}()
+		writer.CloseWithError(err)
+	}()
+	return reader, "multipart/related; boundary=" + parts.Boundary()
+}
+
+// fileCRC32C returns the base64 encoded big-endian CRC32C used by GCS.
+func fileCRC32C(file io.Reader) (string, error) {
+	hash := crc32.New(crc32cTable)
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], hash.Sum32())
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+func gcsPrefix(dest model.BackupDestination) string {
+	prefix := strings.Trim(dest.GCSPrefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
@@ -0,0 +1,288 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// fakeGCSServer emulates the token endpoint and the objects insert, list and
+// delete calls of the GCS JSON API, in the spirit of fake-gcs-server.
+type fakeGCSServer struct {
+	mu          sync.Mutex
+	objects     map[string]*gcsObject
+	corruptCRC  bool
+	listQueries []string
+}
+
+func newFakeGCSServer(t *testing.T) (*fakeGCSServer, model.BackupDestination) {
+	t.Helper()
+	fake := &fakeGCSServer{objects: make(map[string]*gcsObject)}
+	server := httptest.NewServer(http.HandlerFunc(fake.serveHTTP))
+	t.Cleanup(server.Close)
+
+	dest := model.BackupDestination{
+		Type:           "gcs",
+		GCSBucket:      "vault",
+		GCSPrefix:      "/nightly/",
+		GCSCredentials: testServiceAccountKey(t, server.URL+"/token"),
+		GCSEndpoint:    server.URL,
+	}
+	return fake, dest
+}
+
+func testServiceAccountKey(t *testing.T, tokenURL string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	raw, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "backup@example.iam.gserviceaccount.com",
+		"private_key_id": "test",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURL,
+	})
+	return string(raw)
+}
+
+func (f *fakeGCSServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"test-token","token_type":"Bearer","expires_in":3600}`)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		writeGCSError(w, http.StatusUnauthorized, "missing token")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/vault/o":
+		f.insert(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/vault/o":
+		f.list(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/storage/v1/b/vault/o/"):
+		name := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/vault/o/")
+		object, ok := f.objects[name]
+		switch {
+		case !ok:
+			writeGCSError(w, http.StatusNotFound, "No such object")
+		case object.locked(time.Now()):
+			writeGCSError(w, http.StatusForbidden, "Object is subject to bucket's retention policy")
+		default:
+			delete(f.objects, name)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		writeGCSError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (f *fakeGCSServer) insert(w http.ResponseWriter, r *http.Request) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" || r.URL.Query().Get("uploadType") != "multipart" {
+		writeGCSError(w, http.StatusBadRequest, "expected multipart upload")
+		return
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+	metadataPart, err := reader.NextPart()
+	if err != nil {
+		writeGCSError(w, http.StatusBadRequest, "missing metadata")
+		return
+	}
+	var metadata map[string]string
+	if err := json.NewDecoder(metadataPart).Decode(&metadata); err != nil {
+		writeGCSError(w, http.StatusBadRequest, "invalid metadata")
+		return
+	}
+	dataPart, err := reader.NextPart()
+	if err != nil {
+		writeGCSError(w, http.StatusBadRequest, "missing data")
+		return
+	}
+	checksum, err := fileCRC32C(dataPart)
+	if err != nil {
+		writeGCSError(w, http.StatusBadRequest, "read data")
+		return
+	}
+	if metadata["crc32c"] != checksum {
+		writeGCSError(w, http.StatusBadRequest, "Provided CRC32C does not match")
+		return
+	}
+	if f.corruptCRC {
+		checksum = "AAAAAA=="
+	}
+	object := &gcsObject{Name: metadata["name"], CRC32C: checksum, Updated: time.Now().UTC()}
+	f.objects[object.Name] = object
+	_ = json.NewEncoder(w).Encode(object)
+}
+
+// list returns two objects per page so callers have to follow pageToken.
+func (f *fakeGCSServer) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f.listQueries = append(f.listQueries, query.Encode())
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+
+	var names []string
+	for name := range f.objects {
+		relative, ok := strings.CutPrefix(name, prefix)
+		if ok && (delimiter == "" || !strings.Contains(relative, delimiter)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start := 0
+	fmt.Sscan(query.Get("pageToken"), &start)
+	end := min(start+2, len(names))
+	result := map[string]any{"items": []*gcsObject{}}
+	var items []*gcsObject
+	for _, name := range names[start:end] {
+		items = append(items, f.objects[name])
+	}
+	if items != nil {
+		result["items"] = items
+	}
+	if end < len(names) {
+		result["nextPageToken"] = fmt.Sprint(end)
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func writeGCSError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": status, "message": message}})
+}
+
+func writeGCSSource(t *testing.T) string {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, bytes.Repeat([]byte(`{"items":[]}`), 100), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	return source
+}
+
+func TestGCSProviderUploadsAndAppliesRetention(t *testing.T) {
+	fake, dest := newFakeGCSServer(t)
+	source := writeGCSSource(t)
+
+	provider := NewGCSProvider()
+	timestamps := []string{"20251203020000", "20251204020000", "20251205020000", "20251206020000"}
+	for _, timestamp := range timestamps {
+		remotePath, err := provider.Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   timestamp,
+			Destination: dest,
+		})
+		if err != nil {
+			t.Fatalf("backup %s: %v", timestamp, err)
+		}
+		if want := "gs://vault/nightly/bitwarden_encrypted_export_" + timestamp + ".json"; remotePath != want {
+			t.Fatalf("remote path = %q, want %q", remotePath, want)
+		}
+		fake.mu.Lock()
+		fake.objects["nightly/bitwarden_encrypted_export_"+timestamp+".json"].Updated, _ = time.Parse("20060102150405", timestamp)
+		fake.mu.Unlock()
+	}
+
+	fake.mu.Lock()
+	// The oldest object is still under the bucket retention policy and a
+	// nested object is outside the retention scope.
+	retainUntil := time.Now().Add(24 * time.Hour)
+	fake.objects["nightly/bitwarden_encrypted_export_20251203020000.json"].RetentionExpirationTime = &retainUntil
+	fake.objects["nightly/archive/bitwarden_encrypted_export_20250101020000.json"] = &gcsObject{Name: "nightly/archive/bitwarden_encrypted_export_20250101020000.json"}
+	fake.mu.Unlock()
+
+	deleted, err := provider.Cleanup(BackupContext{Context: context.Background(), TaskName: "Nightly", Destination: dest}, 2)
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, name := range []string{
+		"nightly/bitwarden_encrypted_export_20251203020000.json",
+		"nightly/bitwarden_encrypted_export_20251205020000.json",
+		"nightly/bitwarden_encrypted_export_20251206020000.json",
+		"nightly/archive/bitwarden_encrypted_export_20250101020000.json",
+	} {
+		if _, ok := fake.objects[name]; !ok {
+			t.Errorf("object %s was deleted", name)
+		}
+	}
+	if _, ok := fake.objects["nightly/bitwarden_encrypted_export_20251204020000.json"]; ok {
+		t.Errorf("expired object was kept")
+	}
+	if len(fake.listQueries) < 2 {
+		t.Errorf("cleanup did not follow pagination: %v", fake.listQueries)
+	}
+}
+
+func TestGCSProviderRejectsCRC32CMismatch(t *testing.T) {
+	fake, dest := newFakeGCSServer(t)
+	fake.corruptCRC = true
+
+	_, err := NewGCSProvider().Backup(BackupContext{
+		Context:     context.Background(),
+		SourceFile:  writeGCSSource(t),
+		TaskName:    "Nightly",
+		Timestamp:   "20251204020000",
+		Destination: dest,
+	})
+	if err == nil || !strings.Contains(err.Error(), "CRC32C mismatch") {
+		t.Fatalf("Backup() error = %v, want CRC32C mismatch", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.objects) != 0 {
+		t.Fatalf("mismatched object was not removed: %v", fake.objects)
+	}
+}
+
+func TestGCSProviderTestListsPrefix(t *testing.T) {
+	fake, dest := newFakeGCSServer(t)
+	if err := NewGCSProvider().Test(context.Background(), dest); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	if len(fake.listQueries) != 1 || !strings.Contains(fake.listQueries[0], "prefix=nightly%2F") {
+		t.Fatalf("unexpected list queries: %v", fake.listQueries)
+	}
+
+	dest.GCSCredentials = `{"type":"authorized_user"}`
+	if err := NewGCSProvider().Test(context.Background(), dest); err == nil {
+		t.Fatalf("Test() accepted an invalid service account key")
+	}
+}
@@ -0,0 +1,496 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/safety"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultGitAuthorName  = "Bitwarden Backup"
+	defaultGitAuthorEmail = "bitwarden-backup@localhost"
+	// gitHostKeyAlias lets the pinned key be written to known_hosts without
+	// parsing host and port out of every URL form git accepts.
+	gitHostKeyAlias = "bitwarden-backup-git"
+)
+
+// gitWorkingCopyLocks serializes runs that share a working copy.
+var gitWorkingCopyLocks sync.Map
+
+// GitProvider 将备份提交并推送到 git 仓库
+//
+// Every backup is a commit, so retention is the repository history and the
+// provider deliberately does not implement RetentionProvider.
+type GitProvider struct {
+	// workRoot keeps one working copy per destination so a run only fetches
+	// the branch head. Empty means every run starts from a temp directory.
+	workRoot string
+	// allowProtocols is passed as GIT_ALLOW_PROTOCOL. It keeps transports
+	// such as ext:: (which runs commands) out of reach.
+	allowProtocols string
+}
+
+// NewGitProvider 创建 git 存储提供者
+func NewGitProvider() *GitProvider {
+	root := ""
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		root = filepath.Join(cacheDir, "bitwarden-backup", "git")
+	}
+	return &GitProvider{workRoot: root, allowProtocols: "https:ssh"}
+}
+
+// Type 返回提供者类型
+func (p *GitProvider) Type() string {
+	return "git"
+}
+
+// Backup 提交备份文件并推送，返回最终存储路径
+func (p *GitProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	branch := model.GitBranchOrDefault(dest.GitBranch)
+	fail := func(err error) (string, error) {
+		ctx.AddLog("git", "git 推送失败: "+err.Error())
+		return "", err
+	}
+	ctx.AddLog("git", fmt.Sprintf("开始提交到 git 仓库: %s (%s)", dest.GitURL, branch))
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 5*time.Minute)
+	defer cancel()
+
+	session, err := p.open(dest, ctx.AddLog)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.close()
+
+	workDir, release, err := p.workingCopy(dest)
+	if err != nil {
+		return fail(err)
+	}
+	defer release()
+	session.dir = workDir
+
+	artifact := gitArtifactPath(ctx)
+	// A push is rejected when another writer moved the branch since the
+	// fetch. Rebuild the commit on the new head once before giving up.
+	for attempt := 1; ; attempt++ {
+		commit, changed, err := session.commitArtifact(requestCtx, ctx, branch, artifact)
+		if err != nil {
+			return fail(err)
+		}
+		if !changed {
+			ctx.AddLog("git", "备份内容与分支最新提交相同，跳过提交")
+			return gitArtifactLocation(dest.GitURL, branch, artifact, commit), nil
+		}
+
+		res, err := session.run(requestCtx, "push", "-q", "origin", "HEAD:refs/heads/"+branch)
+		if err == nil {
+			ctx.AddLog("git", fmt.Sprintf("git 推送完成: %s@%s", artifact, shortCommit(commit)))
+			return gitArtifactLocation(dest.GitURL, branch, artifact, commit), nil
+		}
+		if attempt >= 2 || !isGitPushRejected(res.Stderr) {
+			return fail(err)
+		}
+		ctx.AddLog("git", "远端分支已更新，重新获取后重试推送")
+	}
+}
+
+// Test checks reachability and credentials with ls-remote, which needs no
+// working copy. An empty repository or a missing branch is fine; the first
+// backup creates the branch.
+func (p *GitProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	session, err := p.open(dest, nil)
+	if err != nil {
+		return fmt.Errorf("git connection test failed: %w", err)
+	}
+	defer session.close()
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx), 30*time.Second)
+	defer cancel()
+	if _, err := session.runIn(requestCtx, session.authDir, "ls-remote", "--heads", dest.GitURL, "refs/heads/"+model.GitBranchOrDefault(dest.GitBranch)); err != nil {
+		return fmt.Errorf("git connection test failed: %w", err)
+	}
+	return nil
+}
+
+// workingCopy returns the locked working copy directory for dest.
+func (p *GitProvider) workingCopy(dest model.BackupDestination) (string, func(), error) {
+	if p.workRoot == "" {
+		tempDir, err := os.MkdirTemp("", "bitwarden-backup-git-")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to create git working copy: %w", err)
+		}
+		return filepath.Join(tempDir, "repo"), func() { _ = os.RemoveAll(tempDir) }, nil
+	}
+	if err := os.MkdirAll(p.workRoot, 0700); err != nil {
+		return "", nil, fmt.Errorf("failed to create git working copy root: %w", err)
+	}
+	// Key the directory by URL and branch as well, so editing the remote
+	// never reuses a working copy of another repository.
+	sum := sha256.Sum256([]byte(dest.GitURL + "\x00" + model.GitBranchOrDefault(dest.GitBranch)))
+	dir := filepath.Join(p.workRoot, fmt.Sprintf("%d-%x", dest.ID, sum[:6]))
+
+	value, _ := gitWorkingCopyLocks.LoadOrStore(dir, &sync.Mutex{})
+	lock := value.(*sync.Mutex)
+	lock.Lock()
+	return dir, lock.Unlock, nil
+}
+
+// gitSession runs git with the destination credentials. authDir holds the
+// SSH key, known_hosts and askpass helper for the duration of one operation.
+type gitSession struct {
+	dir     string
+	authDir string
+	env     []string
+	secrets []string
+	log     func(source, message string)
+	remote  string
+	author  [2]string
+}
+
+type gitExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+func (p *GitProvider) open(dest model.BackupDestination, log func(source, message string)) (*gitSession, error) {
+	if dest.GitURL == "" {
+		return nil, fmt.Errorf("git repository URL is required")
+	}
+	authDir, err := os.MkdirTemp("", "bitwarden-backup-git-auth-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare git credentials: %w", err)
+	}
+	session := &gitSession{
+		authDir: authDir,
+		log:     log,
+		remote:  dest.GitURL,
+		author:  [2]string{defaultGitAuthorName, defaultGitAuthorEmail},
+	}
+	if dest.GitAuthorName != "" {
+		session.author[0] = dest.GitAuthorName
+	}
+	if dest.GitAuthorEmail != "" {
+		session.author[1] = dest.GitAuthorEmail
+	}
+
+	// Ignore the host's git configuration and any GIT_* / SSH_* overrides:
+	// only the destination settings apply, and git must never prompt.
+	globalConfig := filepath.Join(authDir, "gitconfig")
+	if err := os.WriteFile(globalConfig, nil, 0600); err != nil {
+		session.close()
+		return nil, fmt.Errorf("failed to prepare git credentials: %w", err)
+	}
+	session.env = append(commandEnv("GIT_", "SSH_"),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+globalConfig,
+		"GIT_ALLOW_PROTOCOL="+p.allowProtocols,
+	)
+
+	if model.IsGitHTTPSURL(dest.GitURL) {
+		err = session.configureHTTPS(dest)
+	} else if dest.GitPrivateKey != "" {
+		err = session.configureSSH(dest)
+	}
+	if err != nil {
+		session.close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// configureHTTPS hands the credentials to git through an askpass helper and
+// the environment, so they never appear in argv, the URL or .git/config.
+func (s *gitSession) configureHTTPS(dest model.BackupDestination) error {
+	if dest.GitPassword == "" {
+		return nil
+	}
+	askpass := filepath.Join(s.authDir, "askpass.sh")
+	script := "#!/bin/sh\ncase \"$1\" in\nUsername*) printf '%s\\n' \"$BACKUP_GIT_USERNAME\" ;;\n*) printf '%s\\n' \"$BACKUP_GIT_PASSWORD\" ;;\nesac\n"
+	if err := os.WriteFile(askpass, []byte(script), 0700); err != nil {
+		return fmt.Errorf("failed to prepare git credentials: %w", err)
+	}
+	s.env = append(s.env,
+		"GIT_ASKPASS="+askpass,
+		"BACKUP_GIT_USERNAME="+dest.GitUsername,
+		"BACKUP_GIT_PASSWORD="+dest.GitPassword,
+	)
+	s.secrets = append(s.secrets, dest.GitPassword)
+	return nil
+}
+
+// configureSSH pins the host key and uses only the configured private key.
+func (s *gitSession) configureSSH(dest model.BackupDestination) error {
+	hostKey, err := ParseSFTPHostKey(dest.GitHostKey)
+	if err != nil {
+		return fmt.Errorf("git SSH host key must be a public key")
+	}
+	keyFile := filepath.Join(s.authDir, "id")
+	privateKey := strings.TrimSpace(dest.GitPrivateKey) + "\n"
+	if err := os.WriteFile(keyFile, []byte(privateKey), 0600); err != nil {
+		return fmt.Errorf("failed to prepare git credentials: %w", err)
+	}
+	knownHosts := filepath.Join(s.authDir, "known_hosts")
+	line := gitHostKeyAlias + " " + string(ssh.MarshalAuthorizedKey(hostKey))
+	if err := os.WriteFile(knownHosts, []byte(line), 0600); err != nil {
+		return fmt.Errorf("failed to prepare git credentials: %w", err)
+	}
+	s.env = append(s.env, "GIT_SSH_COMMAND="+strings.Join([]string{
+		"ssh", "-F", "/dev/null",
+		"-i", shellQuote(keyFile),
+		"-o", "IdentitiesOnly=yes",
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "UserKnownHostsFile=" + shellQuote(knownHosts),
+		"-o", "HostKeyAlias=" + gitHostKeyAlias,
+	}, " "))
+	return nil
+}
+
+func (s *gitSession) close() {
+	_ = os.RemoveAll(s.authDir)
+}
+
+func (s *gitSession) run(ctx context.Context, args ...string) (gitExecResult, error) {
+	return s.runIn(ctx, s.dir, args...)
+}
+
+func (s *gitSession) runIn(ctx context.Context, dir string, args ...string) (gitExecResult, error) {
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = s.env
+	cmd.WaitDelay = 5 * time.Second
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	err := cmd.Run()
+	exitCode := 0
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+	res := gitExecResult{Stdout: stdoutBuf.String(), Stderr: stderrBuf.String(), ExitCode: exitCode}
+
+	subcommand := gitSubcommand(args)
+	if s.log != nil {
+		s.log("git", fmt.Sprintf("git %s (exit=%d, %dms)", subcommand, exitCode, time.Since(start).Milliseconds()))
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return res, fmt.Errorf("failed to run git: %w", err)
+		}
+		message := sanitizeCommandOutput(res.Stderr, s.secrets)
+		if message == "" {
+			return res, fmt.Errorf("git %s failed (exit=%d)", subcommand, exitCode)
+		}
+		return res, fmt.Errorf("git %s failed (exit=%d): %s", subcommand, exitCode, message)
+	}
+	return res, nil
+}
+
+// checkout brings the working copy to the remote branch head, discarding
+// anything a failed earlier run left behind. A branch that does not exist
+// yet starts from an empty working copy.
+func (s *gitSession) checkout(ctx context.Context, branch string) error {
+	heads, err := s.runIn(ctx, s.authDir, "ls-remote", "--heads", s.remote, "refs/heads/"+branch)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(heads.Stdout) == "" {
+		if err := s.initWorkingCopy(ctx); err != nil {
+			return err
+		}
+		_, err := s.run(ctx, "symbolic-ref", "HEAD", "refs/heads/"+branch)
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); err != nil {
+		if err := s.initWorkingCopy(ctx); err != nil {
+			return err
+		}
+	} else if _, err := s.run(ctx, "remote", "set-url", "origin", s.remote); err != nil {
+		return err
+	}
+
+	remoteRef := "refs/remotes/origin/" + branch
+	steps := [][]string{
+		{"fetch", "-q", "--depth=1", "origin", "+refs/heads/" + branch + ":" + remoteRef},
+		{"checkout", "-q", "-f", "-B", branch, remoteRef},
+		{"clean", "-q", "-f", "-d", "-x"},
+	}
+	for _, args := range steps {
+		if _, err := s.run(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *gitSession) initWorkingCopy(ctx context.Context) error {
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("failed to reset git working copy: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create git working copy: %w", err)
+	}
+	if _, err := s.run(ctx, "init", "-q"); err != nil {
+		return err
+	}
+	_, err := s.run(ctx, "remote", "add", "origin", s.remote)
+	return err
+}
+
+// commitArtifact checks out the branch, writes the artifact and commits it.
+// It reports false when the file is identical to the branch head.
+func (s *gitSession) commitArtifact(ctx context.Context, backup BackupContext, branch, artifact string) (string, bool, error) {
+	if err := s.checkout(ctx, branch); err != nil {
+		return "", false, err
+	}
+	target := filepath.Join(s.dir, filepath.FromSlash(artifact))
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return "", false, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	if err := copyGitArtifact(backup.SourceFile, target); err != nil {
+		return "", false, err
+	}
+	if _, err := s.run(ctx, "add", "--", artifact); err != nil {
+		return "", false, err
+	}
+
+	res, err := s.run(ctx, "diff", "--cached", "--quiet")
+	if err == nil {
+		head, _ := s.run(ctx, "rev-parse", "--verify", "-q", "HEAD")
+		return strings.TrimSpace(head.Stdout), false, nil
+	}
+	if res.ExitCode != 1 {
+		return "", false, err
+	}
+
+	subject, body := gitCommitMessage(backup)
+	if _, err := s.run(ctx,
+		"-c", "user.name="+s.author[0],
+		"-c", "user.email="+s.author[1],
+		"commit", "-q", "--no-verify", "--no-gpg-sign", "-m", subject, "-m", body,
+	); err != nil {
+		return "", false, err
+	}
+	head, err := s.run(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(head.Stdout), true, nil
+}
+
+// gitCommitMessage records the task and run in trailers, so the history can
+// be traced back to a run record.
+func gitCommitMessage(ctx BackupContext) (string, string) {
+	subject := fmt.Sprintf("Backup %s at %s", ctx.TaskName, ctx.Timestamp)
+	trailers := []string{"Task: " + ctx.TaskName}
+	if ctx.TaskID != 0 {
+		trailers = append(trailers, fmt.Sprintf("Task-ID: %d", ctx.TaskID))
+	}
+	if ctx.RunID != 0 {
+		trailers = append(trailers, fmt.Sprintf("Run-ID: %d", ctx.RunID))
+	}
+	trailers = append(trailers,
+		"Timestamp: "+ctx.Timestamp,
+		"Destination: "+ctx.Destination.Name,
+		fmt.Sprintf("Encrypted: %t", ctx.Destination.Encrypted),
+	)
+	return subject, strings.Join(trailers, "\n")
+}
+
+// gitArtifactPath returns the repository path of the artifact. A configured
+// filename is usually stable (e.g. vault.json) so each run is a new version of
+// one file; otherwise the task filename template is used.
+func gitArtifactPath(ctx BackupContext) string {
+	dest := ctx.Destination
+	name := renderBackupFilename(ctx)
+	if dest.GitFilename != "" {
+		raw := model.RenderFilenameTemplate(dest.GitFilename, safety.Filename(ctx.TaskName), ctx.Timestamp, destinationMedium(dest.Type))
+		name = safety.Filename(raw)
+		if !strings.HasSuffix(strings.ToLower(name), ".json") {
+			name += ".json"
+		}
+	}
+	dir := strings.Trim(path.Clean("/"+strings.TrimSpace(dest.GitPath)), "/")
+	return path.Join(dir, name)
+}
+
+func gitArtifactLocation(remote, branch, artifact, commit string) string {
+	location := fmt.Sprintf("%s#%s:%s", remote, branch, artifact)
+	if commit != "" {
+		location += "@" + shortCommit(commit)
+	}
+	return location
+}
+
+func copyGitArtifact(sourceFile, target string) error {
+	source, err := os.Open(sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer source.Close()
+
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if _, err := io.Copy(file, source); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return nil
+}
+
+func isGitPushRejected(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	return strings.Contains(stderr, "fetch first") || strings.Contains(stderr, "non-fast-forward") || strings.Contains(stderr, "[rejected]")
+}
+
+// gitSubcommand skips leading -c options so logs name the actual command.
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// runTestGit runs git isolated from the host configuration.
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=Other Writer",
+		"GIT_AUTHOR_EMAIL=other@example.com",
+		"GIT_COMMITTER_NAME=Other Writer",
+		"GIT_COMMITTER_EMAIL=other@example.com",
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func newTestGitRemote(t *testing.T) (*GitProvider, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "vault.git")
+	runTestGit(t, filepath.Dir(remote), "init", "-q", "--bare", remote)
+	return &GitProvider{workRoot: t.TempDir(), allowProtocols: "file"}, remote
+}
+
+func gitBackup(t *testing.T, provider *GitProvider, dest model.BackupDestination, timestamp, content string) string {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(content), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	location, err := provider.Backup(BackupContext{
+		Context:     context.Background(),
+		SourceFile:  source,
+		TaskID:      3,
+		TaskName:    "Nightly",
+		RunID:       42,
+		Timestamp:   timestamp,
+		Destination: dest,
+	})
+	if err != nil {
+		t.Fatalf("backup %s: %v", timestamp, err)
+	}
+	return location
+}
+
+func TestGitProviderCommitsStableFileWithRunMetadata(t *testing.T) {
+	provider, remote := newTestGitRemote(t)
+	dest := model.BackupDestination{
+		ID: 1, Name: "Vault repo", Type: "git", Encrypted: true,
+		GitURL: remote, GitBranch: "backups", GitPath: "/exports/", GitFilename: "{task_name}.json",
+	}
+
+	location := gitBackup(t, provider, dest, "20251204020000", `{"encrypted":true,"data":"one"}`)
+	if !strings.HasPrefix(location, remote+"#backups:exports/Nightly.json@") {
+		t.Fatalf("location = %q", location)
+	}
+	gitBackup(t, provider, dest, "20251205020000", `{"encrypted":true,"data":"two"}`)
+	// An identical export does not create an empty commit.
+	gitBackup(t, provider, dest, "20251206020000", `{"encrypted":true,"data":"two"}`)
+
+	if got := runTestGit(t, remote, "rev-list", "--count", "backups"); got != "2" {
+		t.Fatalf("commit count = %s, want 2", got)
+	}
+	if got := runTestGit(t, remote, "show", "backups:exports/Nightly.json"); got != `{"encrypted":true,"data":"two"}` {
+		t.Fatalf("file at branch head = %s", got)
+	}
+	message := runTestGit(t, remote, "log", "-1", "--format=%an%n%s%n%b", "backups")
+	for _, want := range []string{"Bitwarden Backup", "Backup Nightly at 20251205020000", "Task-ID: 3", "Run-ID: 42", "Destination: Vault repo"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("commit message %q does not contain %q", message, want)
+		}
+	}
+}
+
+func TestGitProviderBuildsOnCommitsFromOtherWriters(t *testing.T) {
+	provider, remote := newTestGitRemote(t)
+	dest := model.BackupDestination{ID: 2, Type: "git", GitURL: remote}
+
+	gitBackup(t, provider, dest, "20251204020000", `{"run":1}`)
+
+	// Someone else pushes to the branch between two runs.
+	other := filepath.Join(t.TempDir(), "other")
+	runTestGit(t, filepath.Dir(other), "clone", "-q", "-b", "main", remote, other)
+	if err := os.WriteFile(filepath.Join(other, "README.md"), []byte("vault history\n"), 0600); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runTestGit(t, other, "add", "README.md")
+	runTestGit(t, other, "commit", "-q", "-m", "Add README")
+	runTestGit(t, other, "push", "-q", "origin", "main")
+
+	gitBackup(t, provider, dest, "20251205020000", `{"run":2}`)
+
+	files := runTestGit(t, remote, "ls-tree", "--name-only", "main")
+	want := "README.md\nbitwarden_encrypted_export_20251204020000.json\nbitwarden_encrypted_export_20251205020000.json"
+	if files != want {
+		t.Fatalf("files on main:\n%s\nwant:\n%s", files, want)
+	}
+}
+
+func TestGitProviderTest(t *testing.T) {
+	provider, remote := newTestGitRemote(t)
+	if err := provider.Test(context.Background(), model.BackupDestination{Type: "git", GitURL: remote}); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+
+	missing := model.BackupDestination{Type: "git", GitURL: filepath.Join(t.TempDir(), "missing.git")}
+	err := provider.Test(context.Background(), missing)
+	if err == nil || !strings.Contains(err.Error(), "git connection test failed") {
+		t.Fatalf("Test() error = %v, want connection failure", err)
+	}
+
+	provider.allowProtocols = "https:ssh"
+	if err := provider.Test(context.Background(), model.BackupDestination{Type: "git", GitURL: remote}); err == nil {
+		t.Fatalf("Test() used a transport outside GIT_ALLOW_PROTOCOL")
+	}
+}
@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/mingzaily/bitwarden-backup/internal/model"
 )
@@ -10,7 +12,9 @@ import (
 type BackupContext struct {
 	Context          context.Context
 	SourceFile       string // 源文件路径
+	TaskID           uint   // 任务 ID
 	TaskName         string // 任务名称
+	RunID            uint   // 运行记录（BackupLog）ID
 	Timestamp        string // YYYYMMDDHHmmss
 	FilenameTemplate string // 备份文件名模板
 	Destination      model.BackupDestination
@@ -40,6 +44,19 @@ type ConnectionTester interface {
 	Test(ctx context.Context, destination model.BackupDestination) error
 }
 
+// ConnectionCheck is the result of one step of a connection test.
+type ConnectionCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ConnectionChecker is implemented by testers that report every step of the
+// test, e.g. credentials, listing and a write probe, instead of one error.
+type ConnectionChecker interface {
+	CheckConnection(ctx context.Context, destination model.BackupDestination) []ConnectionCheck
+}
+
 // RetentionProvider 支持备份保留策略的提供者接口
 type RetentionProvider interface {
 	// Cleanup 清理当前任务文件名模板下超出保留数量的旧备份
@@ -47,3 +64,43 @@ type RetentionProvider interface {
 	// 返回删除的文件数量和错误
 	Cleanup(ctx BackupContext, maxCount int) (int, error)
 }
+
+// DefaultUploadTimeout bounds the upload and retention cleanup of one
+// destination unless its provider asks for more.
+const DefaultUploadTimeout = 5 * time.Minute
+
+// UploadTimeoutProvider 上传可能超过 DefaultUploadTimeout 的提供者接口，
+// 例如分片上传大文件
+type UploadTimeoutProvider interface {
+	// UploadTimeout returns the time the upload of ctx.SourceFile may need.
+	UploadTimeout(ctx BackupContext) time.Duration
+}
+
+// UploadTimeout returns the budget for one destination, never less than
+// DefaultUploadTimeout.
+func UploadTimeout(p DestinationProvider, ctx BackupContext) time.Duration {
+	if tp, ok := p.(UploadTimeoutProvider); ok {
+		return max(tp.UploadTimeout(ctx), DefaultUploadTimeout)
+	}
+	return DefaultUploadTimeout
+}
+
+// Artifact is a backup file that is already stored on a destination.
+type Artifact struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// ArtifactLister 支持列出已有备份文件的提供者接口
+type ArtifactLister interface {
+	// ListArtifacts 列出当前任务文件名模板下的备份文件
+	ListArtifacts(ctx BackupContext) ([]Artifact, error)
+}
+
+// ArtifactDownloader 支持读回已有备份文件的提供者接口
+type ArtifactDownloader interface {
+	// Download writes the named backup file, as returned by ListArtifacts,
+	// to w.
+	Download(ctx BackupContext, name string, w io.Writer) error
+}
@@ -1,12 +1,31 @@
 package provider
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"os/user"
 	"path/filepath"
 	"sort"
+	"strconv"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// localMinFreeSpace is the free space below which the connection test fails;
+// an encrypted export of a large vault is a few MiB.
+const localMinFreeSpace = 64 << 20
+
+// Hidden temp names used inside a local destination. Neither ends in .json,
+// so retention never counts them; SweepLocalTempFiles removes the ones a
+// crashed run left behind.
+const (
+	localPartialPattern = ".bitwarden-backup-*.partial"
+	localProbePattern   = ".bitwarden-backup-probe-*"
 )
 
 // LocalProvider 本地存储提供者
@@ -34,34 +53,425 @@ func (p *LocalProvider) Backup(ctx BackupContext) (string, error) {
 	if dest.LocalPath == "" {
 		return fail(fmt.Errorf("local path is empty"))
 	}
-	if err := os.MkdirAll(dest.LocalPath, 0700); err != nil {
+	perms, err := resolveLocalPermissions(dest)
+	if err != nil {
+		return fail(err)
+	}
+	if err := os.MkdirAll(dest.LocalPath, perms.dirMode); err != nil {
 		return fail(fmt.Errorf("failed to create local directory: %w", err))
 	}
-	if err := os.Chmod(dest.LocalPath, 0700); err != nil {
+	if err := perms.apply(dest.LocalPath, perms.dirMode); err != nil {
 		return fail(fmt.Errorf("failed to secure local directory: %w", err))
 	}
 
 	filename := renderBackupFilename(ctx)
 	targetFile := filepath.Join(dest.LocalPath, filename)
 
-	source, err := os.Open(ctx.SourceFile)
+	if dest.LocalDedup {
+		linked, err := p.linkUnchanged(ctx, targetFile, perms)
+		if err != nil {
+			ctx.AddLog("local", "硬链接去重失败，改为复制: "+err.Error())
+		} else if linked != "" {
+			ctx.AddLog("local", fmt.Sprintf("内容未变化，已硬链接到上一个备份: %s", linked))
+			ctx.AddLog("local", fmt.Sprintf("本地 CP 完成: %s", targetFile))
+			return targetFile, nil
+		}
+	}
+
+	if err := writeLocalFileAtomic(ctx.SourceFile, targetFile, perms); err != nil {
+		return fail(err)
+	}
+
+	ctx.AddLog("local", fmt.Sprintf("本地 CP 完成: %s", targetFile))
+	return targetFile, nil
+}
+
+// localPermissions is the mode and group applied to backup files and the
+// backup directory. The directory gets execute wherever the file mode grants
+// read, e.g. 0640 files live in a 0750 directory.
+type localPermissions struct {
+	fileMode os.FileMode
+	dirMode  os.FileMode
+	gid      int // -1 keeps the group of the running process
+}
+
+func resolveLocalPermissions(dest model.BackupDestination) (localPermissions, error) {
+	perms := localPermissions{fileMode: 0600, gid: -1}
+	if dest.LocalFileMode != "" {
+		mode, err := ParseLocalFileMode(dest.LocalFileMode)
+		if err != nil {
+			return perms, fmt.Errorf("invalid local file mode: %w", err)
+		}
+		perms.fileMode = mode
+	}
+	perms.dirMode = perms.fileMode | (perms.fileMode&0444)>>2
+	if dest.LocalGroup != "" {
+		gid, err := lookupLocalGroup(dest.LocalGroup)
+		if err != nil {
+			return perms, err
+		}
+		perms.gid = gid
+	}
+	return perms, nil
+}
+
+// apply changes the group before the mode, so the file is never readable by
+// the configured mode under the previous group.
+func (perms localPermissions) apply(path string, mode os.FileMode) error {
+	if perms.gid >= 0 {
+		if err := os.Chown(path, -1, perms.gid); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(path, mode)
+}
+
+// ParseLocalFileMode parses an octal file mode such as "0640". Backups stay
+// writable by the owner only: the owner needs read and write, group and
+// other may at most read.
+func ParseLocalFileMode(value string) (os.FileMode, error) {
+	if len(value) < 3 || len(value) > 4 {
+		return 0, fmt.Errorf("must be an octal mode like 0600 or 0640")
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("must be an octal mode like 0600 or 0640")
+	}
+	if mode&0600 != 0600 {
+		return 0, fmt.Errorf("must grant the owner read and write")
+	}
+	if mode&0133 != 0 {
+		return 0, fmt.Errorf("must not grant execute or group/other write")
+	}
+	return os.FileMode(mode), nil
+}
+
+// lookupLocalGroup resolves a group name or numeric GID.
+func lookupLocalGroup(name string) (int, error) {
+	if gid, err := strconv.Atoi(name); err == nil {
+		return gid, nil
+	}
+	group, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up local group: %w", err)
+	}
+	gid, err := strconv.Atoi(group.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("local group %s has no numeric GID", name)
+	}
+	return gid, nil
+}
+
+// linkUnchanged hardlinks targetFile to the newest previous backup of the
+// task when the export has the same content, and returns the linked file.
+// Encrypted exports differ on every run, so only unchanged plain exports
+// are linked.
+func (p *LocalProvider) linkUnchanged(ctx BackupContext, targetFile string, perms localPermissions) (string, error) {
+	artifacts, err := p.ListArtifacts(ctx)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Base(targetFile)
+	sort.Slice(artifacts, func(i, j int) bool {
+		if !artifacts[i].ModTime.Equal(artifacts[j].ModTime) {
+			return artifacts[i].ModTime.After(artifacts[j].ModTime)
+		}
+		return artifacts[i].Name > artifacts[j].Name
+	})
+	var previous string
+	for _, artifact := range artifacts {
+		if artifact.Name != target {
+			previous = filepath.Join(ctx.Destination.LocalPath, artifact.Name)
+			break
+		}
+	}
+	if previous == "" {
+		return "", nil
+	}
+
+	same, err := sameLocalContent(ctx.SourceFile, previous)
+	if err != nil || !same {
+		return "", err
+	}
+	if err := os.Link(previous, targetFile); err != nil {
+		return "", fmt.Errorf("failed to link previous backup: %w", err)
+	}
+	// The link shares the inode, so this also updates the previous file to
+	// the current mode and group.
+	if err := perms.apply(targetFile, perms.fileMode); err != nil {
+		_ = os.Remove(targetFile)
+		return "", fmt.Errorf("failed to secure target file: %w", err)
+	}
+	if err := syncDir(ctx.Destination.LocalPath); err != nil {
+		return "", fmt.Errorf("failed to sync local directory: %w", err)
+	}
+	return previous, nil
+}
+
+// sameLocalContent reports whether two files have identical bytes.
+func sameLocalContent(a, b string) (bool, error) {
+	fileA, err := os.Open(a)
+	if err != nil {
+		return false, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(b)
+	if err != nil {
+		return false, fmt.Errorf("failed to open previous backup: %w", err)
+	}
+	defer fileB.Close()
+
+	infoA, err := fileA.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect source file: %w", err)
+	}
+	infoB, err := fileB.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect previous backup: %w", err)
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	bufA := make([]byte, 64<<10)
+	bufB := make([]byte, 64<<10)
+	for {
+		nA, errA := io.ReadFull(fileA, bufA)
+		nB, errB := io.ReadFull(fileB, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		endA := errors.Is(errA, io.EOF) || errors.Is(errA, io.ErrUnexpectedEOF)
+		endB := errors.Is(errB, io.EOF) || errors.Is(errB, io.ErrUnexpectedEOF)
+		if errA != nil && !endA {
+			return false, fmt.Errorf("failed to read source file: %w", errA)
+		}
+		if errB != nil && !endB {
+			return false, fmt.Errorf("failed to read previous backup: %w", errB)
+		}
+		if endA || endB {
+			return endA && endB, nil
+		}
+	}
+}
+
+// writeLocalFileAtomic copies sourceFile to a temp name in the target
+// directory, fsyncs it and links it into place, so a crash never leaves a
+// truncated backup under the final name. The directory is synced as well to
+// persist the link. Linking fails when the target exists, so an existing
+// backup is never replaced.
+func writeLocalFileAtomic(sourceFile, targetFile string, perms localPermissions) (err error) {
+	source, err := os.Open(sourceFile)
 	if err != nil {
-		return fail(fmt.Errorf("failed to open source file: %w", err))
+		return fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer source.Close()
 
-	target, err := os.OpenFile(targetFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	dir := filepath.Dir(targetFile)
+	temp, err := os.CreateTemp(dir, localPartialPattern)
 	if err != nil {
-		return fail(fmt.Errorf("failed to create target file: %w", err))
+		return fmt.Errorf("failed to create target file: %w", err)
 	}
-	defer target.Close()
+	defer func() {
+		if err != nil {
+			temp.Close()
+			_ = os.Remove(temp.Name())
+		}
+	}()
 
-	if _, err := io.Copy(target, source); err != nil {
-		return fail(fmt.Errorf("failed to copy file: %w", err))
+	if _, err := io.Copy(temp, source); err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync target file: %w", err)
+	}
+	if err := perms.apply(temp.Name(), perms.fileMode); err != nil {
+		return fmt.Errorf("failed to secure target file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write target file: %w", err)
 	}
+	if err := publishLocalFile(temp.Name(), targetFile); err != nil {
+		return err
+	}
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("failed to sync local directory: %w", err)
+	}
+	return nil
+}
 
-	ctx.AddLog("local", fmt.Sprintf("本地 CP 完成: %s", targetFile))
-	return targetFile, nil
+// publishLocalFile moves temp to target without replacing an existing target.
+// Filesystems without hardlinks, e.g. FAT or some network shares, fall back to
+// checking for the target and renaming, which cannot rule out a target created
+// in between.
+func publishLocalFile(temp, target string) error {
+	err := os.Link(temp, target)
+	if err == nil {
+		// A temp file left behind is removed by the startup sweep.
+		_ = os.Remove(temp)
+		return nil
+	}
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("failed to create target file: %w", err)
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("failed to create target file: %w", &fs.PathError{Op: "create", Path: target, Err: fs.ErrExist})
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to inspect target file: %w", err)
+	}
+	if err := os.Rename(temp, target); err != nil {
+		return fmt.Errorf("failed to move target file into place: %w", err)
+	}
+	return nil
+}
+
+// SweepLocalTempFiles removes the temp and probe files that interrupted runs
+// left in dir. It must only run while no backup writes to dir.
+func SweepLocalTempFiles(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	removed := 0
+	var removeErrors []error
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isLocalTempFile(entry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			removeErrors = append(removeErrors, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		removed++
+	}
+	if len(removeErrors) > 0 {
+		return removed, fmt.Errorf("failed to remove local temp files: %w", errors.Join(removeErrors...))
+	}
+	return removed, nil
+}
+
+func isLocalTempFile(name string) bool {
+	for _, pattern := range []string{localPartialPattern, localProbePattern} {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Test verifies that the backup directory is writable and has free space.
+func (p *LocalProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	return connectionChecks(p.CheckConnection(ctx, dest)).err("local")
+}
+
+// CheckConnection creates the directory like a backup would, writes and
+// removes a probe file and checks the free space of the file system.
+func (p *LocalProvider) CheckConnection(_ context.Context, dest model.BackupDestination) []ConnectionCheck {
+	var checks connectionChecks
+	var perms localPermissions
+	if !checks.run("directory", func() (string, error) {
+		if dest.LocalPath == "" {
+			return "", fmt.Errorf("local path is empty")
+		}
+		var err error
+		if perms, err = resolveLocalPermissions(dest); err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(dest.LocalPath, perms.dirMode); err != nil {
+			return "", fmt.Errorf("failed to create local directory: %w", err)
+		}
+		return dest.LocalPath, nil
+	}) {
+		return checks
+	}
+	if !checks.run("write", func() (string, error) {
+		probe, err := os.CreateTemp(dest.LocalPath, localProbePattern)
+		if err != nil {
+			return "", fmt.Errorf("failed to create probe file: %w", err)
+		}
+		defer os.Remove(probe.Name())
+		if _, err := probe.WriteString("bitwarden-backup connection test\n"); err != nil {
+			probe.Close()
+			return "", fmt.Errorf("failed to write probe file: %w", err)
+		}
+		if err := probe.Sync(); err != nil {
+			probe.Close()
+			return "", fmt.Errorf("failed to sync probe file: %w", err)
+		}
+		if err := probe.Close(); err != nil {
+			return "", fmt.Errorf("failed to write probe file: %w", err)
+		}
+		// Backups get the same mode and group, so a group this process is
+		// not a member of fails here rather than on the first run.
+		if err := perms.apply(probe.Name(), perms.fileMode); err != nil {
+			return "", fmt.Errorf("failed to set probe file permissions: %w", err)
+		}
+		return "", nil
+	}) {
+		return checks
+	}
+	checks.run("free_space", func() (string, error) {
+		free, err := localFreeSpace(dest.LocalPath)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return "not available on this platform", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read free space: %w", err)
+		}
+		if free < localMinFreeSpace {
+			return "", fmt.Errorf("only %d MiB available, need at least %d MiB", free>>20, localMinFreeSpace>>20)
+		}
+		return fmt.Sprintf("%d MiB available", free>>20), nil
+	})
+	return checks
+}
+
+// ListArtifacts 列出本地目录中当前任务的备份文件
+func (p *LocalProvider) ListArtifacts(ctx BackupContext) ([]Artifact, error) {
+	dest := ctx.Destination
+	if dest.LocalPath == "" {
+		return nil, fmt.Errorf("local path is empty")
+	}
+	entries, err := os.ReadDir(dest.LocalPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var artifacts []Artifact
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !matchesBackupFilename(entry.Name(), ctx) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", entry.Name(), err)
+		}
+		artifacts = append(artifacts, Artifact{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return artifacts, nil
+}
+
+// Download 读取本地备份文件
+func (p *LocalProvider) Download(ctx BackupContext, name string, w io.Writer) error {
+	if err := checkArtifactName(name, ctx); err != nil {
+		return err
+	}
+	file, err := os.Open(filepath.Join(ctx.Destination.LocalPath, name))
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	return nil
 }
 
 // Cleanup 清理超出保留数量的旧备份
@@ -111,9 +521,12 @@ func (p *LocalProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 		return 0, nil
 	}
 
-	// 按修改时间降序排序（最新在前）
+	// 按修改时间降序排序（最新在前）；硬链接去重的文件共享修改时间，按文件名排序
 	sort.Slice(backups, func(i, j int) bool {
-		return backups[i].modTime > backups[j].modTime
+		if backups[i].modTime != backups[j].modTime {
+			return backups[i].modTime > backups[j].modTime
+		}
+		return backups[i].name > backups[j].name
 	})
 
 	// 删除超出数量的旧文件
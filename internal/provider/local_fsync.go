@@ -0,0 +1,15 @@
+//go:build !windows
+
+package provider
+
+import "os"
+
+// syncDir persists the directory entries of dir, e.g. after a rename.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package provider
+
+// Windows cannot fsync a directory handle; NTFS journals the rename itself.
+func syncDir(string) error {
+	return nil
+}
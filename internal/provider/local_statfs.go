@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd
+
+package provider
+
+import "syscall"
+
+// localFreeSpace returns the bytes available to unprivileged writers.
+func localFreeSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package provider
+
+import "errors"
+
+func localFreeSpace(string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+func TestLocalProviderCheckConnection(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	checks := NewLocalProvider().CheckConnection(context.Background(), model.BackupDestination{Type: "local", LocalPath: dir})
+
+	var names []string
+	for _, check := range checks {
+		if !check.OK {
+			t.Fatalf("check %s failed: %s", check.Name, check.Detail)
+		}
+		names = append(names, check.Name)
+	}
+	if got := strings.Join(names, ","); got != "directory,write,free_space" {
+		t.Fatalf("checks = %s", got)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("probe file left behind: %v, %v", entries, err)
+	}
+}
+
+func TestLocalProviderCheckConnectionStopsAtDirectory(t *testing.T) {
+	// A regular file where the directory should be.
+	path := filepath.Join(t.TempDir(), "backups")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	provider := NewLocalProvider()
+	checks := provider.CheckConnection(context.Background(), model.BackupDestination{Type: "local", LocalPath: path})
+	if len(checks) != 1 || checks[0].Name != "directory" || checks[0].OK {
+		t.Fatalf("checks = %+v, want failure at directory", checks)
+	}
+	err := provider.Test(context.Background(), model.BackupDestination{Type: "local", LocalPath: path})
+	if err == nil || !strings.Contains(err.Error(), "local connection test failed: directory") {
+		t.Fatalf("Test() error = %v", err)
+	}
+}
+
+func TestLocalProviderListsAndDownloadsArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"nightly_local_20251204020000.json", "notes.json", "other_local_20251204020000.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	ctx := BackupContext{
+		TaskName:         "nightly",
+		FilenameTemplate: "{task_name}_{medium}_{time}.json",
+		Destination:      model.BackupDestination{Type: "local", LocalPath: dir},
+	}
+
+	provider := NewLocalProvider()
+	artifacts, err := provider.ListArtifacts(ctx)
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "nightly_local_20251204020000.json" {
+		t.Fatalf("artifacts = %+v", artifacts)
+	}
+	if timestamp, ok := BackupTimestamp(artifacts[0].Name, ctx); !ok || timestamp != "20251204020000" {
+		t.Fatalf("BackupTimestamp() = %q, %v", timestamp, ok)
+	}
+
+	var content strings.Builder
+	if err := provider.Download(ctx, artifacts[0].Name, &content); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if content.String() != "nightly_local_20251204020000.json" {
+		t.Fatalf("downloaded %q", content.String())
+	}
+	if err := provider.Download(ctx, "../nightly_local_20251204020000.json", &content); err == nil {
+		t.Fatal("Download() accepted a name outside the listing")
+	}
+}
+
+func TestLocalProviderBackupWritesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"encrypted":true}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	ctx := BackupContext{
+		Context:     context.Background(),
+		SourceFile:  source,
+		TaskName:    "nightly",
+		Timestamp:   "20251204020000",
+		Destination: model.BackupDestination{Type: "local", LocalPath: dir},
+	}
+
+	provider := NewLocalProvider()
+	target, err := provider.Backup(ctx)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if content, err := os.ReadFile(target); err != nil || string(content) != `{"encrypted":true}` {
+		t.Fatalf("backup file = %q, %v", content, err)
+	}
+	if err := os.WriteFile(source, []byte(`{"encrypted":false}`), 0600); err != nil {
+		t.Fatalf("rewrite source: %v", err)
+	}
+	if _, err := provider.Backup(ctx); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("second Backup() error = %v, want existing file", err)
+	}
+	if content, err := os.ReadFile(target); err != nil || string(content) != `{"encrypted":true}` {
+		t.Fatalf("existing backup was replaced: %q, %v", content, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("directory entries = %v, %v", entries, err)
+	}
+}
+
+func TestParseLocalFileMode(t *testing.T) {
+	for value, want := range map[string]os.FileMode{"0600": 0600, "640": 0640, "0644": 0644} {
+		if got, err := ParseLocalFileMode(value); err != nil || got != want {
+			t.Errorf("ParseLocalFileMode(%q) = %o, %v", value, got, err)
+		}
+	}
+	for _, value := range []string{"", "600x", "0400", "0660", "0700", "0606", "01640", "0999"} {
+		if _, err := ParseLocalFileMode(value); err == nil {
+			t.Errorf("ParseLocalFileMode(%q) accepted", value)
+		}
+	}
+}
+
+func TestLocalProviderDedupLinksUnchangedExport(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "export.json")
+	backup := func(timestamp, content string) string {
+		t.Helper()
+		if err := os.WriteFile(source, []byte(content), 0600); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+		target, err := NewLocalProvider().Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "nightly",
+			Timestamp:   timestamp,
+			Destination: model.BackupDestination{Type: "local", LocalPath: dir, LocalDedup: true},
+		})
+		if err != nil {
+			t.Fatalf("Backup(%s) error = %v", timestamp, err)
+		}
+		return target
+	}
+
+	first := backup("20251204020000", `{"items":[1]}`)
+	second := backup("20251205020000", `{"items":[1]}`)
+	third := backup("20251206020000", `{"items":[2]}`)
+
+	sameFile := func(a, b string) bool {
+		infoA, errA := os.Stat(a)
+		infoB, errB := os.Stat(b)
+		if errA != nil || errB != nil {
+			t.Fatalf("stat: %v, %v", errA, errB)
+		}
+		return os.SameFile(infoA, infoB)
+	}
+	if !sameFile(first, second) {
+		t.Fatalf("unchanged export was copied instead of linked")
+	}
+	if sameFile(second, third) {
+		t.Fatalf("changed export was linked to the previous backup")
+	}
+	if content, err := os.ReadFile(third); err != nil || string(content) != `{"items":[2]}` {
+		t.Fatalf("third backup = %q, %v", content, err)
+	}
+
+	// The linked files share a modification time; retention keeps the newer name.
+	deleted, err := NewLocalProvider().Cleanup(BackupContext{
+		TaskName:    "nightly",
+		Destination: model.BackupDestination{Type: "local", LocalPath: dir},
+	}, 2)
+	if err != nil || deleted != 1 {
+		t.Fatalf("Cleanup() = %d, %v", deleted, err)
+	}
+	if _, err := os.Stat(second); err != nil {
+		t.Fatalf("newer linked backup was removed: %v", err)
+	}
+}
+
+func TestSweepLocalTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		".bitwarden-backup-123.partial",
+		".bitwarden-backup-probe-456",
+		"bitwarden_encrypted_export_20251204020000.json",
+		".bitwarden-backup-notes",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	removed, err := SweepLocalTempFiles(dir)
+	if err != nil || removed != 2 {
+		t.Fatalf("SweepLocalTempFiles() = %d, %v", removed, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var left []string
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+	if got := strings.Join(left, ","); got != ".bitwarden-backup-notes,bitwarden_encrypted_export_20251204020000.json" {
+		t.Fatalf("files left = %s", got)
+	}
+	if removed, err := SweepLocalTempFiles(filepath.Join(dir, "missing")); err != nil || removed != 0 {
+		t.Fatalf("SweepLocalTempFiles(missing) = %d, %v", removed, err)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+func TestLocalProviderAppliesModeAndGroup(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"encrypted":true}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	gid := os.Getgid()
+	target, err := NewLocalProvider().Backup(BackupContext{
+		Context:    context.Background(),
+		SourceFile: source,
+		TaskName:   "nightly",
+		Timestamp:  "20251204020000",
+		Destination: model.BackupDestination{
+			Type: "local", LocalPath: dir, LocalFileMode: "0640", LocalGroup: strconv.Itoa(gid),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	for path, want := range map[string]os.FileMode{target: 0640, dir: 0750} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat %s: %v", path, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %o, want %o", path, got, want)
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Gid) != gid {
+			t.Errorf("%s gid = %d, want %d", path, stat.Gid, gid)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// rclone exits with 3 when the listed directory does not exist yet.
+const rcloneExitDirNotFound = 3
+
+// Config keys whose values are credentials, e.g. pass, token, client_secret,
+// secret_access_key, account_key or sas_url.
+var rcloneSecretKeyRegex = regexp.MustCompile(`(?i)pass|token|secret|key|sas|credential|auth|cookie|session|client_id`)
+
+// RcloneProvider 通过 rclone CLI 写入任意 rclone 远端
+type RcloneProvider struct {
+	// command is the argv prefix used to start rclone; tests replace it with
+	// a helper process.
+	command []string
+}
+
+// NewRcloneProvider 创建 rclone 存储提供者
+func NewRcloneProvider() *RcloneProvider {
+	return &RcloneProvider{command: []string{"rclone"}}
+}
+
+// Type 返回提供者类型
+func (p *RcloneProvider) Type() string {
+	return "rclone"
+}
+
+// Backup 执行 rclone 上传，返回最终存储路径
+func (p *RcloneProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	fail := func(err error) (string, error) {
+		ctx.AddLog("rclone", "rclone 上传失败: "+err.Error())
+		return "", err
+	}
+	ctx.AddLog("rclone", fmt.Sprintf("开始 rclone 上传: %s", dest.RcloneRemote))
+
+	runner, cleanup, err := p.open(dest, ctx.AddLog)
+	if err != nil {
+		return fail(err)
+	}
+	defer cleanup()
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 5*time.Minute)
+	defer cancel()
+
+	// copyto verifies the checksum after the transfer whenever the backend
+	// supports one, and backends without atomic writes upload to a partial
+	// name first.
+	target := rcloneTarget(dest, renderBackupFilename(ctx))
+	if _, err := runner.run(requestCtx, "copyto", ctx.SourceFile, target); err != nil {
+		return fail(err)
+	}
+
+	ctx.AddLog("rclone", fmt.Sprintf("rclone 上传完成: %s", target))
+	return target, nil
+}
+
+// Test lists the configured directory, which checks the config, the
+// credentials and the listing permission retention needs. A directory that
+// does not exist yet is fine; copyto creates it on the first backup.
+func (p *RcloneProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	runner, cleanup, err := p.open(dest, nil)
+	if err != nil {
+		return fmt.Errorf("rclone connection test failed: %w", err)
+	}
+	defer cleanup()
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx), 30*time.Second)
+	defer cancel()
+	if _, err := runner.list(requestCtx, rcloneTarget(dest, "")); err != nil {
+		return fmt.Errorf("rclone connection test failed: %w", err)
+	}
+	return nil
+}
+
+// Cleanup 清理超出保留数量的旧备份
+func (p *RcloneProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
+	if maxCount <= 0 {
+		return 0, nil
+	}
+
+	dest := ctx.Destination
+	runner, cleanup, err := p.open(dest, ctx.AddLog)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 2*time.Minute)
+	defer cancel()
+
+	entries, err := runner.list(requestCtx, rcloneTarget(dest, ""))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list remote directory: %w", err)
+	}
+	var backups []rcloneEntry
+	for _, entry := range entries {
+		if !entry.IsDir && matchesBackupFilename(entry.Name, ctx) {
+			backups = append(backups, entry)
+		}
+	}
+	if len(backups) <= maxCount {
+		return 0, nil
+	}
+
+	// 按修改时间降序排序
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime.After(backups[j].ModTime)
+	})
+
+	deleted := 0
+	var deleteErrors []error
+	for i := maxCount; i < len(backups); i++ {
+		if _, err := runner.run(requestCtx, "deletefile", rcloneTarget(dest, backups[i].Name)); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("%s: %w", backups[i].Name, err))
+			continue
+		}
+		deleted++
+	}
+	if len(deleteErrors) > 0 {
+		return deleted, fmt.Errorf("failed to delete some rclone files: %w", errors.Join(deleteErrors...))
+	}
+	return deleted, nil
+}
+
+type rcloneEntry struct {
+	Name    string    `json:"Name"`
+	ModTime time.Time `json:"ModTime"`
+	IsDir   bool      `json:"IsDir"`
+}
+
+type rcloneExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// rcloneRunner runs rclone against a private copy of the inline config.
+type rcloneRunner struct {
+	command    []string
+	configPath string
+	secrets    []string
+	log        func(source, message string)
+}
+
+// open writes the decrypted config to a 0600 temp file that only lives for
+// one operation. rclone may rewrite it to refresh OAuth tokens; those
+// refreshes are discarded, the stored refresh token stays valid.
+func (p *RcloneProvider) open(dest model.BackupDestination, log func(source, message string)) (*rcloneRunner, func(), error) {
+	if strings.TrimSpace(dest.RcloneConfig) == "" {
+		return nil, nil, fmt.Errorf("rclone config is required")
+	}
+	if dest.RcloneRemote == "" {
+		return nil, nil, fmt.Errorf("rclone remote is required")
+	}
+
+	file, err := os.CreateTemp("", "bitwarden-backup-rclone-*.conf")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create rclone config: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(file.Name()) }
+	if _, err := file.WriteString(dest.RcloneConfig); err != nil {
+		file.Close()
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write rclone config: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write rclone config: %w", err)
+	}
+
+	return &rcloneRunner{
+		command:    p.command,
+		configPath: file.Name(),
+		secrets:    rcloneConfigSecrets(dest.RcloneConfig),
+		log:        log,
+	}, cleanup, nil
+}
+
+func (r *rcloneRunner) run(ctx context.Context, args ...string) (rcloneExecResult, error) {
+	argv := append([]string{}, r.command[1:]...)
+	argv = append(argv, "--config", r.configPath, "--ask-password=false")
+	argv = append(argv, args...)
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, r.command[0], argv...)
+	cmd.WaitDelay = 5 * time.Second
+	// Environment variables override the config file in rclone (for example
+	// RCLONE_CONFIG_<REMOTE>_TYPE), so only the inline config applies.
+	cmd.Env = commandEnv("RCLONE_")
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	err := cmd.Run()
+	exitCode := 0
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+	res := rcloneExecResult{Stdout: stdoutBuf.String(), Stderr: stderrBuf.String(), ExitCode: exitCode}
+
+	if r.log != nil {
+		r.log("rclone", fmt.Sprintf("rclone %s (exit=%d, %dms)", strings.Join(args, " "), exitCode, time.Since(start).Milliseconds()))
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return res, fmt.Errorf("failed to run rclone: %w", err)
+		}
+		message := sanitizeCommandOutput(res.Stderr, r.secrets)
+		if message == "" {
+			return res, fmt.Errorf("rclone %s failed (exit=%d)", args[0], exitCode)
+		}
+		return res, fmt.Errorf("rclone %s failed (exit=%d): %s", args[0], exitCode, message)
+	}
+	return res, nil
+}
+
+// list returns the files directly inside target; a missing directory is
+// reported as empty.
+func (r *rcloneRunner) list(ctx context.Context, target string) ([]rcloneEntry, error) {
+	res, err := r.run(ctx, "lsjson", "--files-only", "--no-mimetype", target)
+	if res.ExitCode == rcloneExitDirNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []rcloneEntry
+	if err := json.Unmarshal([]byte(res.Stdout), &entries); err != nil {
+		return nil, fmt.Errorf("invalid rclone lsjson output: %w", err)
+	}
+	return entries, nil
+}
+
+// rcloneConfigSecrets collects credential values from an rclone.conf.
+func rcloneConfigSecrets(config string) []string {
+	var secrets []string
+	for _, line := range strings.Split(config, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		value = strings.TrimSpace(value)
+		if ok && len(value) >= 4 && rcloneSecretKeyRegex.MatchString(key) {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// rcloneTarget builds "remote:dir/name"; an empty name addresses the
+// configured directory itself.
+func rcloneTarget(dest model.BackupDestination, name string) string {
+	dir := strings.Trim(path.Clean("/"+strings.TrimSpace(dest.RclonePath)), "/")
+	return dest.RcloneRemote + ":" + path.Join(dir, name)
+}
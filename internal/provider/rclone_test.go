@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+const testRcloneConfig = `[vault]
+type = sftp
+host = nas.local
+user = backup
+pass = 0bscured-s3cret-value
+`
+
+// TestRcloneHelperProcess is not a real test. It is started as the rclone
+// binary and serves copyto, lsjson and deletefile for the remote "vault"
+// from the directory in GO_RCLONE_HELPER_ROOT.
+func TestRcloneHelperProcess(t *testing.T) {
+	root := os.Getenv("GO_RCLONE_HELPER_ROOT")
+	if root == "" {
+		return
+	}
+	os.Exit(runRcloneHelper(root, os.Args))
+}
+
+func runRcloneHelper(root string, argv []string) int {
+	for i, arg := range argv {
+		if arg == "--" {
+			argv = argv[i+1:]
+			break
+		}
+	}
+	for _, entry := range os.Environ() {
+		if strings.HasPrefix(entry, "RCLONE_") {
+			fmt.Fprintf(os.Stderr, "unexpected environment override %s\n", entry)
+			return 1
+		}
+	}
+	if len(argv) < 4 || argv[0] != "--config" || argv[2] != "--ask-password=false" {
+		fmt.Fprintf(os.Stderr, "unexpected arguments %v\n", argv)
+		return 1
+	}
+	config, err := os.ReadFile(argv[1])
+	if err != nil || !strings.Contains(string(config), "[vault]") {
+		fmt.Fprintln(os.Stderr, "Failed to create file system: didn't find section in config file")
+		return 1
+	}
+
+	local := func(target string) (string, bool) {
+		remote, dir, ok := strings.Cut(target, ":")
+		return filepath.Join(root, filepath.FromSlash(dir)), ok && remote == "vault"
+	}
+	args := argv[3:]
+	switch args[0] {
+	case "copyto":
+		target, ok := local(args[2])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Failed to create file system for %q: pass = %s\n", args[2], "0bscured-s3cret-value")
+			return 1
+		}
+		data, err := os.ReadFile(args[1])
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(target), 0700)
+		}
+		if err == nil {
+			err = os.WriteFile(target, data, 0600)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "lsjson":
+		dir, _ := local(args[len(args)-1])
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "error listing: directory not found")
+			return rcloneExitDirNotFound
+		}
+		var listing []map[string]any
+		for _, entry := range entries {
+			info, _ := entry.Info()
+			listing = append(listing, map[string]any{"Name": entry.Name(), "ModTime": info.ModTime(), "IsDir": entry.IsDir()})
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(listing)
+	case "deletefile":
+		target, _ := local(args[1])
+		if err := os.Remove(target); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 4
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return 1
+	}
+	return 0
+}
+
+func newTestRcloneProvider(t *testing.T) (*RcloneProvider, string) {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("GO_RCLONE_HELPER_ROOT", root)
+	// Must not reach the child process, it would override the inline config.
+	t.Setenv("RCLONE_CONFIG_VAULT_TYPE", "local")
+	return &RcloneProvider{command: []string{os.Args[0], "-test.run=^TestRcloneHelperProcess$", "--"}}, root
+}
+
+func TestRcloneProviderUploadsAndAppliesRetention(t *testing.T) {
+	provider, root := newTestRcloneProvider(t)
+	dest := model.BackupDestination{Type: "rclone", RcloneRemote: "vault", RclonePath: "/nightly/", RcloneConfig: testRcloneConfig}
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	var logs []string
+	remoteDir := filepath.Join(root, "nightly")
+	for _, timestamp := range []string{"20251204020000", "20251205020000", "20251206020000"} {
+		remotePath, err := provider.Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   timestamp,
+			Destination: dest,
+			Log:         func(_, message string) { logs = append(logs, message) },
+		})
+		if err != nil {
+			t.Fatalf("backup %s: %v", timestamp, err)
+		}
+		if want := "vault:nightly/bitwarden_encrypted_export_" + timestamp + ".json"; remotePath != want {
+			t.Fatalf("remote path = %q, want %q", remotePath, want)
+		}
+		modTime, _ := time.Parse("20060102150405", timestamp)
+		if err := os.Chtimes(filepath.Join(remoteDir, "bitwarden_encrypted_export_"+timestamp+".json"), modTime, modTime); err != nil {
+			t.Fatalf("set modification time: %v", err)
+		}
+	}
+	if !strings.Contains(strings.Join(logs, "\n"), "rclone copyto") {
+		t.Fatalf("command was not logged: %v", logs)
+	}
+
+	deleted, err := provider.Cleanup(BackupContext{Context: context.Background(), TaskName: "Nightly", Destination: dest}, 2)
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+	entries, err := os.ReadDir(remoteDir)
+	if err != nil {
+		t.Fatalf("read remote directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "bitwarden_encrypted_export_20251205020000.json,bitwarden_encrypted_export_20251206020000.json" {
+		t.Fatalf("unexpected remaining files: %v", names)
+	}
+}
+
+func TestRcloneProviderTestAcceptsMissingDirectory(t *testing.T) {
+	provider, _ := newTestRcloneProvider(t)
+	dest := model.BackupDestination{Type: "rclone", RcloneRemote: "vault", RclonePath: "not/created/yet", RcloneConfig: testRcloneConfig}
+	if err := provider.Test(context.Background(), dest); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+}
+
+func TestRcloneProviderRedactsConfigSecretsInErrors(t *testing.T) {
+	provider, _ := newTestRcloneProvider(t)
+	dest := model.BackupDestination{Type: "rclone", RcloneRemote: "other", RcloneConfig: testRcloneConfig}
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	_, err := provider.Backup(BackupContext{Context: context.Background(), SourceFile: source, Timestamp: "20251204020000", Destination: dest})
+	if err == nil || !strings.Contains(err.Error(), "rclone copyto failed (exit=1)") {
+		t.Fatalf("Backup() error = %v, want copyto failure", err)
+	}
+	if strings.Contains(err.Error(), "0bscured-s3cret-value") {
+		t.Fatalf("error leaks the config secret: %v", err)
+	}
+}
+
+func TestSanitizeCommandOutputMasksRcloneSecrets(t *testing.T) {
+	secrets := rcloneConfigSecrets(testRcloneConfig + "token = {\"access_token\":\"ya29.short\"}\n")
+	output := "\x1b[31mERROR\x1b[0m : upload failed: 401 for 0bscured-s3cret-value\n" +
+		"DEBUG : vault: pass = 0bscured-s3cret-value\n" +
+		"using key " + strings.Repeat("a", 70)
+	want := "ERROR : upload failed: 401 for ***\nusing key ***"
+	if got := sanitizeCommandOutput(output, secrets); got != want {
+		t.Fatalf("sanitizeCommandOutput() = %q, want %q", got, want)
+	}
+}
@@ -27,6 +27,14 @@ func GetRegistry() *Registry {
 		defaultRegistry.Register(NewWebDAVProvider())
 		defaultRegistry.Register(NewServerProvider())
 		defaultRegistry.Register(NewS3Provider())
+		defaultRegistry.Register(NewSFTPProvider())
+		defaultRegistry.Register(NewFTPProvider())
+		defaultRegistry.Register(NewSMBProvider())
+		defaultRegistry.Register(NewAzureBlobProvider())
+		defaultRegistry.Register(NewGCSProvider())
+		defaultRegistry.Register(NewRcloneProvider())
+		defaultRegistry.Register(NewGitProvider())
+		defaultRegistry.Register(NewEmailProvider())
 	})
 	return defaultRegistry
 }
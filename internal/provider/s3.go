@@ -2,8 +2,11 @@ package provider
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -12,18 +15,35 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
 	"github.com/mingzaily/bitwarden-backup/internal/model"
 	"github.com/mingzaily/bitwarden-backup/internal/safety"
 )
 
+const (
+	s3DefaultPartSize   = 16 * 1024 * 1024
+	s3UploadConcurrency = 3
+	// Multipart uploads older than this are considered orphaned; no backup
+	// upload runs for a day.
+	s3StaleUploadAge = 24 * time.Hour
+)
+
 // S3Provider S3 存储提供者
-type S3Provider struct{}
+type S3Provider struct {
+	partSize int64
+	// httpClient replaces the SDK's HTTP client when set; tests use it to
+	// route virtual-hosted requests to a local server.
+	httpClient aws.HTTPClient
+}
 
 // NewS3Provider 创建 S3 存储提供者
 func NewS3Provider() *S3Provider {
-	return &S3Provider{}
+	return &S3Provider{partSize: s3DefaultPartSize}
 }
 
 // Type 返回提供者类型
@@ -31,6 +51,15 @@ func (p *S3Provider) Type() string {
 	return "s3"
 }
 
+// s3UploadTimeout bounds an upload. Large exports with attachments are
+// uploaded in parts and need more than DefaultUploadTimeout.
+const s3UploadTimeout = 30 * time.Minute
+
+// UploadTimeout extends the scheduler's budget to s3UploadTimeout.
+func (p *S3Provider) UploadTimeout(BackupContext) time.Duration {
+	return s3UploadTimeout
+}
+
 // Backup 执行 S3 备份，返回最终存储路径
 func (p *S3Provider) Backup(ctx BackupContext) (string, error) {
 	dest := ctx.Destination
@@ -39,37 +68,18 @@ func (p *S3Provider) Backup(ctx BackupContext) (string, error) {
 		return "", err
 	}
 	ctx.AddLog("s3", fmt.Sprintf("开始 OSS 上传: %s", dest.S3Bucket))
-	if err := validateS3Destination(dest); err != nil {
-		return fail(err)
-	}
 	parentCtx := ctx.Context
 	if parentCtx == nil {
 		parentCtx = context.Background()
 	}
-	requestCtx, cancel := context.WithTimeout(parentCtx, 5*time.Minute)
+	requestCtx, cancel := context.WithTimeout(parentCtx, s3UploadTimeout)
 	defer cancel()
 
-	// 创建 S3 客户端配置
-	cfg, err := config.LoadDefaultConfig(requestCtx,
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			dest.S3AccessKey,
-			dest.S3SecretKey,
-			"",
-		)),
-		config.WithRegion(dest.S3Region),
-	)
+	client, err := p.createClient(requestCtx, dest)
 	if err != nil {
-		return fail(fmt.Errorf("failed to load S3 config: %w", err))
+		return fail(err)
 	}
 
-	// 创建 S3 客户端
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		if dest.S3Endpoint != "" {
-			o.BaseEndpoint = aws.String(dest.S3Endpoint)
-			o.UsePathStyle = true
-		}
-	})
-
 	// 打开源文件
 	file, err := os.Open(ctx.SourceFile)
 	if err != nil {
@@ -77,28 +87,269 @@ func (p *S3Provider) Backup(ctx BackupContext) (string, error) {
 	}
 	defer file.Close()
 
-	// 构建远程路径
-	remotePath := strings.TrimPrefix(dest.S3Path, "/")
-	if remotePath != "" {
-		remotePath = remotePath + "/"
-	}
-	key := remotePath + renderBackupFilename(ctx)
+	key := s3Prefix(dest) + renderBackupFilename(ctx)
 
 	// 上传文件
-	_, err = client.PutObject(requestCtx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(dest.S3Bucket),
 		Key:    aws.String(key),
 		Body:   file,
-	})
-	if err != nil {
+	}
+	if err := applyS3UploadOptions(input, dest, time.Now()); err != nil {
+		return fail(err)
+	}
+	if input.ObjectLockRetainUntilDate != nil {
+		ctx.AddLog("s3", fmt.Sprintf("对象锁定 %s 至 %s", dest.S3ObjectLockMode, input.ObjectLockRetainUntilDate.Format(time.RFC3339)))
+	}
+	if err := p.upload(requestCtx, client, input, ctx.AddLog); err != nil {
 		return fail(fmt.Errorf("failed to upload to S3: %w", err))
 	}
 
 	ctx.AddLog("s3", fmt.Sprintf("OSS 上传完成: %s", key))
+	p.abortStaleUploads(requestCtx, client, ctx)
 	// 返回 S3 路径
 	return fmt.Sprintf("s3://%s/%s", dest.S3Bucket, key), nil
 }
 
+// upload sends small files with one PutObject and larger ones as a multipart
+// upload. The manager's own abort would reuse the cancelled context, so a
+// failed multipart upload is aborted here with a fresh one; otherwise its
+// parts stay billable until the next stale-upload sweep.
+func (p *S3Provider) upload(ctx context.Context, client *s3.Client, input *s3.PutObjectInput, log func(source, message string)) error {
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = p.partSize
+		u.Concurrency = s3UploadConcurrency
+		u.LeavePartsOnError = true
+	})
+	_, err := uploader.Upload(ctx, input)
+	if err == nil {
+		return nil
+	}
+	var multipartErr manager.MultiUploadFailure
+	if errors.As(err, &multipartErr) && multipartErr.UploadID() != "" {
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		_, abortErr := client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   input.Bucket,
+			Key:      input.Key,
+			UploadId: aws.String(multipartErr.UploadID()),
+		})
+		if abortErr != nil {
+			log("s3", "中止分片上传失败，将在之后的备份中清理: "+abortErr.Error())
+		} else {
+			log("s3", "已中止未完成的分片上传")
+		}
+	}
+	return err
+}
+
+// abortStaleUploads aborts multipart uploads of this task's backups that were
+// started more than s3StaleUploadAge ago, e.g. by a process that crashed
+// mid-upload. Failures only log: the backup itself already succeeded.
+func (p *S3Provider) abortStaleUploads(ctx context.Context, client *s3.Client, backupCtx BackupContext) {
+	dest := backupCtx.Destination
+	prefix := s3Prefix(dest)
+	cutoff := time.Now().Add(-s3StaleUploadAge)
+	aborted := 0
+	input := &s3.ListMultipartUploadsInput{Bucket: aws.String(dest.S3Bucket), Prefix: aws.String(prefix)}
+	for {
+		result, err := client.ListMultipartUploads(ctx, input)
+		if err != nil {
+			backupCtx.AddLog("s3", "列出未完成的分片上传失败: "+err.Error())
+			return
+		}
+		for _, upload := range result.Uploads {
+			relative := strings.TrimPrefix(aws.ToString(upload.Key), prefix)
+			if strings.Contains(relative, "/") || !matchesBackupFilename(relative, backupCtx) {
+				continue
+			}
+			if upload.Initiated == nil || upload.Initiated.After(cutoff) {
+				continue
+			}
+			if _, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(dest.S3Bucket),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			}); err != nil {
+				backupCtx.AddLog("s3", fmt.Sprintf("中止遗留分片上传失败 %s: %s", aws.ToString(upload.Key), err.Error()))
+				continue
+			}
+			aborted++
+		}
+		if !aws.ToBool(result.IsTruncated) {
+			break
+		}
+		input.KeyMarker = result.NextKeyMarker
+		input.UploadIdMarker = result.NextUploadIdMarker
+	}
+	if aborted > 0 {
+		backupCtx.AddLog("s3", fmt.Sprintf("已中止 %d 个遗留的分片上传", aborted))
+	}
+}
+
+// Test verifies the credentials, the bucket listing and a probe write.
+func (p *S3Provider) Test(ctx context.Context, dest model.BackupDestination) error {
+	return connectionChecks(p.CheckConnection(ctx, dest)).err("S3")
+}
+
+// CheckConnection resolves the credentials, checks the bucket and the
+// listing retention needs, then writes and deletes a probe object under the
+// prefix. The probe carries the encryption options, which a bucket policy
+// may require, but never an object lock or legal hold that would pin it.
+func (p *S3Provider) CheckConnection(ctx context.Context, dest model.BackupDestination) []ConnectionCheck {
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx), 30*time.Second)
+	defer cancel()
+
+	var checks connectionChecks
+	var client *s3.Client
+	if !checks.run("credentials", func() (string, error) {
+		if err := validateS3Destination(dest); err != nil {
+			return "", err
+		}
+		cfg, err := p.loadS3Config(requestCtx, dest)
+		if err != nil {
+			return "", err
+		}
+		creds, err := cfg.Credentials.Retrieve(requestCtx)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve S3 credentials: %w", err)
+		}
+		client = p.newClient(cfg, dest)
+		return creds.Source, nil
+	}) {
+		return checks
+	}
+	if !checks.run("bucket", func() (string, error) {
+		output, err := client.HeadBucket(requestCtx, &s3.HeadBucketInput{Bucket: aws.String(dest.S3Bucket)})
+		if err != nil {
+			return "", fmt.Errorf("failed to access bucket: %w", err)
+		}
+		return aws.ToString(output.BucketRegion), nil
+	}) {
+		return checks
+	}
+	prefix := s3Prefix(dest)
+	if !checks.run("list", func() (string, error) {
+		_, err := client.ListObjectsV2(requestCtx, &s3.ListObjectsV2Input{
+			Bucket:  aws.String(dest.S3Bucket),
+			Prefix:  aws.String(prefix),
+			MaxKeys: aws.Int32(1),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to list objects: %w", err)
+		}
+		return "", nil
+	}) {
+		return checks
+	}
+
+	key := fmt.Sprintf("%s.bitwarden-backup-probe-%d", prefix, time.Now().UnixNano())
+	if !checks.run("write", func() (string, error) {
+		probeDest := dest
+		probeDest.S3ObjectLockMode = ""
+		probeDest.S3ObjectLockDays = 0
+		probeDest.S3LegalHold = false
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(dest.S3Bucket),
+			Key:         aws.String(key),
+			Body:        strings.NewReader("bitwarden-backup connection test\n"),
+			ContentType: aws.String("text/plain"),
+		}
+		if err := applyS3UploadOptions(input, probeDest, time.Now()); err != nil {
+			return "", err
+		}
+		if _, err := client.PutObject(requestCtx, input); err != nil {
+			return "", fmt.Errorf("failed to write probe object: %w", err)
+		}
+		return key, nil
+	}) {
+		return checks
+	}
+	checks.run("delete", func() (string, error) {
+		if _, err := client.DeleteObject(requestCtx, &s3.DeleteObjectInput{
+			Bucket: aws.String(dest.S3Bucket),
+			Key:    aws.String(key),
+		}); err != nil {
+			return "", fmt.Errorf("failed to delete probe object: %w", err)
+		}
+		return "", nil
+	})
+	return checks
+}
+
+// ListArtifacts 列出配置目录下当前任务的备份对象
+func (p *S3Provider) ListArtifacts(ctx BackupContext) ([]Artifact, error) {
+	dest := ctx.Destination
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 2*time.Minute)
+	defer cancel()
+	client, err := p.createClient(requestCtx, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := s3Prefix(dest)
+	var artifacts []Artifact
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(dest.S3Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(requestCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, obj := range result.Contents {
+			relative := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if strings.Contains(relative, "/") || !matchesBackupFilename(relative, ctx) {
+				continue
+			}
+			artifacts = append(artifacts, Artifact{
+				Name:    relative,
+				Size:    aws.ToInt64(obj.Size),
+				ModTime: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+	return artifacts, nil
+}
+
+// Download 读取备份对象；SSE-C 对象需要携带同一个客户密钥
+func (p *S3Provider) Download(ctx BackupContext, name string, w io.Writer) error {
+	if err := checkArtifactName(name, ctx); err != nil {
+		return err
+	}
+	dest := ctx.Destination
+	requestCtx, cancel := context.WithTimeout(contextOrBackground(ctx.Context), 30*time.Minute)
+	defer cancel()
+	client, err := p.createClient(requestCtx, dest)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(dest.S3Bucket),
+		Key:    aws.String(s3Prefix(dest) + name),
+	}
+	if dest.S3Encryption == "sse-c" {
+		keyMD5, err := s3SSECustomerKeyMD5(dest)
+		if err != nil {
+			return err
+		}
+		input.SSECustomerAlgorithm = aws.String("AES256")
+		input.SSECustomerKey = aws.String(dest.S3SSECustomerKey)
+		input.SSECustomerKeyMD5 = aws.String(keyMD5)
+	}
+	output, err := client.GetObject(requestCtx, input)
+	if err != nil {
+		return fmt.Errorf("failed to download object: %w", err)
+	}
+	defer output.Body.Close()
+	if _, err := io.Copy(w, output.Body); err != nil {
+		return fmt.Errorf("failed to download object: %w", err)
+	}
+	return nil
+}
+
 // Cleanup 清理超出保留数量的旧备份
 func (p *S3Provider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 	if maxCount <= 0 {
@@ -106,26 +357,22 @@ func (p *S3Provider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 	}
 
 	dest := ctx.Destination
-	client, err := p.createClient(dest)
+	parentCtx := ctx.Context
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	requestCtx, cancel := context.WithTimeout(parentCtx, 2*time.Minute)
+	defer cancel()
+	client, err := p.createClient(requestCtx, dest)
 	if err != nil {
 		return 0, err
 	}
 
-	// 构建前缀
-	prefix := strings.TrimPrefix(dest.S3Path, "/")
-	if prefix != "" {
-		prefix = prefix + "/"
-	}
 	// List the directory prefix so both the new bitwarden_* names and legacy
 	// backup_* files can participate in retention cleanup.
+	prefix := s3Prefix(dest)
 
 	// 列举对象
-	parentCtx := ctx.Context
-	if parentCtx == nil {
-		parentCtx = context.Background()
-	}
-	requestCtx, cancel := context.WithTimeout(parentCtx, 2*time.Minute)
-	defer cancel()
 	listInput := &s3.ListObjectsV2Input{
 		Bucket: aws.String(dest.S3Bucket),
 		Prefix: aws.String(prefix),
@@ -168,21 +415,32 @@ func (p *S3Provider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 		return backups[i].LastModified.After(*backups[j].LastModified)
 	})
 
-	// 删除超出数量的旧文件
+	// 删除超出数量的旧文件。仍受保留期或合法保留保护的对象不能删除，
+	// 跳过它们而不是报错，等锁定到期后的某次清理再删除。
 	var toDelete []types.ObjectIdentifier
+	var deleteErrors []error
+	locks := &s3LockChecker{client: client, bucket: dest.S3Bucket, now: time.Now()}
+	skipped := 0
 	for i := maxCount; i < len(backups); i++ {
+		locked, err := locks.locked(requestCtx, aws.ToString(backups[i].Key))
+		if err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("%s: %w", aws.ToString(backups[i].Key), err))
+			continue
+		}
+		if locked {
+			skipped++
+			continue
+		}
 		toDelete = append(toDelete, types.ObjectIdentifier{
 			Key: backups[i].Key,
 		})
 	}
-
-	if len(toDelete) == 0 {
-		return 0, nil
+	if skipped > 0 {
+		ctx.AddLog("s3", fmt.Sprintf("跳过 %d 个仍处于对象锁定期的旧备份", skipped))
 	}
 
 	const maxDeleteBatch = 1000
 	deleted := 0
-	var deleteErrors []error
 	for start := 0; start < len(toDelete); start += maxDeleteBatch {
 		end := start + maxDeleteBatch
 		if end > len(toDelete) {
@@ -215,32 +473,110 @@ func (p *S3Provider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 }
 
 // createClient 创建 S3 客户端
-func (p *S3Provider) createClient(dest model.BackupDestination) (*s3.Client, error) {
+func (p *S3Provider) createClient(ctx context.Context, dest model.BackupDestination) (*s3.Client, error) {
 	if err := validateS3Destination(dest); err != nil {
 		return nil, err
 	}
-	requestCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	cfg, err := config.LoadDefaultConfig(requestCtx,
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			dest.S3AccessKey,
-			dest.S3SecretKey,
-			"",
-		)),
-		config.WithRegion(dest.S3Region),
-	)
+	cfg, err := p.loadS3Config(ctx, dest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load S3 config: %w", err)
+		return nil, err
 	}
+	return p.newClient(cfg, dest), nil
+}
 
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+func (p *S3Provider) newClient(cfg aws.Config, dest model.BackupDestination) *s3.Client {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
 		if dest.S3Endpoint != "" {
 			o.BaseEndpoint = aws.String(dest.S3Endpoint)
-			o.UsePathStyle = true
+		}
+		o.UsePathStyle = s3UsePathStyle(dest)
+		if p.httpClient != nil {
+			o.HTTPClient = p.httpClient
 		}
 	})
+}
+
+// loadS3Config resolves the credentials for the destination's mode. The role
+// modes exchange a base identity for temporary credentials through STS; the
+// cache refreshes them before they expire during long uploads.
+func (p *S3Provider) loadS3Config(ctx context.Context, dest model.BackupDestination) (aws.Config, error) {
+	options := []func(*config.LoadOptions) error{config.WithRegion(dest.S3Region)}
+	mode := model.S3CredentialModeOrDefault(dest.S3CredentialMode)
+	// AssumeRole signs the STS call with the static keys when they are set
+	// and with the default chain otherwise.
+	if mode == "static" || (mode == "assume_role" && dest.S3AccessKey != "") {
+		options = append(options, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			dest.S3AccessKey,
+			dest.S3SecretKey,
+			"",
+		)))
+	} else if mode == "web_identity" {
+		// Replaced below; this keeps the SDK from resolving its own web
+		// identity provider, which fails without AWS_ROLE_ARN.
+		options = append(options, config.WithCredentialsProvider(aws.AnonymousCredentials{}))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load S3 config: %w", err)
+	}
 
-	return client, nil
+	stsClient := func() *sts.Client {
+		return sts.NewFromConfig(cfg, func(o *sts.Options) {
+			if p.httpClient != nil {
+				o.HTTPClient = p.httpClient
+			}
+		})
+	}
+	sessionName := dest.S3RoleSessionName
+	if sessionName == "" {
+		sessionName = "bitwarden-backup"
+	}
+	switch mode {
+	case "assume_role":
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsClient(), dest.S3RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if dest.S3ExternalID != "" {
+				o.ExternalID = aws.String(dest.S3ExternalID)
+			}
+		}))
+	case "web_identity":
+		tokenFile := dest.S3WebIdentityTokenFile
+		if tokenFile == "" {
+			// Set by EKS for pods with an IAM role for service accounts.
+			tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+		}
+		if tokenFile == "" {
+			return aws.Config{}, fmt.Errorf("web identity token file is required")
+		}
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewWebIdentityRoleProvider(stsClient(), dest.S3RoleARN, stscreds.IdentityTokenFile(tokenFile), func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = sessionName
+		}))
+	}
+	return cfg, nil
+}
+
+// s3UsePathStyle resolves the addressing style. Without an explicit choice a
+// custom endpoint keeps path-style, which most S3-compatible services need,
+// and AWS itself uses virtual-hosted style.
+func s3UsePathStyle(dest model.BackupDestination) bool {
+	switch dest.S3AddressingStyle {
+	case "path":
+		return true
+	case "virtual":
+		return false
+	default:
+		return dest.S3Endpoint != ""
+	}
+}
+
+// s3Prefix returns the configured directory as a key prefix ending in "/",
+// or "" for the bucket root.
+func s3Prefix(dest model.BackupDestination) string {
+	prefix := strings.TrimPrefix(dest.S3Path, "/")
+	if prefix != "" {
+		prefix = prefix + "/"
+	}
+	return prefix
 }
 
 func validateS3Destination(dest model.BackupDestination) error {
@@ -249,3 +585,124 @@ func validateS3Destination(dest model.BackupDestination) error {
 	}
 	return safety.ValidateURL(dest.S3Endpoint, "s3_endpoint", true)
 }
+
+// applyS3UploadOptions sets the storage class, server-side encryption, object
+// lock and checksum options configured on the destination.
+func applyS3UploadOptions(input *s3.PutObjectInput, dest model.BackupDestination, now time.Time) error {
+	if dest.S3StorageClass != "" {
+		input.StorageClass = types.StorageClass(dest.S3StorageClass)
+	}
+	switch dest.S3Encryption {
+	case "":
+	case "sse-s3":
+		input.ServerSideEncryption = types.ServerSideEncryptionAes256
+	case "sse-kms":
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		if dest.S3KMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(dest.S3KMSKeyID)
+		}
+	case "sse-c":
+		keyMD5, err := s3SSECustomerKeyMD5(dest)
+		if err != nil {
+			return err
+		}
+		input.SSECustomerAlgorithm = aws.String("AES256")
+		input.SSECustomerKey = aws.String(dest.S3SSECustomerKey)
+		input.SSECustomerKeyMD5 = aws.String(keyMD5)
+	default:
+		return fmt.Errorf("unsupported S3 encryption mode: %s", dest.S3Encryption)
+	}
+	if dest.S3ObjectLockMode != "" {
+		if dest.S3ObjectLockDays <= 0 {
+			return fmt.Errorf("S3 object lock requires a retention period")
+		}
+		input.ObjectLockMode = types.ObjectLockMode(dest.S3ObjectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(now.AddDate(0, 0, dest.S3ObjectLockDays).UTC())
+	}
+	if dest.S3LegalHold {
+		input.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	}
+	if dest.S3ChecksumAlgorithm != "" {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithm(dest.S3ChecksumAlgorithm)
+	}
+	return nil
+}
+
+// s3SSECustomerKeyMD5 checks the SSE-C key and returns its base64 MD5 digest.
+func s3SSECustomerKeyMD5(dest model.BackupDestination) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(dest.S3SSECustomerKey)
+	if err != nil || len(key) != 32 {
+		return "", fmt.Errorf("SSE-C key must be a base64 encoded 256-bit key")
+	}
+	sum := md5.Sum(key)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// s3LockChecker reports whether object lock still protects an object. Buckets
+// without object lock, and S3-compatible services that do not implement it,
+// answer the first probe with an error; every object then counts as unlocked
+// and no further probes are sent.
+type s3LockChecker struct {
+	client      *s3.Client
+	bucket      string
+	now         time.Time
+	unsupported bool
+}
+
+func (c *s3LockChecker) locked(ctx context.Context, key string) (bool, error) {
+	if c.unsupported {
+		return false, nil
+	}
+	retention, err := c.client.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	switch {
+	case err == nil:
+		if retention.Retention != nil && retention.Retention.RetainUntilDate != nil && retention.Retention.RetainUntilDate.After(c.now) {
+			return true, nil
+		}
+	case isS3LockUnsupported(err):
+		c.unsupported = true
+		return false, nil
+	case !isS3NoLockConfiguration(err):
+		return false, fmt.Errorf("failed to read object retention: %w", err)
+	}
+
+	hold, err := c.client.GetObjectLegalHold(ctx, &s3.GetObjectLegalHoldInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	switch {
+	case err == nil:
+		return hold.LegalHold != nil && hold.LegalHold.Status == types.ObjectLockLegalHoldStatusOn, nil
+	case isS3NoLockConfiguration(err):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to read object legal hold: %w", err)
+	}
+}
+
+// isS3LockUnsupported matches the errors for a bucket without object lock
+// ("Bucket is missing Object Lock Configuration") or a service without the
+// API.
+func isS3LockUnsupported(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "NotImplemented", "MethodNotAllowed", "ObjectLockConfigurationNotFoundError":
+		return true
+	case "InvalidRequest":
+		return strings.Contains(apiErr.ErrorMessage(), "Object Lock")
+	}
+	return false
+}
+
+// isS3NoLockConfiguration matches an object that has no retention or legal
+// hold of its own.
+func isS3NoLockConfiguration(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchObjectLockConfiguration"
+}
@@ -0,0 +1,696 @@
+package provider
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// fakeS3Server emulates the HeadBucket, PutObject, GetObject, multipart upload,
+// ListObjectsV2, DeleteObject(s) and object lock read calls of the S3 API, addressed either
+// path-style or virtual-hosted style.
+type fakeS3Server struct {
+	mu      sync.Mutex
+	objects map[string]*s3TestObject
+	headers map[string]http.Header // request headers of the last PUT per key
+	gets    map[string]http.Header // request headers of the last GET per key
+	noLock  bool                   // bucket has no object lock configuration
+	probes  int
+	deleted []string
+	hosts   []string
+
+	uploads map[string]*s3TestUpload // by upload ID
+	aborted []string
+	// partStarted and releaseParts, when set, hold every UploadPart request
+	// until the client gives up.
+	partStarted  chan struct{}
+	releaseParts chan struct{}
+}
+
+type s3TestUpload struct {
+	key       string
+	initiated time.Time
+	parts     map[int]string
+}
+
+type s3TestObject struct {
+	body        string
+	customerKey string // SSE-C key the object was written with
+	modified    time.Time
+	retainUntil time.Time
+	legalHold   bool
+}
+
+func newFakeS3Server(t *testing.T) (*fakeS3Server, model.BackupDestination) {
+	t.Helper()
+	fake := &fakeS3Server{
+		objects: make(map[string]*s3TestObject),
+		headers: make(map[string]http.Header),
+		gets:    make(map[string]http.Header),
+		uploads: make(map[string]*s3TestUpload),
+	}
+	server := httptest.NewServer(http.HandlerFunc(fake.serveHTTP))
+	t.Cleanup(server.Close)
+	return fake, model.BackupDestination{
+		Type:        "s3",
+		S3Endpoint:  server.URL,
+		S3Region:    "us-east-1",
+		S3Bucket:    "vault",
+		S3AccessKey: "AKIATEST",
+		S3SecretKey: "secret",
+		S3Path:      "/nightly",
+	}
+}
+
+func (f *fakeS3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	key := strings.TrimPrefix(r.URL.Path, "/vault/")
+	if strings.HasPrefix(r.Host, "vault.") {
+		key = strings.TrimPrefix(r.URL.Path, "/")
+	}
+	if r.Method == http.MethodPut && query.Has("partNumber") {
+		f.uploadPart(w, r, query)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts = append(f.hosts, r.Host)
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadID := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[uploadID] = &s3TestUpload{key: key, initiated: time.Now(), parts: make(map[int]string)}
+		f.headers[key] = r.Header.Clone()
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>vault</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, key, uploadID)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		upload := f.uploads[query.Get("uploadId")]
+		if upload == nil {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "unknown upload")
+			return
+		}
+		var body strings.Builder
+		for i := 1; i <= len(upload.parts); i++ {
+			body.WriteString(upload.parts[i])
+		}
+		f.objects[key] = &s3TestObject{body: body.String(), modified: time.Now()}
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>vault</Bucket><Key>%s</Key><ETag>"done"</ETag></CompleteMultipartUploadResult>`, key)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		f.aborted = append(f.aborted, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && query.Has("uploads"):
+		io.WriteString(w, `<ListMultipartUploadsResult><Bucket>vault</Bucket><IsTruncated>false</IsTruncated>`)
+		for id, upload := range f.uploads {
+			if strings.HasPrefix(upload.key, query.Get("prefix")) {
+				fmt.Fprintf(w, `<Upload><Key>%s</Key><UploadId>%s</UploadId><Initiated>%s</Initiated></Upload>`,
+					upload.key, id, upload.initiated.UTC().Format(time.RFC3339))
+			}
+		}
+		io.WriteString(w, `</ListMultipartUploadsResult>`)
+	case r.Method == http.MethodHead && (key == "/vault" || key == ""):
+		w.Header().Set("X-Amz-Bucket-Region", "us-east-1")
+	case r.Method == http.MethodDelete && key != "":
+		delete(f.objects, key)
+		f.deleted = append(f.deleted, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && key != "":
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = &s3TestObject{
+			body:        string(body),
+			customerKey: r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Key"),
+			modified:    time.Now(),
+		}
+		f.headers[key] = r.Header.Clone()
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		prefix := query.Get("prefix")
+		var keys []string
+		for name := range f.objects {
+			if strings.HasPrefix(name, prefix) {
+				keys = append(keys, name)
+			}
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(w, `<ListBucketResult><Name>vault</Name><Prefix>%s</Prefix><KeyCount>%d</KeyCount><IsTruncated>false</IsTruncated>`, prefix, len(keys))
+		for _, name := range keys {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><LastModified>%s</LastModified><Size>%d</Size></Contents>`,
+				name, f.objects[name].modified.UTC().Format(time.RFC3339), len(f.objects[name].body))
+		}
+		io.WriteString(w, `</ListBucketResult>`)
+	case r.Method == http.MethodGet && query.Has("retention"):
+		f.probes++
+		object := f.objects[key]
+		switch {
+		case f.noLock:
+			writeS3Error(w, http.StatusBadRequest, "InvalidRequest", "Bucket is missing Object Lock Configuration")
+		case object == nil || object.retainUntil.IsZero():
+			writeS3Error(w, http.StatusNotFound, "NoSuchObjectLockConfiguration", "The specified object does not have a ObjectLock configuration")
+		default:
+			fmt.Fprintf(w, `<Retention><Mode>COMPLIANCE</Mode><RetainUntilDate>%s</RetainUntilDate></Retention>`, object.retainUntil.UTC().Format(time.RFC3339))
+		}
+	case r.Method == http.MethodGet && query.Has("legal-hold"):
+		status := "OFF"
+		if object := f.objects[key]; object != nil && object.legalHold {
+			status = "ON"
+		}
+		fmt.Fprintf(w, `<LegalHold><Status>%s</Status></LegalHold>`, status)
+	case r.Method == http.MethodGet && key != "":
+		f.gets[key] = r.Header.Clone()
+		object := f.objects[key]
+		switch {
+		case object == nil:
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		case object.customerKey != r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Key"):
+			writeS3Error(w, http.StatusBadRequest, "InvalidRequest", "The object was stored using a form of Server Side Encryption.")
+		default:
+			io.WriteString(w, object.body)
+		}
+	case r.Method == http.MethodPost && query.Has("delete"):
+		var request struct {
+			Objects []struct {
+				Key string `xml:"Key"`
+			} `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		io.WriteString(w, `<DeleteResult>`)
+		for _, object := range request.Objects {
+			delete(f.objects, object.Key)
+			f.deleted = append(f.deleted, object.Key)
+			fmt.Fprintf(w, `<Deleted><Key>%s</Key></Deleted>`, object.Key)
+		}
+		io.WriteString(w, `</DeleteResult>`)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+func (f *fakeS3Server) uploadPart(w http.ResponseWriter, r *http.Request, query url.Values) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	f.mu.Lock()
+	started, release := f.partStarted, f.releaseParts
+	f.mu.Unlock()
+	if release != nil {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	upload := f.uploads[query.Get("uploadId")]
+	if upload == nil {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "unknown upload")
+		return
+	}
+	number, _ := strconv.Atoi(query.Get("partNumber"))
+	upload.parts[number] = string(body)
+	w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, number))
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, message)
+}
+
+func s3BackupContext(t *testing.T, dest model.BackupDestination, timestamp string) BackupContext {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"encrypted":true}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	return BackupContext{Context: context.Background(), SourceFile: source, Timestamp: timestamp, Destination: dest}
+}
+
+func TestS3ProviderAppliesUploadOptions(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	dest.S3StorageClass = "STANDARD_IA"
+	dest.S3Encryption = "sse-kms"
+	dest.S3KMSKeyID = "alias/vault-backup"
+	dest.S3ObjectLockMode = "COMPLIANCE"
+	dest.S3ObjectLockDays = 30
+	dest.S3LegalHold = true
+	dest.S3ChecksumAlgorithm = "SHA256"
+
+	location, err := NewS3Provider().Backup(s3BackupContext(t, dest, "20251204020000"))
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	key := "nightly/bitwarden_encrypted_export_20251204020000.json"
+	if location != "s3://vault/"+key {
+		t.Fatalf("location = %q", location)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	header := fake.headers[key]
+	for name, want := range map[string]string{
+		"X-Amz-Storage-Class":                         "STANDARD_IA",
+		"X-Amz-Server-Side-Encryption":                "aws:kms",
+		"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": "alias/vault-backup",
+		"X-Amz-Object-Lock-Mode":                      "COMPLIANCE",
+		"X-Amz-Object-Lock-Legal-Hold":                "ON",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	retainUntil, err := time.Parse(time.RFC3339, header.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	if err != nil || retainUntil.Before(time.Now().AddDate(0, 0, 29)) {
+		t.Errorf("retain-until = %q, want about 30 days ahead", header.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	}
+	if header.Get("X-Amz-Checksum-Sha256") == "" && header.Get("X-Amz-Trailer") != "x-amz-checksum-sha256" {
+		t.Errorf("upload did not carry a SHA256 checksum: %v", header)
+	}
+}
+
+func TestS3ProviderSendsSSECustomerKey(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	dest.S3Encryption = "sse-c"
+	dest.S3SSECustomerKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+	if _, err := NewS3Provider().Backup(s3BackupContext(t, dest, "20251204020000")); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	fake.mu.Lock()
+	header := fake.headers["nightly/bitwarden_encrypted_export_20251204020000.json"]
+	fake.mu.Unlock()
+	if header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "AES256" ||
+		header.Get("X-Amz-Server-Side-Encryption-Customer-Key") != dest.S3SSECustomerKey ||
+		header.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5") == "" {
+		t.Fatalf("SSE-C headers missing: %v", header)
+	}
+
+	dest.S3SSECustomerKey = "c2hvcnQ="
+	if _, err := NewS3Provider().Backup(s3BackupContext(t, dest, "20251205020000")); err == nil {
+		t.Fatalf("Backup() accepted a short SSE-C key")
+	}
+}
+
+func TestS3ProviderListsAndDownloadsSSECArtifacts(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	dest.S3Encryption = "sse-c"
+	dest.S3SSECustomerKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+	provider := NewS3Provider()
+	for _, timestamp := range []string{"20251204020000", "20251205020000"} {
+		if _, err := provider.Backup(s3BackupContext(t, dest, timestamp)); err != nil {
+			t.Fatalf("Backup(%s) error = %v", timestamp, err)
+		}
+	}
+	fake.mu.Lock()
+	fake.objects["nightly/notes.txt"] = &s3TestObject{body: "x", modified: time.Now()}
+	fake.objects["nightly/old/bitwarden_encrypted_export_20251201020000.json"] = &s3TestObject{body: "x", modified: time.Now()}
+	fake.mu.Unlock()
+
+	ctx := BackupContext{Context: context.Background(), Destination: dest}
+	artifacts, err := provider.ListArtifacts(ctx)
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+	var names []string
+	for _, artifact := range artifacts {
+		names = append(names, artifact.Name)
+		if artifact.Size != int64(len(`{"encrypted":true}`)) || artifact.ModTime.IsZero() {
+			t.Errorf("artifact %+v is missing its size or time", artifact)
+		}
+	}
+	if strings.Join(names, ",") != "bitwarden_encrypted_export_20251204020000.json,bitwarden_encrypted_export_20251205020000.json" {
+		t.Fatalf("artifacts = %v", names)
+	}
+
+	var body strings.Builder
+	if err := provider.Download(ctx, names[0], &body); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if body.String() != `{"encrypted":true}` {
+		t.Fatalf("downloaded %q", body.String())
+	}
+	fake.mu.Lock()
+	header := fake.gets["nightly/"+names[0]]
+	fake.mu.Unlock()
+	if header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "AES256" ||
+		header.Get("X-Amz-Server-Side-Encryption-Customer-Key") != dest.S3SSECustomerKey ||
+		header.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5") != "hRasmdxgYDKV3nvbahU1MA==" {
+		t.Fatalf("GetObject SSE-C headers = %v", header)
+	}
+
+	if err := provider.Download(ctx, "../secrets.json", io.Discard); err == nil {
+		t.Fatal("Download() accepted a name outside the backup pattern")
+	}
+	ctx.Destination.S3Encryption = ""
+	if err := provider.Download(ctx, names[0], io.Discard); err == nil {
+		t.Fatal("Download() without the SSE-C key should fail")
+	}
+}
+
+func TestS3ProviderUploadOutlivesDefaultBudget(t *testing.T) {
+	_, dest := newFakeS3Server(t)
+	ctx := s3BackupContext(t, dest, "20251204020000")
+	if budget := UploadTimeout(NewS3Provider(), ctx); budget != 30*time.Minute {
+		t.Fatalf("UploadTimeout() = %s, want 30m for multipart uploads", budget)
+	}
+}
+
+func TestS3ProviderCleanupSkipsLockedObjects(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	now := time.Now()
+	fake.objects = map[string]*s3TestObject{
+		"nightly/bitwarden_encrypted_export_20251204020000.json": {modified: now},
+		"nightly/bitwarden_encrypted_export_20251203020000.json": {modified: now.Add(-24 * time.Hour), retainUntil: now.Add(24 * time.Hour)},
+		"nightly/bitwarden_encrypted_export_20251202020000.json": {modified: now.Add(-48 * time.Hour), legalHold: true},
+		"nightly/bitwarden_encrypted_export_20251201020000.json": {modified: now.Add(-72 * time.Hour), retainUntil: now.Add(-time.Hour)},
+	}
+
+	deleted, err := NewS3Provider().Cleanup(BackupContext{Context: context.Background(), Destination: dest}, 1)
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.deleted) != 1 || fake.deleted[0] != "nightly/bitwarden_encrypted_export_20251201020000.json" {
+		t.Fatalf("deleted keys = %v, want only the expired one", fake.deleted)
+	}
+}
+
+func TestS3ProviderCleanupWithoutObjectLock(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	fake.noLock = true
+	now := time.Now()
+	fake.objects = map[string]*s3TestObject{
+		"nightly/bitwarden_encrypted_export_20251204020000.json": {modified: now},
+		"nightly/bitwarden_encrypted_export_20251203020000.json": {modified: now.Add(-24 * time.Hour)},
+		"nightly/bitwarden_encrypted_export_20251202020000.json": {modified: now.Add(-48 * time.Hour)},
+	}
+
+	deleted, err := NewS3Provider().Cleanup(BackupContext{Context: context.Background(), Destination: dest}, 1)
+	if err != nil || deleted != 2 {
+		t.Fatalf("Cleanup() = %d, %v; want 2 deleted", deleted, err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.probes != 1 {
+		t.Fatalf("retention probes = %d, want 1 for a bucket without object lock", fake.probes)
+	}
+}
+
+func writeLargeExport(t *testing.T, size int) (string, string) {
+	t.Helper()
+	content := strings.Repeat("0123456789abcdef", size/16)
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(content), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	return source, content
+}
+
+func TestS3ProviderUploadsLargeExportInParts(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	dest.S3ObjectLockMode = "GOVERNANCE"
+	dest.S3ObjectLockDays = 7
+	source, content := writeLargeExport(t, 11<<20)
+
+	provider := &S3Provider{partSize: 5 << 20}
+	ctx := BackupContext{Context: context.Background(), SourceFile: source, Timestamp: "20251204020000", Destination: dest}
+	if _, err := provider.Backup(ctx); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	key := "nightly/bitwarden_encrypted_export_20251204020000.json"
+	object := fake.objects[key]
+	if object == nil || object.body != content {
+		t.Fatalf("assembled object does not match the export")
+	}
+	if got := fake.headers[key].Get("X-Amz-Object-Lock-Mode"); got != "GOVERNANCE" {
+		t.Fatalf("CreateMultipartUpload object lock mode = %q", got)
+	}
+}
+
+func TestS3ProviderAbortsMultipartUploadOnCancel(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	fake.partStarted = make(chan struct{}, 1)
+	fake.releaseParts = make(chan struct{})
+	source, _ := writeLargeExport(t, 11<<20)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		provider := &S3Provider{partSize: 5 << 20}
+		_, err := provider.Backup(BackupContext{Context: runCtx, SourceFile: source, Timestamp: "20251204020000", Destination: dest})
+		done <- err
+	}()
+
+	select {
+	case <-fake.partStarted:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("upload never reached UploadPart")
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("Backup() succeeded after cancellation")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Backup() did not return after cancellation")
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.aborted) != 1 || len(fake.uploads) != 0 {
+		t.Fatalf("aborted = %v, open uploads = %d; want the upload aborted", fake.aborted, len(fake.uploads))
+	}
+}
+
+func TestS3ProviderAbortsStaleMultipartUploads(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	stale := time.Now().Add(-48 * time.Hour)
+	fake.uploads = map[string]*s3TestUpload{
+		"crashed":  {key: "nightly/bitwarden_encrypted_export_20251101020000.json", initiated: stale},
+		"running":  {key: "nightly/bitwarden_encrypted_export_20251203020000.json", initiated: time.Now().Add(-time.Hour)},
+		"foreign":  {key: "nightly/notes.json", initiated: stale},
+		"nested":   {key: "nightly/old/bitwarden_encrypted_export_20251101020000.json", initiated: stale},
+		"outbound": {key: "other/bitwarden_encrypted_export_20251101020000.json", initiated: stale},
+	}
+
+	if _, err := NewS3Provider().Backup(s3BackupContext(t, dest, "20251204020000")); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.aborted) != 1 || fake.aborted[0] != "crashed" {
+		t.Fatalf("aborted = %v, want only the stale upload of this task", fake.aborted)
+	}
+}
+
+func TestS3ProviderVirtualHostedStyle(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	endpoint, _ := url.Parse(dest.S3Endpoint)
+	dest.S3Endpoint = "http://localhost:" + endpoint.Port()
+	dest.S3AddressingStyle = "virtual"
+
+	// Resolve every bucket host name to the fake server.
+	provider := NewS3Provider()
+	provider.httpClient = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, endpoint.Host)
+		},
+	}}
+	if _, err := provider.Backup(s3BackupContext(t, dest, "20251204020000")); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.hosts[0] != "vault.localhost:"+endpoint.Port() {
+		t.Fatalf("request host = %q, want virtual-hosted bucket", fake.hosts[0])
+	}
+	if fake.objects["nightly/bitwarden_encrypted_export_20251204020000.json"] == nil {
+		t.Fatalf("object was not stored under the bucket host")
+	}
+}
+
+// newFakeSTSServer answers AssumeRole and AssumeRoleWithWebIdentity with
+// temporary credentials and records the form of each call.
+func newFakeSTSServer(t *testing.T) *[]url.Values {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		calls = append(calls, r.PostForm)
+		mu.Unlock()
+		action := r.PostForm.Get("Action")
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<%[1]sResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><%[1]sResult>
+<Credentials><AccessKeyId>ASIATEMP</AccessKeyId><SecretAccessKey>temp-secret</SecretAccessKey>
+<SessionToken>temp-session</SessionToken><Expiration>%[2]s</Expiration></Credentials>
+<AssumedRoleUser><Arn>arn:aws:sts::123456789012:assumed-role/backup/x</Arn><AssumedRoleId>AROA:x</AssumedRoleId></AssumedRoleUser>
+</%[1]sResult></%[1]sResponse>`, action, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	return &calls
+}
+
+func assertS3TemporaryCredentials(t *testing.T, fake *fakeS3Server) {
+	t.Helper()
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	header := fake.headers["nightly/bitwarden_encrypted_export_20251204020000.json"]
+	if !strings.Contains(header.Get("Authorization"), "Credential=ASIATEMP/") || header.Get("X-Amz-Security-Token") != "temp-session" {
+		t.Fatalf("upload was not signed with the role credentials: %v", header)
+	}
+}
+
+func TestS3ProviderAssumesRoleWithExternalID(t *testing.T) {
+	calls := newFakeSTSServer(t)
+	fake, dest := newFakeS3Server(t)
+	dest.S3CredentialMode = "assume_role"
+	dest.S3RoleARN = "arn:aws:iam::123456789012:role/backup"
+	dest.S3ExternalID = "tenant-42"
+
+	if _, err := NewS3Provider().Backup(s3BackupContext(t, dest, "20251204020000")); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("STS calls = %d, want 1", len(*calls))
+	}
+	form := (*calls)[0]
+	if form.Get("Action") != "AssumeRole" || form.Get("RoleArn") != dest.S3RoleARN ||
+		form.Get("ExternalId") != "tenant-42" || form.Get("RoleSessionName") != "bitwarden-backup" {
+		t.Fatalf("AssumeRole form = %v", form)
+	}
+	assertS3TemporaryCredentials(t, fake)
+}
+
+func TestS3ProviderUsesWebIdentityTokenFile(t *testing.T) {
+	calls := newFakeSTSServer(t)
+	fake, dest := newFakeS3Server(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("service-account-jwt"), 0600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+	dest.S3CredentialMode = "web_identity"
+	dest.S3RoleARN = "arn:aws:iam::123456789012:role/backup"
+	dest.S3AccessKey, dest.S3SecretKey = "", ""
+	// The EKS variable is the fallback when no token file is configured.
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+
+	if _, err := NewS3Provider().Backup(s3BackupContext(t, dest, "20251204020000")); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("STS calls = %d, want 1", len(*calls))
+	}
+	form := (*calls)[0]
+	if form.Get("Action") != "AssumeRoleWithWebIdentity" || form.Get("WebIdentityToken") != "service-account-jwt" {
+		t.Fatalf("AssumeRoleWithWebIdentity form = %v", form)
+	}
+	assertS3TemporaryCredentials(t, fake)
+}
+
+func TestS3ProviderDefaultCredentialChain(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	dest.S3CredentialMode = "default"
+	dest.S3AccessKey, dest.S3SecretKey = "", ""
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	if _, err := NewS3Provider().Backup(s3BackupContext(t, dest, "20251204020000")); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	header := fake.headers["nightly/bitwarden_encrypted_export_20251204020000.json"]
+	if !strings.Contains(header.Get("Authorization"), "Credential=AKIAENV/") {
+		t.Fatalf("upload was not signed with the environment credentials: %v", header.Get("Authorization"))
+	}
+}
+
+func TestS3ProviderCheckConnectionProbesPrefix(t *testing.T) {
+	fake, dest := newFakeS3Server(t)
+	dest.S3Encryption = "sse-s3"
+	dest.S3ObjectLockMode = "COMPLIANCE"
+	dest.S3ObjectLockDays = 30
+	dest.S3LegalHold = true
+
+	checks := NewS3Provider().CheckConnection(context.Background(), dest)
+	var names []string
+	for _, check := range checks {
+		if !check.OK {
+			t.Fatalf("check %s failed: %s", check.Name, check.Detail)
+		}
+		names = append(names, check.Name)
+	}
+	if got := strings.Join(names, ","); got != "credentials,bucket,list,write,delete" {
+		t.Fatalf("checks = %s", got)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.deleted) != 1 || !strings.HasPrefix(fake.deleted[0], "nightly/.bitwarden-backup-probe-") {
+		t.Fatalf("deleted = %v, want one probe under the prefix", fake.deleted)
+	}
+	if len(fake.objects) != 0 {
+		t.Fatalf("probe object left behind: %v", fake.objects)
+	}
+	header := fake.headers[fake.deleted[0]]
+	if header.Get("X-Amz-Server-Side-Encryption") != "AES256" {
+		t.Errorf("probe did not carry the encryption options: %v", header)
+	}
+	if header.Get("X-Amz-Object-Lock-Mode") != "" || header.Get("X-Amz-Object-Lock-Legal-Hold") != "" {
+		t.Errorf("probe was locked: %v", header)
+	}
+}
+
+func TestS3ProviderCheckConnectionStopsAtFailedStep(t *testing.T) {
+	_, dest := newFakeS3Server(t)
+	dest.S3Bucket = "missing"
+
+	checks := NewS3Provider().CheckConnection(context.Background(), dest)
+	last := checks[len(checks)-1]
+	if len(checks) != 2 || last.Name != "bucket" || last.OK {
+		t.Fatalf("checks = %+v, want failure at bucket", checks)
+	}
+	err := NewS3Provider().Test(context.Background(), dest)
+	if err == nil || !strings.Contains(err.Error(), "S3 connection test failed: bucket") {
+		t.Fatalf("Test() error = %v", err)
+	}
+}
@@ -42,6 +42,12 @@ func (p *ServerProvider) Backup(ctx BackupContext) (string, error) {
 		return "", fmt.Errorf("target server is disabled: %s", targetServer.Name)
 	}
 
+	mode := model.ServerSyncModeOrDefault(dest.ServerSyncMode)
+	if mode == model.ServerSyncPurge && !dest.ServerPurgeConfirmed {
+		ctx.AddLog("server", "服务器导入失败: 清空目标密码库需要确认")
+		return "", fmt.Errorf("purging the target vault is not confirmed")
+	}
+
 	bwCtx := ctx.Context
 	if bwCtx == nil {
 		bwCtx = context.Background()
@@ -50,7 +56,31 @@ func (p *ServerProvider) Backup(ctx BackupContext) (string, error) {
 	defer cancel()
 	ctx.AddLog("server", fmt.Sprintf("开始导入服务器: %s", targetServer.Name))
 	client := bitwarden.NewClientWithLogSink("server", ctx.Log)
-	if err := client.WithProcessLock(bwCtx, func(lockedCtx context.Context) (err error) {
+	if err := withTargetVault(bwCtx, client, targetServer, func(lockedCtx context.Context) error {
+		scope := newServerSyncScope(dest)
+		result, err := syncServerVault(lockedCtx, client, scope, mode, ctx.SourceFile)
+		if err != nil {
+			if result != (serverSyncResult{}) {
+				ctx.AddLog("server", "已完成的变更: "+result.String())
+			}
+			return err
+		}
+		ctx.AddLog("server", fmt.Sprintf("同步结果（%s，%s）: %s", mode, scope, result.String()))
+		return nil
+	}); err != nil {
+		ctx.AddLog("server", "服务器导入失败: "+err.Error())
+		return "", err
+	}
+
+	ctx.AddLog("server", fmt.Sprintf("服务器导入完成: %s", targetServer.Name))
+	// 返回目标服务器信息
+	return fmt.Sprintf("server://%s", targetServer.Name), nil
+}
+
+// withTargetVault runs fn with the CLI logged in to and unlocked for the
+// target server, holding the process lock for the whole cycle.
+func withTargetVault(ctx context.Context, client *bitwarden.Client, targetServer model.ServerConfig, fn func(context.Context) error) error {
+	return client.WithProcessLock(ctx, func(lockedCtx context.Context) (err error) {
 		// Clear any previous CLI session before switching the global server and
 		// always clean up again on every early-return path.
 		_ = client.Logout(lockedCtx)
@@ -74,16 +104,96 @@ func (p *ServerProvider) Backup(ctx BackupContext) (string, error) {
 		if err := client.Unlock(lockedCtx, targetServer.MasterPassword); err != nil {
 			return fmt.Errorf("failed to unlock target: %w", err)
 		}
-		if err := client.Import(lockedCtx, ctx.SourceFile, "json"); err != nil {
-			return fmt.Errorf("failed to import: %w", err)
+		return fn(lockedCtx)
+	})
+}
+
+// ListServerOrganizations returns the organizations of the server's account
+// with the collections it can access. Names are encrypted in the vault, so
+// this runs the full login and unlock cycle.
+func ListServerOrganizations(ctx context.Context, server model.ServerConfig) ([]model.OrganizationResponse, error) {
+	client := bitwarden.NewClient()
+	var organizations []model.OrganizationResponse
+	err := withTargetVault(contextOrBackground(ctx), client, server, func(lockedCtx context.Context) error {
+		orgs, err := client.ListOrganizations(lockedCtx)
+		if err != nil {
+			return fmt.Errorf("failed to list organizations: %w", err)
+		}
+		for _, org := range orgs {
+			collections, err := client.ListCollections(lockedCtx, org.ID())
+			if err != nil {
+				return fmt.Errorf("failed to list collections of %s: %w", org.String("name"), err)
+			}
+			response := model.OrganizationResponse{ID: org.ID(), Name: org.String("name"), Collections: []model.CollectionResponse{}}
+			for _, collection := range collections {
+				response.Collections = append(response.Collections, model.CollectionResponse{ID: collection.ID(), Name: collection.String("name")})
+			}
+			organizations = append(organizations, response)
 		}
 		return nil
-	}); err != nil {
-		ctx.AddLog("server", "服务器导入失败: "+err.Error())
-		return "", err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return organizations, nil
+}
 
-	ctx.AddLog("server", fmt.Sprintf("服务器导入完成: %s", targetServer.Name))
-	// 返回目标服务器信息
-	return fmt.Sprintf("server://%s", targetServer.Name), nil
+// Test verifies that the import can log in to and out of the target server.
+func (p *ServerProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	return connectionChecks(p.CheckConnection(ctx, dest)).err("server")
+}
+
+// CheckConnection runs the config, login and logout cycle of an import
+// against the target server without unlocking or writing to its vault.
+func (p *ServerProvider) CheckConnection(ctx context.Context, dest model.BackupDestination) []ConnectionCheck {
+	var checks connectionChecks
+	var targetServer model.ServerConfig
+	if !checks.run("server", func() (string, error) {
+		if dest.TargetServerID == nil {
+			return "", fmt.Errorf("target server id is nil")
+		}
+		if err := database.DB.First(&targetServer, *dest.TargetServerID).Error; err != nil {
+			return "", fmt.Errorf("failed to get target server: %w", err)
+		}
+		if !targetServer.Enabled {
+			return "", fmt.Errorf("target server is disabled: %s", targetServer.Name)
+		}
+		return targetServer.Name, nil
+	}) {
+		return checks
+	}
+
+	client := bitwarden.NewClient()
+	err := client.WithProcessLock(contextOrBackground(ctx), func(lockedCtx context.Context) error {
+		_ = client.Logout(lockedCtx)
+		loggedIn := checks.run("config", func() (string, error) {
+			if err := client.ConfigServer(lockedCtx, targetServer.ServerURL); err != nil {
+				return "", fmt.Errorf("failed to config target server: %w", err)
+			}
+			return targetServer.ServerURL, nil
+		}) && checks.run("login", func() (string, error) {
+			if err := client.Login(lockedCtx, targetServer.ClientID, targetServer.ClientSecret); err != nil {
+				return "", fmt.Errorf("failed to login to target: %w", err)
+			}
+			return "", nil
+		})
+
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(lockedCtx), 30*time.Second)
+		defer cancel()
+		if !loggedIn {
+			_ = client.Logout(cleanupCtx)
+			return nil
+		}
+		checks.run("logout", func() (string, error) {
+			if err := client.Logout(cleanupCtx); err != nil {
+				return "", fmt.Errorf("failed to logout from target: %w", err)
+			}
+			return "", nil
+		})
+		return nil
+	})
+	if err != nil {
+		checks = append(checks, ConnectionCheck{Name: "config", Detail: err.Error()})
+	}
+	return checks
 }
@@ -0,0 +1,446 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mingzaily/bitwarden-backup/internal/bitwarden"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// serverSyncResult counts the items a server destination run changed.
+type serverSyncResult struct {
+	Created   int
+	Updated   int
+	Deleted   int
+	Unchanged int
+}
+
+func (r serverSyncResult) String() string {
+	return fmt.Sprintf("新增 %d，更新 %d，删除 %d，未变化 %d", r.Created, r.Updated, r.Deleted, r.Unchanged)
+}
+
+// serverExport is the part of a plain JSON export the sync reads.
+type serverExport struct {
+	Folders []bitwarden.VaultObject `json:"folders"`
+	Items   []bitwarden.VaultObject `json:"items"`
+}
+
+func readServerExport(path string) (serverExport, error) {
+	var export serverExport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return export, fmt.Errorf("failed to read export: %w", err)
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return export, fmt.Errorf("failed to parse export: %w", err)
+	}
+	return export, nil
+}
+
+// serverVault is the slice of the CLI client the sync modes use.
+type serverVault interface {
+	ListItems(ctx context.Context) ([]bitwarden.VaultObject, error)
+	ListFolders(ctx context.Context) ([]bitwarden.VaultObject, error)
+	CreateObject(ctx context.Context, kind string, object bitwarden.VaultObject) (bitwarden.VaultObject, error)
+	EditObject(ctx context.Context, kind, id string, object bitwarden.VaultObject) (bitwarden.VaultObject, error)
+	DeleteObject(ctx context.Context, kind, id string, permanent bool) error
+	Import(ctx context.Context, inputPath, format string, organizationID ...string) error
+}
+
+// serverSyncScope is the part of the target vault a destination manages:
+// the personal vault, or one organization and optionally one collection.
+type serverSyncScope struct {
+	organizationID string
+	collectionID   string
+}
+
+func newServerSyncScope(dest model.BackupDestination) serverSyncScope {
+	return serverSyncScope{organizationID: dest.TargetOrganizationID, collectionID: dest.TargetCollectionID}
+}
+
+func (scope serverSyncScope) personal() bool {
+	return scope.organizationID == ""
+}
+
+func (scope serverSyncScope) String() string {
+	switch {
+	case scope.personal():
+		return "个人密码库"
+	case scope.collectionID != "":
+		return fmt.Sprintf("组织 %s / 集合 %s", scope.organizationID, scope.collectionID)
+	default:
+		return "组织 " + scope.organizationID
+	}
+}
+
+// contains reports whether a target item belongs to the scope. Folders are
+// personal, so they are only managed for the personal vault.
+func (scope serverSyncScope) contains(item bitwarden.VaultObject) bool {
+	if item.String("organizationId") != scope.organizationID {
+		return false
+	}
+	if scope.collectionID == "" {
+		return true
+	}
+	collections, _ := item["collectionIds"].([]any)
+	for _, id := range collections {
+		if id == scope.collectionID {
+			return true
+		}
+	}
+	return false
+}
+
+// syncServerVault brings the scope of the unlocked target vault in line with
+// the export according to the destination's sync mode. Items outside the
+// scope are never touched.
+func syncServerVault(ctx context.Context, vault serverVault, scope serverSyncScope, mode, sourceFile string) (serverSyncResult, error) {
+	var result serverSyncResult
+	export, err := readServerExport(sourceFile)
+	if err != nil {
+		return result, err
+	}
+
+	switch mode {
+	case model.ServerSyncAppend:
+	case model.ServerSyncPurge:
+		if result.Deleted, err = purgeServerVault(ctx, vault, scope); err != nil {
+			return result, err
+		}
+	case model.ServerSyncMirror:
+		items, err := scopeItems(ctx, vault, scope)
+		if err != nil {
+			return result, err
+		}
+		var folders []bitwarden.VaultObject
+		if scope.personal() {
+			if folders, err = vault.ListFolders(ctx); err != nil {
+				return result, fmt.Errorf("failed to list target folders: %w", err)
+			}
+		}
+		// An empty target takes one import instead of a create per item;
+		// bw import cannot place items in a collection.
+		if len(items) > 0 || len(userFolders(folders)) > 0 || scope.collectionID != "" {
+			return mirrorServerVault(ctx, vault, scope, export, items, folders)
+		}
+	default:
+		return result, fmt.Errorf("unsupported server sync mode: %s", mode)
+	}
+
+	if err := vault.Import(ctx, sourceFile, "json", scope.organizationID); err != nil {
+		return result, fmt.Errorf("failed to import: %w", err)
+	}
+	result.Created = len(export.Items)
+	return result, nil
+}
+
+// purgeServerVault permanently deletes every item of the scope, and the
+// folders of a personal vault.
+func purgeServerVault(ctx context.Context, vault serverVault, scope serverSyncScope) (int, error) {
+	items, err := scopeItems(ctx, vault, scope)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, item := range items {
+		if err := vault.DeleteObject(ctx, "item", item.ID(), true); err != nil {
+			return deleted, fmt.Errorf("failed to purge target item: %w", err)
+		}
+		deleted++
+	}
+	if !scope.personal() {
+		return deleted, nil
+	}
+	folders, err := vault.ListFolders(ctx)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to list target folders: %w", err)
+	}
+	for _, folder := range userFolders(folders) {
+		if err := vault.DeleteObject(ctx, "folder", folder.ID(), true); err != nil {
+			return deleted, fmt.Errorf("failed to purge target folder: %w", err)
+		}
+	}
+	return deleted, nil
+}
+
+// mirrorServerVault pairs source and target items by serverItemKey, edits
+// the pairs whose content differs, creates the unpaired source items and
+// moves the unpaired target items to the trash.
+func mirrorServerVault(ctx context.Context, vault serverVault, scope serverSyncScope, export serverExport, targetItems, targetFolders []bitwarden.VaultObject) (serverSyncResult, error) {
+	var result serverSyncResult
+	var sourceFolderNames folderNameIndex
+	if scope.personal() {
+		sourceFolderNames = folderNames(export.Folders)
+	}
+	targetFolderIDs := make(map[string]string)
+	for _, folder := range userFolders(targetFolders) {
+		if _, ok := targetFolderIDs[folder.String("name")]; !ok {
+			targetFolderIDs[folder.String("name")] = folder.ID()
+		}
+	}
+	for _, folder := range export.Folders {
+		if !scope.personal() {
+			break
+		}
+		name := folder.String("name")
+		if _, ok := targetFolderIDs[name]; ok || name == "" {
+			continue
+		}
+		created, err := vault.CreateObject(ctx, "folder", bitwarden.VaultObject{"name": name})
+		if err != nil {
+			return result, fmt.Errorf("failed to create target folder: %w", err)
+		}
+		targetFolderIDs[name] = created.ID()
+	}
+	targetFolderNames := folderNames(targetFolders)
+
+	plan := planServerMirror(export.Items, sourceFolderNames, targetItems, targetFolderNames)
+	for _, item := range plan.create {
+		if _, err := vault.CreateObject(ctx, "item", serverItemPayload(item, scope, sourceFolderNames, targetFolderIDs)); err != nil {
+			return result, fmt.Errorf("failed to create target item: %w", err)
+		}
+		result.Created++
+	}
+	for _, pair := range plan.update {
+		payload := serverItemPayload(pair.source, scope, sourceFolderNames, targetFolderIDs)
+		// The edit replaces the whole item; keep the history the target
+		// already has so the CLI appends to it instead of clearing it.
+		if history, ok := pair.target["passwordHistory"]; ok {
+			payload["passwordHistory"] = history
+		}
+		if _, err := vault.EditObject(ctx, "item", pair.target.ID(), payload); err != nil {
+			return result, fmt.Errorf("failed to update target item: %w", err)
+		}
+		result.Updated++
+	}
+	for _, item := range plan.delete {
+		if err := vault.DeleteObject(ctx, "item", item.ID(), false); err != nil {
+			return result, fmt.Errorf("failed to delete target item: %w", err)
+		}
+		result.Deleted++
+	}
+	result.Unchanged = plan.unchanged
+
+	// Folders the source no longer has are removed once their items moved.
+	for name, id := range targetFolderIDs {
+		if !sourceFolderNames.hasName(name) {
+			if err := vault.DeleteObject(ctx, "folder", id, false); err != nil {
+				return result, fmt.Errorf("failed to delete target folder: %w", err)
+			}
+		}
+	}
+	return result, nil
+}
+
+type serverMirrorPair struct {
+	source bitwarden.VaultObject
+	target bitwarden.VaultObject
+}
+
+type serverMirrorPlan struct {
+	create    []bitwarden.VaultObject
+	update    []serverMirrorPair
+	delete    []bitwarden.VaultObject
+	unchanged int
+}
+
+// planServerMirror pairs items in order within each key, so duplicates in
+// the target left by earlier imports are deleted down to the source count.
+func planServerMirror(sourceItems []bitwarden.VaultObject, sourceFolders folderNameIndex, targetItems []bitwarden.VaultObject, targetFolders folderNameIndex) serverMirrorPlan {
+	var plan serverMirrorPlan
+	byKey := make(map[string][]int)
+	for i, item := range targetItems {
+		key := serverItemKey(item)
+		byKey[key] = append(byKey[key], i)
+	}
+	paired := make([]bool, len(targetItems))
+	for _, item := range sourceItems {
+		key := serverItemKey(item)
+		candidates := byKey[key]
+		if len(candidates) == 0 {
+			plan.create = append(plan.create, item)
+			continue
+		}
+		byKey[key] = candidates[1:]
+		paired[candidates[0]] = true
+		target := targetItems[candidates[0]]
+		if serverItemContent(item, sourceFolders) == serverItemContent(target, targetFolders) {
+			plan.unchanged++
+		} else {
+			plan.update = append(plan.update, serverMirrorPair{source: item, target: target})
+		}
+	}
+	for i, item := range targetItems {
+		if !paired[i] {
+			plan.delete = append(plan.delete, item)
+		}
+	}
+	return plan
+}
+
+// serverItemKey is the stable identity of an item across vaults: ids differ
+// after an import, so it uses the type, the name and for logins the
+// username and first URI.
+func serverItemKey(item bitwarden.VaultObject) string {
+	key := fmt.Sprintf("%v\x00%s", item["type"], item.String("name"))
+	if login, ok := item["login"].(map[string]any); ok {
+		username, _ := login["username"].(string)
+		uri := ""
+		if uris, ok := login["uris"].([]any); ok && len(uris) > 0 {
+			if first, ok := uris[0].(map[string]any); ok {
+				uri, _ = first["uri"].(string)
+			}
+		}
+		key += "\x00" + username + "\x00" + uri
+	}
+	return key
+}
+
+// serverItemVolatileKeys are set by the server or refer to ids of one vault;
+// they do not count as content.
+var serverItemVolatileKeys = []string{
+	"id", "organizationId", "folderId", "collectionIds", "revisionDate", "creationDate", "deletedDate",
+	"passwordHistory", "attachments", "object", "edit", "viewPassword", "organizationUseTotp", "permissions", "key",
+}
+
+// serverItemContent renders the comparable content of an item. The folder
+// is compared by name; null, empty and server-managed values are dropped.
+func serverItemContent(item bitwarden.VaultObject, folders folderNameIndex) string {
+	content := make(map[string]any, len(item))
+	for key, value := range item {
+		content[key] = value
+	}
+	for _, key := range serverItemVolatileKeys {
+		delete(content, key)
+	}
+	if login, ok := content["login"].(map[string]any); ok {
+		trimmed := make(map[string]any, len(login))
+		for key, value := range login {
+			if key != "passwordRevisionDate" {
+				trimmed[key] = value
+			}
+		}
+		content["login"] = trimmed
+	}
+	content["folder"] = folders.name(item.String("folderId"))
+	data, _ := json.Marshal(pruneEmpty(content))
+	return string(data)
+}
+
+// pruneEmpty drops null, empty string, empty list and empty object values
+// recursively, so the CLI's null placeholders compare equal to omissions.
+func pruneEmpty(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		pruned := make(map[string]any, len(v))
+		for key, child := range v {
+			if child = pruneEmpty(child); child != nil {
+				pruned[key] = child
+			}
+		}
+		if len(pruned) == 0 {
+			return nil
+		}
+		return pruned
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+		pruned := make([]any, len(v))
+		for i, child := range v {
+			pruned[i] = pruneEmpty(child)
+		}
+		return pruned
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// serverItemPayload turns a source item into an item of the target scope,
+// with the folder of a personal item resolved by name.
+func serverItemPayload(item bitwarden.VaultObject, scope serverSyncScope, sourceFolders folderNameIndex, targetFolderIDs map[string]string) bitwarden.VaultObject {
+	payload := make(bitwarden.VaultObject, len(item))
+	for key, value := range item {
+		payload[key] = value
+	}
+	for _, key := range serverItemVolatileKeys {
+		delete(payload, key)
+	}
+	payload["organizationId"] = nil
+	if !scope.personal() {
+		payload["organizationId"] = scope.organizationID
+	}
+	if scope.collectionID != "" {
+		payload["collectionIds"] = []string{scope.collectionID}
+	}
+	payload["folderId"] = nil
+	if name := sourceFolders.name(item.String("folderId")); name != "" {
+		if id, ok := targetFolderIDs[name]; ok {
+			payload["folderId"] = id
+		}
+	}
+	return payload
+}
+
+// folderNameIndex maps folder ids to names.
+type folderNameIndex map[string]string
+
+func folderNames(folders []bitwarden.VaultObject) folderNameIndex {
+	index := make(folderNameIndex, len(folders))
+	for _, folder := range folders {
+		if id := folder.ID(); id != "" {
+			index[id] = folder.String("name")
+		}
+	}
+	return index
+}
+
+func (index folderNameIndex) name(id string) string {
+	if id == "" {
+		return ""
+	}
+	return index[id]
+}
+
+func (index folderNameIndex) hasName(name string) bool {
+	for _, existing := range index {
+		if existing == name {
+			return true
+		}
+	}
+	return false
+}
+
+// userFolders drops the implicit "No Folder" entry, which has no id.
+func userFolders(folders []bitwarden.VaultObject) []bitwarden.VaultObject {
+	var result []bitwarden.VaultObject
+	for _, folder := range folders {
+		if folder.ID() != "" {
+			result = append(result, folder)
+		}
+	}
+	return result
+}
+
+// scopeItems lists the target items inside the scope.
+func scopeItems(ctx context.Context, vault serverVault, scope serverSyncScope) ([]bitwarden.VaultObject, error) {
+	items, err := vault.ListItems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target items: %w", err)
+	}
+	var scoped []bitwarden.VaultObject
+	for _, item := range items {
+		if scope.contains(item) {
+			scoped = append(scoped, item)
+		}
+	}
+	return scoped, nil
+}
@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mingzaily/bitwarden-backup/internal/bitwarden"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// fakeServerVault records the CLI operations of a sync in memory.
+type fakeServerVault struct {
+	items   []bitwarden.VaultObject
+	folders []bitwarden.VaultObject
+	ops     []string
+	created []bitwarden.VaultObject
+	edited  map[string]bitwarden.VaultObject
+	nextID  int
+}
+
+func (v *fakeServerVault) ListItems(context.Context) ([]bitwarden.VaultObject, error) {
+	return v.items, nil
+}
+
+func (v *fakeServerVault) ListFolders(context.Context) ([]bitwarden.VaultObject, error) {
+	// The CLI always lists the implicit "No Folder" entry.
+	return append([]bitwarden.VaultObject{{"id": nil, "name": "No Folder"}}, v.folders...), nil
+}
+
+func (v *fakeServerVault) CreateObject(_ context.Context, kind string, object bitwarden.VaultObject) (bitwarden.VaultObject, error) {
+	v.nextID++
+	object["id"] = fmt.Sprintf("new-%d", v.nextID)
+	if kind == "folder" {
+		v.folders = append(v.folders, object)
+	} else {
+		v.created = append(v.created, object)
+	}
+	v.ops = append(v.ops, fmt.Sprintf("create %s %s folder=%v", kind, object.String("name"), object["folderId"]))
+	return object, nil
+}
+
+func (v *fakeServerVault) EditObject(_ context.Context, kind, id string, object bitwarden.VaultObject) (bitwarden.VaultObject, error) {
+	v.ops = append(v.ops, fmt.Sprintf("edit %s %s", kind, id))
+	if v.edited == nil {
+		v.edited = make(map[string]bitwarden.VaultObject)
+	}
+	v.edited[id] = object
+	return object, nil
+}
+
+func (v *fakeServerVault) DeleteObject(_ context.Context, kind, id string, permanent bool) error {
+	v.ops = append(v.ops, fmt.Sprintf("delete %s %s permanent=%t", kind, id, permanent))
+	return nil
+}
+
+func (v *fakeServerVault) Import(_ context.Context, path, format string, organizationID ...string) error {
+	v.ops = append(v.ops, strings.TrimSpace("import "+format+" "+strings.Join(organizationID, "")))
+	return nil
+}
+
+func writeServerExport(t *testing.T, export string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(path, []byte(export), 0600); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+	return path
+}
+
+func vaultObjects(t *testing.T, raw string) []bitwarden.VaultObject {
+	t.Helper()
+	var objects []bitwarden.VaultObject
+	if err := json.Unmarshal([]byte(raw), &objects); err != nil {
+		t.Fatalf("parse objects: %v", err)
+	}
+	return objects
+}
+
+const serverSyncExport = `{"encrypted":false,"folders":[{"id":"f-src","name":"Work"}],"items":[
+	{"id":"s1","type":1,"name":"Mail","folderId":"f-src","login":{"username":"me","password":"new","uris":[{"uri":"https://mail.example.com","match":null}]}},
+	{"id":"s2","type":1,"name":"Bank","folderId":null,"notes":null,"login":{"username":"me","password":"same","uris":[]}},
+	{"id":"s3","type":2,"name":"Recovery codes","notes":"1234","secureNote":{"type":0}}
+]}`
+
+func TestSyncServerVaultMirrorsByStableKey(t *testing.T) {
+	vault := &fakeServerVault{
+		folders: vaultObjects(t, `[{"id":"f-old","name":"Old"}]`),
+		items: vaultObjects(t, `[
+			{"id":"t1","type":1,"name":"Mail","folderId":null,"login":{"username":"me","password":"old","uris":[{"uri":"https://mail.example.com"}]},"passwordHistory":[{"password":"older","lastUsedDate":"2024-12-01T00:00:00Z"}],"revisionDate":"2025-01-01"},
+			{"id":"t2","type":1,"name":"Bank","notes":"","login":{"username":"me","password":"same","passwordRevisionDate":null},"revisionDate":"2025-01-02"},
+			{"id":"t3","type":1,"name":"Bank","login":{"username":"me","password":"same"}},
+			{"id":"t4","type":1,"name":"Gone","login":{"username":"me"}},
+			{"id":"t5","type":1,"name":"Shared","organizationId":"org-1","login":{"username":"me"}}
+		]`),
+	}
+
+	result, err := syncServerVault(context.Background(), vault, serverSyncScope{}, model.ServerSyncMirror, writeServerExport(t, serverSyncExport))
+	if err != nil {
+		t.Fatalf("syncServerVault() error = %v", err)
+	}
+	if result != (serverSyncResult{Created: 1, Updated: 1, Deleted: 2, Unchanged: 1}) {
+		t.Fatalf("result = %+v", result)
+	}
+	want := []string{
+		"create folder Work folder=<nil>",
+		"create item Recovery codes folder=<nil>",
+		"edit item t1",
+		"delete item t3 permanent=false",
+		"delete item t4 permanent=false",
+		"delete folder f-old permanent=false",
+	}
+	if !slices.Equal(vault.ops, want) {
+		t.Fatalf("ops = %q\nwant %q", vault.ops, want)
+	}
+	edited := vault.edited["t1"]
+	if edited["login"].(map[string]any)["password"] != "new" {
+		t.Fatalf("edited item = %v, want the source password", edited)
+	}
+	if history, _ := json.Marshal(edited["passwordHistory"]); string(history) != `[{"lastUsedDate":"2024-12-01T00:00:00Z","password":"older"}]` {
+		t.Fatalf("edited password history = %s, want the target's history", history)
+	}
+}
+
+func TestSyncServerVaultMirrorImportsIntoEmptyTarget(t *testing.T) {
+	vault := &fakeServerVault{items: vaultObjects(t, `[{"id":"t5","type":1,"name":"Shared","organizationId":"org-1"}]`)}
+	result, err := syncServerVault(context.Background(), vault, serverSyncScope{}, model.ServerSyncMirror, writeServerExport(t, serverSyncExport))
+	if err != nil || result != (serverSyncResult{Created: 3}) {
+		t.Fatalf("syncServerVault() = %+v, %v", result, err)
+	}
+	if !slices.Equal(vault.ops, []string{"import json"}) {
+		t.Fatalf("ops = %q", vault.ops)
+	}
+}
+
+func TestSyncServerVaultPurgesBeforeImport(t *testing.T) {
+	vault := &fakeServerVault{
+		folders: vaultObjects(t, `[{"id":"f-old","name":"Old"}]`),
+		items:   vaultObjects(t, `[{"id":"t1","type":2,"name":"Note"},{"id":"t5","type":1,"name":"Shared","organizationId":"org-1"}]`),
+	}
+	result, err := syncServerVault(context.Background(), vault, serverSyncScope{}, model.ServerSyncPurge, writeServerExport(t, serverSyncExport))
+	if err != nil || result != (serverSyncResult{Created: 3, Deleted: 1}) {
+		t.Fatalf("syncServerVault() = %+v, %v", result, err)
+	}
+	want := []string{"delete item t1 permanent=true", "delete folder f-old permanent=true", "import json"}
+	if !slices.Equal(vault.ops, want) {
+		t.Fatalf("ops = %q", vault.ops)
+	}
+}
+
+func TestSyncServerVaultMirrorsIntoCollection(t *testing.T) {
+	const org, collection = "7b5d2a3c-0000-4000-8000-000000000001", "7b5d2a3c-0000-4000-8000-000000000002"
+	vault := &fakeServerVault{
+		items: vaultObjects(t, `[
+			{"id":"t1","type":1,"name":"Mail","organizationId":"`+org+`","collectionIds":["`+collection+`"],"login":{"username":"me","password":"old","uris":[{"uri":"https://mail.example.com"}]}},
+			{"id":"t2","type":2,"name":"Elsewhere","organizationId":"`+org+`","collectionIds":["other"]},
+			{"id":"t3","type":2,"name":"Personal"}
+		]`),
+	}
+	scope := serverSyncScope{organizationID: org, collectionID: collection}
+	result, err := syncServerVault(context.Background(), vault, scope, model.ServerSyncMirror, writeServerExport(t, serverSyncExport))
+	if err != nil {
+		t.Fatalf("syncServerVault() error = %v", err)
+	}
+	if result != (serverSyncResult{Created: 2, Updated: 1}) {
+		t.Fatalf("result = %+v", result)
+	}
+	// Folders are personal and items outside the collection stay as they are.
+	want := []string{"create item Bank folder=<nil>", "create item Recovery codes folder=<nil>", "edit item t1"}
+	if !slices.Equal(vault.ops, want) {
+		t.Fatalf("ops = %q", vault.ops)
+	}
+	for _, item := range vault.created {
+		if item["organizationId"] != org || !slices.Equal(item["collectionIds"].([]string), []string{collection}) {
+			t.Fatalf("created item = %v, want it in the collection", item)
+		}
+	}
+}
+
+func TestSyncServerVaultImportsIntoOrganization(t *testing.T) {
+	vault := &fakeServerVault{}
+	scope := serverSyncScope{organizationID: "org-1"}
+	if _, err := syncServerVault(context.Background(), vault, scope, model.ServerSyncAppend, writeServerExport(t, serverSyncExport)); err != nil {
+		t.Fatalf("syncServerVault() error = %v", err)
+	}
+	if !slices.Equal(vault.ops, []string{"import json org-1"}) {
+		t.Fatalf("ops = %q", vault.ops)
+	}
+}
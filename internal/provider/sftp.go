@@ -0,0 +1,345 @@
+package provider
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const sftpDialTimeout = 30 * time.Second
+
+// SFTPProvider SFTP 存储提供者
+type SFTPProvider struct{}
+
+// NewSFTPProvider 创建 SFTP 存储提供者
+func NewSFTPProvider() *SFTPProvider {
+	return &SFTPProvider{}
+}
+
+// Type 返回提供者类型
+func (p *SFTPProvider) Type() string {
+	return "sftp"
+}
+
+// Backup 执行 SFTP 上传，返回最终存储路径
+func (p *SFTPProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	remoteFile := path.Join(sftpDirectory(dest), renderBackupFilename(ctx))
+	ctx.AddLog("sftp", fmt.Sprintf("开始 SFTP 上传: %s", remoteFile))
+	fail := func(err error) (string, error) {
+		ctx.AddLog("sftp", "SFTP 上传失败: "+err.Error())
+		return "", err
+	}
+
+	client, closeClient, err := dialSFTP(ctx.Context, dest)
+	if err != nil {
+		return fail(err)
+	}
+	defer closeClient()
+
+	if err := client.MkdirAll(sftpDirectory(dest)); err != nil {
+		return fail(fmt.Errorf("failed to create remote directory: %w", err))
+	}
+	if err := uploadSFTPFile(client, ctx.SourceFile, remoteFile); err != nil {
+		return fail(err)
+	}
+
+	ctx.AddLog("sftp", fmt.Sprintf("SFTP 上传完成: %s", remoteFile))
+	return sftpLocation(dest, remoteFile), nil
+}
+
+// sftpLocation builds the recorded artifact URL. An empty or relative
+// directory resolves against the login directory and has no leading slash.
+func sftpLocation(dest model.BackupDestination, remoteFile string) string {
+	return "sftp://" + net.JoinHostPort(dest.SFTPHost, strconv.Itoa(sftpPort(dest))) + "/" + strings.TrimPrefix(path.Clean(remoteFile), "/")
+}
+
+// Test verifies authentication, the pinned host key and that the target
+// directory can be listed, without uploading anything.
+func (p *SFTPProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	client, closeClient, err := dialSFTP(ctx, dest)
+	if err != nil {
+		return fmt.Errorf("SFTP connection test failed: %w", err)
+	}
+	defer closeClient()
+
+	if _, err := client.ReadDir(sftpDirectory(dest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("SFTP connection test failed: %w", err)
+	}
+	return nil
+}
+
+// ListArtifacts 列出远端目录中当前任务的备份文件
+func (p *SFTPProvider) ListArtifacts(ctx BackupContext) ([]Artifact, error) {
+	client, closeClient, err := dialSFTP(ctx.Context, ctx.Destination)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClient()
+
+	entries, err := client.ReadDir(sftpDirectory(ctx.Destination))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	var artifacts []Artifact
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || !matchesBackupFilename(entry.Name(), ctx) {
+			continue
+		}
+		artifacts = append(artifacts, Artifact{Name: entry.Name(), Size: entry.Size(), ModTime: entry.ModTime()})
+	}
+	return artifacts, nil
+}
+
+// Download 下载远端备份文件
+func (p *SFTPProvider) Download(ctx BackupContext, name string, w io.Writer) error {
+	if err := checkArtifactName(name, ctx); err != nil {
+		return err
+	}
+	client, closeClient, err := dialSFTP(ctx.Context, ctx.Destination)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	file, err := client.Open(path.Join(sftpDirectory(ctx.Destination), name))
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to download remote file: %w", err)
+	}
+	return nil
+}
+
+// Cleanup 清理超出保留数量的旧备份
+func (p *SFTPProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
+	if maxCount <= 0 {
+		return 0, nil
+	}
+
+	dest := ctx.Destination
+	client, closeClient, err := dialSFTP(ctx.Context, dest)
+	if err != nil {
+		return 0, err
+	}
+	defer closeClient()
+
+	entries, err := client.ReadDir(sftpDirectory(dest))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var backups []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !matchesBackupFilename(entry.Name(), ctx) {
+			continue
+		}
+		backups = append(backups, entry)
+	}
+	if len(backups) <= maxCount {
+		return 0, nil
+	}
+
+	// 按修改时间降序排序
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+
+	deleted := 0
+	var deleteErrors []error
+	for i := maxCount; i < len(backups); i++ {
+		remotePath := path.Join(sftpDirectory(dest), backups[i].Name())
+		if err := client.Remove(remotePath); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("%s: %w", backups[i].Name(), err))
+			continue
+		}
+		deleted++
+	}
+	if len(deleteErrors) > 0 {
+		return deleted, fmt.Errorf("failed to remove old SFTP backups: %w", errors.Join(deleteErrors...))
+	}
+	return deleted, nil
+}
+
+// dialSFTP opens an SSH connection and an SFTP session. The connection is
+// closed when ctx is cancelled so a stalled transfer cannot outlive the run.
+func dialSFTP(ctx context.Context, dest model.BackupDestination) (*sftp.Client, func(), error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	config, err := sftpClientConfig(dest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	address := net.JoinHostPort(dest.SFTPHost, strconv.Itoa(sftpPort(dest)))
+	dialer := net.Dialer{Timeout: sftpDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, fmt.Errorf("SSH handshake failed: %w", err)
+	}
+	sshClient := ssh.NewClient(sshConn, channels, requests)
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		stop()
+		sshClient.Close()
+		return nil, nil, fmt.Errorf("failed to start SFTP session: %w", err)
+	}
+
+	closeClient := func() {
+		stop()
+		client.Close()
+		sshClient.Close()
+	}
+	return client, closeClient, nil
+}
+
+func sftpClientConfig(dest model.BackupDestination) (*ssh.ClientConfig, error) {
+	hostKeyCallback, err := pinnedHostKeyCallback(dest.SFTPHostKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth []ssh.AuthMethod
+	if dest.SFTPPrivateKey != "" {
+		var signer ssh.Signer
+		if dest.SFTPKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(dest.SFTPPrivateKey), []byte(dest.SFTPKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(dest.SFTPPrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SFTP private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if dest.SFTPPassword != "" {
+		auth = append(auth, ssh.Password(dest.SFTPPassword))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("SFTP password or private key is required")
+	}
+
+	return &ssh.ClientConfig{
+		User:            dest.SFTPUsername,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sftpDialTimeout,
+	}, nil
+}
+
+// pinnedHostKeyCallback accepts only the configured server key. The pin is
+// either an authorized_keys/known_hosts style public key or the SHA256
+// fingerprint printed by ssh-keygen -lf.
+func pinnedHostKeyCallback(pin string) (ssh.HostKeyCallback, error) {
+	pin = strings.TrimSpace(pin)
+	if pin == "" {
+		return nil, fmt.Errorf("SFTP host key is required")
+	}
+	if strings.HasPrefix(pin, "SHA256:") {
+		return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if subtle.ConstantTimeCompare([]byte(ssh.FingerprintSHA256(key)), []byte(pin)) != 1 {
+				return fmt.Errorf("host key mismatch: got %s", ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}, nil
+	}
+
+	expected, err := ParseSFTPHostKey(pin)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.FixedHostKey(expected), nil
+}
+
+// ParseSFTPHostKey parses a pinned public key. A known_hosts line is accepted
+// by skipping its host pattern field.
+func ParseSFTPHostKey(pin string) (ssh.PublicKey, error) {
+	fields := strings.Fields(pin)
+	for i := range fields {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[i:], " ")))
+		if err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("SFTP host key must be a public key or a SHA256 fingerprint")
+}
+
+// uploadSFTPFile streams the export into the remotePartName file and renames
+// it once the close has been acknowledged by the server.
+func uploadSFTPFile(client *sftp.Client, sourceFile, remoteFile string) error {
+	source, err := os.Open(sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer source.Close()
+
+	tempFile := remotePartName(remoteFile)
+	target, err := client.OpenFile(tempFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		_ = client.Remove(tempFile)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := target.Close(); err != nil {
+		_ = client.Remove(tempFile)
+		return fmt.Errorf("failed to finish remote file: %w", err)
+	}
+	if err := client.Chmod(tempFile, 0600); err != nil {
+		_ = client.Remove(tempFile)
+		return fmt.Errorf("failed to secure remote file: %w", err)
+	}
+	if err := client.PosixRename(tempFile, remoteFile); err != nil {
+		// Servers without the posix-rename extension still support a plain
+		// rename; the timestamped name does not exist yet.
+		if renameErr := client.Rename(tempFile, remoteFile); renameErr != nil {
+			_ = client.Remove(tempFile)
+			return fmt.Errorf("failed to move remote file into place: %w", renameErr)
+		}
+	}
+	return nil
+}
+
+func sftpDirectory(dest model.BackupDestination) string {
+	directory := strings.TrimSpace(dest.SFTPPath)
+	if directory == "" {
+		return "."
+	}
+	return path.Clean(directory)
+}
+
+func sftpPort(dest model.BackupDestination) int {
+	if dest.SFTPPort == 0 {
+		return 22
+	}
+	return dest.SFTPPort
+}
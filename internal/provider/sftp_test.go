@@ -0,0 +1,274 @@
+package provider
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSFTPServer runs an in-process SSH server that only offers the sftp
+// subsystem and authenticates user "backup" with password "secret".
+func startSFTPServer(t *testing.T, options ...sftp.ServerOption) (host string, port int, hostKey ssh.PublicKey) {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("create host signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == "backup" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTPConn(conn, config, options...)
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	return address.IP.String(), address.Port, signer.PublicKey()
+}
+
+func serveSFTPConn(conn net.Conn, config *ssh.ServerConfig, options ...sftp.ServerOption) {
+	defer conn.Close()
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for request := range channelRequests {
+				isSFTP := request.Type == "subsystem" && len(request.Payload) > 4 && string(request.Payload[4:]) == "sftp"
+				_ = request.Reply(isSFTP, nil)
+				if !isSFTP {
+					continue
+				}
+				server, err := sftp.NewServer(channel, options...)
+				if err != nil {
+					channel.Close()
+					return
+				}
+				if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+					channel.Close()
+					return
+				}
+				server.Close()
+			}
+		}()
+	}
+}
+
+func testSFTPDestination(t *testing.T, options ...sftp.ServerOption) model.BackupDestination {
+	t.Helper()
+	host, port, hostKey := startSFTPServer(t, options...)
+	return model.BackupDestination{
+		Type:         "sftp",
+		SFTPHost:     host,
+		SFTPPort:     port,
+		SFTPUsername: "backup",
+		SFTPPassword: "secret",
+		SFTPHostKey:  string(ssh.MarshalAuthorizedKey(hostKey)),
+		SFTPPath:     filepath.ToSlash(filepath.Join(t.TempDir(), "nested", "backups")),
+	}
+}
+
+func TestSFTPProviderUploadsAndAppliesRetention(t *testing.T) {
+	dest := testSFTPDestination(t)
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	provider := NewSFTPProvider()
+	for _, timestamp := range []string{"20251204020000", "20251205020000", "20251206020000"} {
+		ctx := BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   timestamp,
+			Destination: dest,
+		}
+		remotePath, err := provider.Backup(ctx)
+		if err != nil {
+			t.Fatalf("backup %s: %v", timestamp, err)
+		}
+		if !strings.HasPrefix(remotePath, "sftp://") || !strings.HasSuffix(remotePath, timestamp+".json") {
+			t.Fatalf("unexpected remote path %q", remotePath)
+		}
+		// Keep modification times ordered for retention on coarse clocks.
+		written := filepath.Join(filepath.FromSlash(dest.SFTPPath), "bitwarden_encrypted_export_"+timestamp+".json")
+		modTime, _ := time.Parse("20060102150405", timestamp)
+		if err := os.Chtimes(written, modTime, modTime); err != nil {
+			t.Fatalf("set modification time: %v", err)
+		}
+	}
+
+	deleted, err := provider.Cleanup(BackupContext{Context: context.Background(), TaskName: "Nightly", Destination: dest}, 2)
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+	entries, err := os.ReadDir(filepath.FromSlash(dest.SFTPPath))
+	if err != nil {
+		t.Fatalf("read remote directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "bitwarden_encrypted_export_20251205020000.json,bitwarden_encrypted_export_20251206020000.json" {
+		t.Fatalf("unexpected remaining files: %v", names)
+	}
+}
+
+func TestSFTPProviderLocationForLoginRelativePaths(t *testing.T) {
+	home := t.TempDir()
+	dest := testSFTPDestination(t, sftp.WithServerWorkingDirectory(home))
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	host := net.JoinHostPort(dest.SFTPHost, strconv.Itoa(dest.SFTPPort))
+
+	for _, tc := range []struct{ path, dir string }{{"", ""}, {"exports/vault", "exports/vault/"}} {
+		dest.SFTPPath = tc.path
+		location, err := NewSFTPProvider().Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   "20251204020000",
+			Destination: dest,
+		})
+		if err != nil {
+			t.Fatalf("Backup(%q) error = %v", tc.path, err)
+		}
+		want := "sftp://" + host + "/" + tc.dir + "bitwarden_encrypted_export_20251204020000.json"
+		if location != want {
+			t.Errorf("Backup(%q) location = %q, want %q", tc.path, location, want)
+		}
+		if _, err := os.Stat(filepath.Join(home, filepath.FromSlash(tc.dir), "bitwarden_encrypted_export_20251204020000.json")); err != nil {
+			t.Errorf("Backup(%q) did not write below the login directory: %v", tc.path, err)
+		}
+	}
+}
+
+func TestSFTPProviderListsAndDownloadsArtifacts(t *testing.T) {
+	dest := testSFTPDestination(t)
+	provider := NewSFTPProvider()
+	ctx := BackupContext{Context: context.Background(), Destination: dest}
+
+	// A directory that was never written to has nothing to replicate.
+	if artifacts, err := provider.ListArtifacts(ctx); err != nil || len(artifacts) != 0 {
+		t.Fatalf("ListArtifacts() on a missing directory = %v, %v", artifacts, err)
+	}
+
+	directory := filepath.FromSlash(dest.SFTPPath)
+	if err := os.MkdirAll(filepath.Join(directory, "bitwarden_encrypted_export_20251201020000.json"), 0700); err != nil {
+		t.Fatalf("create directory: %v", err)
+	}
+	for name, body := range map[string]string{
+		"bitwarden_encrypted_export_20251204020000.json": `{"encrypted":true}`,
+		"notes.txt": "not a backup",
+	} {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(body), 0600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	artifacts, err := provider.ListArtifacts(ctx)
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "bitwarden_encrypted_export_20251204020000.json" ||
+		artifacts[0].Size != int64(len(`{"encrypted":true}`)) || artifacts[0].ModTime.IsZero() {
+		t.Fatalf("artifacts = %+v", artifacts)
+	}
+
+	var body strings.Builder
+	if err := provider.Download(ctx, artifacts[0].Name, &body); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if body.String() != `{"encrypted":true}` {
+		t.Fatalf("downloaded %q", body.String())
+	}
+	if err := provider.Download(ctx, "bitwarden_encrypted_export_20251205020000.json", io.Discard); err == nil {
+		t.Fatal("Download() of a missing file should fail")
+	}
+	if err := provider.Download(ctx, "notes.txt", io.Discard); err == nil {
+		t.Fatal("Download() accepted a name outside the backup pattern")
+	}
+}
+
+func TestSFTPProviderRejectsUnpinnedHostKey(t *testing.T) {
+	dest := testSFTPDestination(t)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	otherSigner, err := ssh.NewSignerFromKey(otherKey)
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	dest.SFTPHostKey = ssh.FingerprintSHA256(otherSigner.PublicKey())
+
+	if err := NewSFTPProvider().Test(context.Background(), dest); err == nil || !strings.Contains(err.Error(), "host key mismatch") {
+		t.Fatalf("Test() error = %v, want host key mismatch", err)
+	}
+}
+
+func TestSFTPProviderTestAcceptsFingerprintPin(t *testing.T) {
+	dest := testSFTPDestination(t)
+	key, err := ParseSFTPHostKey(dest.SFTPHostKey)
+	if err != nil {
+		t.Fatalf("parse host key: %v", err)
+	}
+	dest.SFTPHostKey = ssh.FingerprintSHA256(key)
+
+	if err := NewSFTPProvider().Test(context.Background(), dest); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+const smbDialTimeout = 30 * time.Second
+
+// smbFS is the part of an SMB share the provider uses. Tests substitute a
+// local directory because there is no in-process SMB server.
+type smbFS interface {
+	MkdirAll(dir string) error
+	WriteFile(name string, source io.Reader) error
+	Rename(oldpath, newpath string) error
+	ReadDir(dir string) ([]os.FileInfo, error)
+	Remove(name string) error
+}
+
+type smbDialFunc func(ctx context.Context, dest model.BackupDestination) (smbFS, func(), error)
+
+// SMBProvider SMB/CIFS 共享存储提供者
+type SMBProvider struct {
+	dial smbDialFunc
+}
+
+// NewSMBProvider 创建 SMB 存储提供者
+func NewSMBProvider() *SMBProvider {
+	return &SMBProvider{dial: dialSMB}
+}
+
+// Type 返回提供者类型
+func (p *SMBProvider) Type() string {
+	return "smb"
+}
+
+// Backup 执行 SMB 上传，返回最终存储路径
+func (p *SMBProvider) Backup(ctx BackupContext) (string, error) {
+	dest := ctx.Destination
+	directory := smbDirectory(dest)
+	remoteFile := path.Join(directory, renderBackupFilename(ctx))
+	ctx.AddLog("smb", fmt.Sprintf("开始 SMB 上传: %s", smbDisplayPath(dest, remoteFile)))
+	fail := func(err error) (string, error) {
+		ctx.AddLog("smb", "SMB 上传失败: "+err.Error())
+		return "", err
+	}
+
+	share, closeShare, err := p.dial(ctx.Context, dest)
+	if err != nil {
+		return fail(err)
+	}
+	defer closeShare()
+
+	if err := share.MkdirAll(directory); err != nil {
+		return fail(fmt.Errorf("failed to create remote directory: %w", err))
+	}
+	if err := uploadSMBFile(share, ctx.SourceFile, remoteFile); err != nil {
+		return fail(err)
+	}
+
+	ctx.AddLog("smb", fmt.Sprintf("SMB 上传完成: %s", smbDisplayPath(dest, remoteFile)))
+	return smbDisplayPath(dest, remoteFile), nil
+}
+
+// Test verifies authentication, that the share can be mounted and that the
+// target directory can be listed, without uploading anything.
+func (p *SMBProvider) Test(ctx context.Context, dest model.BackupDestination) error {
+	share, closeShare, err := p.dial(ctx, dest)
+	if err != nil {
+		return fmt.Errorf("SMB connection test failed: %w", err)
+	}
+	defer closeShare()
+
+	if _, err := share.ReadDir(smbDirectory(dest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("SMB connection test failed: %w", err)
+	}
+	return nil
+}
+
+// Cleanup 清理超出保留数量的旧备份
+func (p *SMBProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
+	if maxCount <= 0 {
+		return 0, nil
+	}
+
+	dest := ctx.Destination
+	share, closeShare, err := p.dial(ctx.Context, dest)
+	if err != nil {
+		return 0, err
+	}
+	defer closeShare()
+
+	entries, err := share.ReadDir(smbDirectory(dest))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var backups []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !matchesBackupFilename(entry.Name(), ctx) {
+			continue
+		}
+		backups = append(backups, entry)
+	}
+	if len(backups) <= maxCount {
+		return 0, nil
+	}
+
+	// 按修改时间降序排序
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+
+	deleted := 0
+	var deleteErrors []error
+	for i := maxCount; i < len(backups); i++ {
+		if err := share.Remove(path.Join(smbDirectory(dest), backups[i].Name())); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("%s: %w", backups[i].Name(), err))
+			continue
+		}
+		deleted++
+	}
+	if len(deleteErrors) > 0 {
+		return deleted, fmt.Errorf("failed to remove old SMB backups: %w", errors.Join(deleteErrors...))
+	}
+	return deleted, nil
+}
+
+// uploadSMBFile writes the export to the remotePartName file on the share
+// and renames it within the same share.
+func uploadSMBFile(share smbFS, sourceFile, remoteFile string) error {
+	source, err := os.Open(sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer source.Close()
+
+	tempFile := remotePartName(remoteFile)
+	if err := share.WriteFile(tempFile, source); err != nil {
+		_ = share.Remove(tempFile)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := share.Rename(tempFile, remoteFile); err != nil {
+		_ = share.Remove(tempFile)
+		return fmt.Errorf("failed to move remote file into place: %w", err)
+	}
+	return nil
+}
+
+// smbShare adapts *smb2.Share to smbFS.
+type smbShare struct {
+	share *smb2.Share
+}
+
+func (s smbShare) MkdirAll(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	return s.share.MkdirAll(dir, 0700)
+}
+
+func (s smbShare) WriteFile(name string, source io.Reader) error {
+	file, err := s.share.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, source); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s smbShare) Rename(oldpath, newpath string) error {
+	return s.share.Rename(oldpath, newpath)
+}
+
+func (s smbShare) ReadDir(dir string) ([]os.FileInfo, error) {
+	return s.share.ReadDir(dir)
+}
+
+func (s smbShare) Remove(name string) error {
+	return s.share.Remove(name)
+}
+
+// dialSMB negotiates an SMB2/3 session with NTLM and mounts the share. The
+// TCP connection is closed when ctx is cancelled so a stalled transfer cannot
+// outlive the run.
+func dialSMB(ctx context.Context, dest model.BackupDestination) (smbFS, func(), error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	address := net.JoinHostPort(dest.SMBHost, strconv.Itoa(smbPort(dest)))
+	dialer := net.Dialer{Timeout: smbDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	smbDialer := &smb2.Dialer{
+		Initiator: &smb2.NTLMInitiator{
+			User:     dest.SMBUsername,
+			Password: dest.SMBPassword,
+			Domain:   dest.SMBDomain,
+		},
+	}
+	session, err := smbDialer.DialContext(ctx, conn)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, fmt.Errorf("SMB authentication failed: %w", err)
+	}
+	session = session.WithContext(ctx)
+
+	share, err := session.Mount(dest.SMBShare)
+	if err != nil {
+		stop()
+		_ = session.Logoff()
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to mount share %s: %w", dest.SMBShare, err)
+	}
+
+	closeShare := func() {
+		stop()
+		_ = share.Umount()
+		_ = session.Logoff()
+		conn.Close()
+	}
+	return smbShare{share: share.WithContext(ctx)}, closeShare, nil
+}
+
+// smbDirectory returns the directory relative to the share root. SMB paths
+// inside a share must not start with a separator.
+func smbDirectory(dest model.BackupDestination) string {
+	return strings.Trim(path.Clean("/"+strings.ReplaceAll(strings.TrimSpace(dest.SMBPath), `\`, "/")), "/")
+}
+
+func smbDisplayPath(dest model.BackupDestination, remoteFile string) string {
+	return "smb://" + net.JoinHostPort(dest.SMBHost, strconv.Itoa(smbPort(dest))) + "/" + path.Join(dest.SMBShare, remoteFile)
+}
+
+func smbPort(dest model.BackupDestination) int {
+	if dest.SMBPort == 0 {
+		return 445
+	}
+	return dest.SMBPort
+}
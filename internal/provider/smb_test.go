@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// localSMBShare serves smbFS from a directory so the provider logic can be
+// exercised without an SMB server.
+type localSMBShare struct {
+	root string
+}
+
+func (s localSMBShare) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+func (s localSMBShare) MkdirAll(dir string) error {
+	return os.MkdirAll(s.path(dir), 0700)
+}
+
+func (s localSMBShare) WriteFile(name string, source io.Reader) error {
+	file, err := os.Create(s.path(name))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, source); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s localSMBShare) Rename(oldpath, newpath string) error {
+	return os.Rename(s.path(oldpath), s.path(newpath))
+}
+
+func (s localSMBShare) ReadDir(dir string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(s.path(dir))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (s localSMBShare) Remove(name string) error {
+	return os.Remove(s.path(name))
+}
+
+func TestSMBProviderUploadsAndAppliesRetention(t *testing.T) {
+	root := t.TempDir()
+	provider := &SMBProvider{dial: func(context.Context, model.BackupDestination) (smbFS, func(), error) {
+		return localSMBShare{root: root}, func() {}, nil
+	}}
+	dest := model.BackupDestination{Type: "smb", SMBHost: "fileserver", SMBShare: "backups", SMBPath: `\vault\nightly`}
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"items":[]}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	remoteDir := filepath.Join(root, "vault", "nightly")
+	for _, timestamp := range []string{"20251204020000", "20251205020000", "20251206020000"} {
+		remotePath, err := provider.Backup(BackupContext{
+			Context:     context.Background(),
+			SourceFile:  source,
+			TaskName:    "Nightly",
+			Timestamp:   timestamp,
+			Destination: dest,
+		})
+		if err != nil {
+			t.Fatalf("backup %s: %v", timestamp, err)
+		}
+		want := "smb://fileserver:445/backups/vault/nightly/bitwarden_encrypted_export_" + timestamp + ".json"
+		if remotePath != want {
+			t.Fatalf("remote path = %q, want %q", remotePath, want)
+		}
+		modTime, _ := time.Parse("20060102150405", timestamp)
+		if err := os.Chtimes(filepath.Join(remoteDir, "bitwarden_encrypted_export_"+timestamp+".json"), modTime, modTime); err != nil {
+			t.Fatalf("set modification time: %v", err)
+		}
+	}
+
+	deleted, err := provider.Cleanup(BackupContext{Context: context.Background(), TaskName: "Nightly", Destination: dest}, 2)
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+	entries, err := os.ReadDir(remoteDir)
+	if err != nil {
+		t.Fatalf("read remote directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "bitwarden_encrypted_export_20251205020000.json,bitwarden_encrypted_export_20251206020000.json" {
+		t.Fatalf("unexpected remaining files: %v", names)
+	}
+}
+
+func TestSMBDirectoryIsRelativeToShareRoot(t *testing.T) {
+	for raw, want := range map[string]string{
+		"":                 "",
+		"/":                "",
+		`\backups\vault\`:  "backups/vault",
+		"/backups/../keep": "keep",
+	} {
+		if got := smbDirectory(model.BackupDestination{SMBPath: raw}); got != want {
+			t.Errorf("smbDirectory(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestSMBProviderTestReportsNegotiationFailure(t *testing.T) {
+	// A listener that closes every connection stands in for a host that
+	// does not speak SMB.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	dest := model.BackupDestination{Type: "smb", SMBHost: address.IP.String(), SMBPort: address.Port, SMBShare: "backups", SMBUsername: "backup", SMBPassword: "secret"}
+	err = NewSMBProvider().Test(context.Background(), dest)
+	if err == nil || !strings.Contains(err.Error(), "SMB connection test failed") {
+		t.Fatalf("Test() error = %v, want connection failure", err)
+	}
+}
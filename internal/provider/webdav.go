@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path"
 	"sort"
+	"time"
 
 	"github.com/mingzaily/bitwarden-backup/internal/model"
 	"github.com/mingzaily/bitwarden-backup/internal/webdav"
@@ -28,9 +31,13 @@ func (p *WebDAVProvider) Type() string {
 func (p *WebDAVProvider) Backup(ctx BackupContext) (string, error) {
 	dest := ctx.Destination
 
-	client := webdav.NewClient(dest.WebDAVURL, dest.WebDAVUsername, dest.WebDAVPassword)
 	remoteFile := path.Join(dest.WebDAVPath, renderBackupFilename(ctx))
 	ctx.AddLog("webdav", fmt.Sprintf("开始 WebDAV 上传: %s", remoteFile))
+	client, err := newWebDAVClient(dest)
+	if err != nil {
+		ctx.AddLog("webdav", "WebDAV 上传失败: "+err.Error())
+		return "", err
+	}
 
 	if err := client.UploadFileContext(ctx.Context, ctx.SourceFile, remoteFile); err != nil {
 		ctx.AddLog("webdav", "WebDAV 上传失败: "+err.Error())
@@ -42,16 +49,70 @@ func (p *WebDAVProvider) Backup(ctx BackupContext) (string, error) {
 	return dest.WebDAVURL + remoteFile, nil
 }
 
+// UploadTimeout lets the configured request timeout extend the upload budget,
+// so large chunked or resumed uploads over slow links can finish.
+func (p *WebDAVProvider) UploadTimeout(ctx BackupContext) time.Duration {
+	client, err := newWebDAVClient(ctx.Destination)
+	if err != nil {
+		return 0
+	}
+	info, err := os.Stat(ctx.SourceFile)
+	if err != nil {
+		return 0
+	}
+	return client.UploadBudget(info.Size())
+}
+
 // Test verifies that the configured collection can be queried without
 // uploading a backup file or creating a test task.
 func (p *WebDAVProvider) Test(ctx context.Context, dest model.BackupDestination) error {
-	client := webdav.NewClient(dest.WebDAVURL, dest.WebDAVUsername, dest.WebDAVPassword)
+	client, err := newWebDAVClient(dest)
+	if err != nil {
+		return fmt.Errorf("WebDAV connection test failed: %w", err)
+	}
 	if err := client.Test(ctx, dest.WebDAVPath); err != nil {
 		return fmt.Errorf("WebDAV connection test failed: %w", err)
 	}
 	return nil
 }
 
+// ListArtifacts 列出 WebDAV 目录中当前任务的备份文件
+func (p *WebDAVProvider) ListArtifacts(ctx BackupContext) ([]Artifact, error) {
+	dest := ctx.Destination
+	client, err := newWebDAVClient(dest)
+	if err != nil {
+		return nil, err
+	}
+	files, err := client.ListFilesContext(ctx.Context, dest.WebDAVPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var artifacts []Artifact
+	for _, f := range files {
+		if f.IsDir || !matchesBackupFilename(f.Name, ctx) {
+			continue
+		}
+		artifacts = append(artifacts, Artifact{Name: f.Name, ModTime: f.ModTime})
+	}
+	return artifacts, nil
+}
+
+// Download 下载 WebDAV 备份文件
+func (p *WebDAVProvider) Download(ctx BackupContext, name string, w io.Writer) error {
+	if err := checkArtifactName(name, ctx); err != nil {
+		return err
+	}
+	client, err := newWebDAVClient(ctx.Destination)
+	if err != nil {
+		return err
+	}
+	if err := client.DownloadContext(ctx.Context, path.Join(ctx.Destination.WebDAVPath, name), w); err != nil {
+		return fmt.Errorf("failed to download from webdav: %w", err)
+	}
+	return nil
+}
+
 // Cleanup 清理超出保留数量的旧备份
 func (p *WebDAVProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 	if maxCount <= 0 {
@@ -59,7 +120,10 @@ func (p *WebDAVProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 	}
 
 	dest := ctx.Destination
-	client := webdav.NewClient(dest.WebDAVURL, dest.WebDAVUsername, dest.WebDAVPassword)
+	client, err := newWebDAVClient(dest)
+	if err != nil {
+		return 0, err
+	}
 	files, err := client.ListFilesContext(ctx.Context, dest.WebDAVPath)
 	if err != nil {
 		return 0, fmt.Errorf("failed to list files: %w", err)
@@ -103,3 +167,15 @@ func (p *WebDAVProvider) Cleanup(ctx BackupContext, maxCount int) (int, error) {
 
 	return deleted, nil
 }
+
+func newWebDAVClient(dest model.BackupDestination) (*webdav.Client, error) {
+	client := webdav.NewClient(dest.WebDAVURL, dest.WebDAVUsername, dest.WebDAVPassword)
+	client.SetTimeout(time.Duration(dest.WebDAVTimeout) * time.Second)
+	client.SetAuthMode(model.WebDAVAuthModeOrDefault(dest.WebDAVAuthMode), dest.WebDAVBearerToken)
+	tlsConfig, err := webdav.NewTLSConfig(dest.WebDAVClientCert, dest.WebDAVClientKey, dest.WebDAVCACert)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebDAV TLS settings: %w", err)
+	}
+	client.SetTLSConfig(tlsConfig)
+	return client, nil
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// startWebDAVServer serves a single collection at /dav/backups/ holding files
+// for PROPFIND and GET, behind basic auth for user "backup".
+func startWebDAVServer(t *testing.T, files map[string]string) model.BackupDestination {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "backup" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "PROPFIND" && strings.TrimSuffix(r.URL.Path, "/") == "/dav/backups":
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, `<?xml version="1.0"?><D:multistatus xmlns:D="DAV:">`)
+			io.WriteString(w, `<D:response><D:href>/dav/backups/</D:href><D:propstat><D:prop><D:resourcetype><D:collection/></D:resourcetype></D:prop></D:propstat></D:response>`)
+			io.WriteString(w, `<D:response><D:href>/dav/backups/archive/</D:href><D:propstat><D:prop><D:resourcetype><D:collection/></D:resourcetype></D:prop></D:propstat></D:response>`)
+			for name := range files {
+				fmt.Fprintf(w, `<D:response><D:href>/dav/backups/%s</D:href><D:propstat><D:prop><D:getlastmodified>Thu, 04 Dec 2025 02:00:00 GMT</D:getlastmodified><D:resourcetype/></D:prop></D:propstat></D:response>`, name)
+			}
+			io.WriteString(w, `</D:multistatus>`)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/dav/backups/"):
+			body, ok := files[strings.TrimPrefix(r.URL.Path, "/dav/backups/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, body)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return model.BackupDestination{
+		Type:           "webdav",
+		WebDAVURL:      server.URL + "/dav/",
+		WebDAVUsername: "backup",
+		WebDAVPassword: "secret",
+		WebDAVPath:     "/backups",
+	}
+}
+
+func TestWebDAVProviderListsAndDownloadsArtifacts(t *testing.T) {
+	dest := startWebDAVServer(t, map[string]string{
+		"bitwarden_encrypted_export_20251204020000.json": `{"encrypted":true}`,
+		"notes.txt": "not a backup",
+	})
+	provider := NewWebDAVProvider()
+	ctx := BackupContext{Context: context.Background(), Destination: dest}
+
+	artifacts, err := provider.ListArtifacts(ctx)
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "bitwarden_encrypted_export_20251204020000.json" || artifacts[0].ModTime.IsZero() {
+		t.Fatalf("artifacts = %+v", artifacts)
+	}
+
+	var body strings.Builder
+	if err := provider.Download(ctx, artifacts[0].Name, &body); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if body.String() != `{"encrypted":true}` {
+		t.Fatalf("downloaded %q", body.String())
+	}
+	if err := provider.Download(ctx, "bitwarden_encrypted_export_20251205020000.json", io.Discard); err == nil {
+		t.Fatal("Download() of a missing file should fail")
+	}
+	if err := provider.Download(ctx, "notes.txt", io.Discard); err == nil {
+		t.Fatal("Download() accepted a name outside the backup pattern")
+	}
+}
+
+func TestWebDAVProviderTimeoutExtendsUploadBudget(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(source, []byte(`{"encrypted":true}`), 0600); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	dest := model.BackupDestination{Type: "webdav", WebDAVURL: "https://dav.example.com/", WebDAVTimeout: 900}
+	ctx := BackupContext{Context: context.Background(), SourceFile: source, Destination: dest}
+
+	// One PUT may use its full 15 minutes on every attempt.
+	if budget := UploadTimeout(NewWebDAVProvider(), ctx); budget < 45*time.Minute {
+		t.Fatalf("UploadTimeout() = %s, want room for every attempt of a 15 minute request", budget)
+	}
+	if budget := UploadTimeout(NewLocalProvider(), ctx); budget != DefaultUploadTimeout {
+		t.Fatalf("local UploadTimeout() = %s, want the default", budget)
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"gorm.io/gorm"
+)
+
+// BlackoutRepository 管理全局禁止窗口。任务级窗口随任务一起保存。
+type BlackoutRepository struct {
+	db *gorm.DB
+}
+
+func NewBlackoutRepository(db *gorm.DB) *BlackoutRepository {
+	return &BlackoutRepository{db: db}
+}
+
+// FindGlobal 返回对所有任务生效的禁止窗口
+func (r *BlackoutRepository) FindGlobal() ([]model.BlackoutWindow, error) {
+	var windows []model.BlackoutWindow
+	err := r.db.Where("task_id IS NULL").Order("id ASC").Find(&windows).Error
+	return windows, err
+}
+
+func (r *BlackoutRepository) FindGlobalByID(id uint) (*model.BlackoutWindow, error) {
+	var window model.BlackoutWindow
+	err := r.db.Where("task_id IS NULL").First(&window, id).Error
+	return &window, err
+}
+
+func (r *BlackoutRepository) Create(window *model.BlackoutWindow) error {
+	window.TaskID = nil
+	return r.db.Create(window).Error
+}
+
+func (r *BlackoutRepository) Update(window *model.BlackoutWindow) error {
+	result := r.db.Model(&model.BlackoutWindow{}).Where("id = ? AND task_id IS NULL", window.ID).Updates(map[string]any{
+		"name":       window.Name,
+		"weekdays":   window.Weekdays,
+		"date":       window.Date,
+		"start_time": window.StartTime,
+		"end_time":   window.EndTime,
+		"action":     window.Action,
+		"enabled":    window.Enabled,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *BlackoutRepository) Delete(id uint) error {
+	result := r.db.Where("task_id IS NULL").Delete(&model.BlackoutWindow{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
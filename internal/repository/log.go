@@ -19,6 +19,12 @@ func (r *LogRepository) FindAll() ([]model.BackupLog, error) {
 	return logs, err
 }
 
+func (r *LogRepository) FindByID(id uint) (*model.BackupLog, error) {
+	var log model.BackupLog
+	err := r.db.First(&log, id).Error
+	return &log, err
+}
+
 func (r *LogRepository) FindByTaskID(taskID uint) ([]model.BackupLog, error) {
 	var logs []model.BackupLog
 	err := r.db.Where("task_id = ?", taskID).Order("created_at DESC").Find(&logs).Error
@@ -61,6 +61,9 @@ func (r *OverviewRepository) GetSummary() (model.OverviewResponse, error) {
 	if err := r.loadRecentTasks(&response.RecentTasks); err != nil {
 		return response, err
 	}
+	if err := r.loadLastRuns(response.RecentTasks); err != nil {
+		return response, err
+	}
 	if err := r.loadRecentLogs(&response.RecentLogs); err != nil {
 		return response, err
 	}
@@ -98,6 +101,35 @@ func (r *OverviewRepository) loadRecentTasks(tasks *[]model.OverviewTaskSummary)
 		Scan(tasks).Error
 }
 
+// loadLastRuns 填充每个任务最近一次运行的时间和状态
+func (r *OverviewRepository) loadLastRuns(tasks []model.OverviewTaskSummary) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	taskIDs := make([]uint, len(tasks))
+	for i := range tasks {
+		taskIDs[i] = tasks[i].ID
+	}
+
+	var lastRuns []model.BackupLog
+	latest := r.db.Model(&model.BackupLog{}).Select("MAX(id)").Where("task_id IN ?", taskIDs).Group("task_id")
+	if err := r.db.Select("task_id, status, start_time").Where("id IN (?)", latest).Find(&lastRuns).Error; err != nil {
+		return err
+	}
+	byTask := make(map[uint]model.BackupLog, len(lastRuns))
+	for _, run := range lastRuns {
+		byTask[run.TaskID] = run
+	}
+	for i := range tasks {
+		if run, ok := byTask[tasks[i].ID]; ok {
+			startTime := run.StartTime
+			tasks[i].LastRunAt = &startTime
+			tasks[i].LastRunStatus = run.Status
+		}
+	}
+	return nil
+}
+
 func (r *OverviewRepository) loadRecentLogs(logs *[]model.OverviewLogSummary) error {
 	return r.db.Table("backup_logs AS logs").
 		Select("logs.id, logs.task_id, COALESCE(tasks.name, '') AS task_name, logs.status, logs.message, logs.backup_file, logs.created_at").
@@ -48,7 +48,8 @@ func TestOverviewRepositoryGetSummary(t *testing.T) {
 	if err := db.Create(&model.BackupLog{TaskID: task.ID, Status: "success", Message: "Backup completed successfully", CreatedAt: time.Now()}).Error; err != nil {
 		t.Fatalf("create log: %v", err)
 	}
-	if err := db.Create(&model.BackupLog{TaskID: task.ID, Status: "failed", Message: "Backup failed", CreatedAt: time.Now()}).Error; err != nil {
+	lastStart := time.Date(2025, 12, 4, 2, 0, 0, 0, time.UTC)
+	if err := db.Create(&model.BackupLog{TaskID: task.ID, Status: "failed", Message: "Backup failed", StartTime: lastStart, CreatedAt: time.Now()}).Error; err != nil {
 		t.Fatalf("create failed log: %v", err)
 	}
 
@@ -71,6 +72,9 @@ func TestOverviewRepositoryGetSummary(t *testing.T) {
 	if len(overview.RecentTasks) != 1 || overview.RecentTasks[0].SourceServerName != "Production" || overview.RecentTasks[0].DestinationCount != 1 {
 		t.Fatalf("unexpected recent tasks: %+v", overview.RecentTasks)
 	}
+	if lastRun := overview.RecentTasks[0]; lastRun.LastRunAt == nil || !lastRun.LastRunAt.Equal(lastStart) || lastRun.LastRunStatus != "failed" {
+		t.Fatalf("unexpected last run: %+v", lastRun)
+	}
 	if len(overview.RecentLogs) != 2 || overview.RecentLogs[0].TaskName != "Nightly" {
 		t.Fatalf("unexpected recent logs: %+v", overview.RecentLogs)
 	}
@@ -15,19 +15,19 @@ func NewTaskRepository(db *gorm.DB) *TaskRepository {
 
 func (r *TaskRepository) FindAll() ([]model.BackupTask, error) {
 	var tasks []model.BackupTask
-	err := r.db.Preload("SourceServer").Preload("Destinations").Find(&tasks).Error
+	err := r.db.Preload("SourceServer").Preload("SourceDestination").Preload("Destinations").Preload("BlackoutWindows").Find(&tasks).Error
 	return tasks, err
 }
 
 func (r *TaskRepository) FindByID(id uint) (*model.BackupTask, error) {
 	var task model.BackupTask
-	err := r.db.Preload("SourceServer").Preload("Destinations").First(&task, id).Error
+	err := r.db.Preload("SourceServer").Preload("SourceDestination").Preload("Destinations").Preload("BlackoutWindows").First(&task, id).Error
 	return &task, err
 }
 
 func (r *TaskRepository) FindEnabled() ([]model.BackupTask, error) {
 	var tasks []model.BackupTask
-	err := r.db.Preload("SourceServer").Preload("Destinations").
+	err := r.db.Preload("SourceServer").Preload("SourceDestination").Preload("Destinations").Preload("BlackoutWindows").
 		Where("enabled = ?", true).Find(&tasks).Error
 	return tasks, err
 }
@@ -78,11 +78,18 @@ func (r *TaskRepository) UpdateWithDestinations(task *model.BackupTask, destinat
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		// 只更新指定字段，保留 created_at
 		result := tx.Model(&model.BackupTask{}).Where("id = ?", task.ID).Updates(map[string]any{
-			"name":              task.Name,
-			"source_server_id":  task.SourceServerID,
-			"cron_expression":   task.CronExpression,
-			"filename_template": model.NormalizeFilenameTemplate(task.FilenameTemplate),
-			"enabled":           task.Enabled,
+			"name":                  task.Name,
+			"type":                  model.TaskTypeOrDefault(task.Type),
+			"source_server_id":      task.SourceServerID,
+			"source_destination_id": task.SourceDestinationID,
+			"cron_expression":       task.CronExpression,
+			"filename_template":     model.NormalizeFilenameTemplate(task.FilenameTemplate),
+			"timezone":              task.Timezone,
+			"jitter_minutes":        task.JitterMinutes,
+			"stagger_minutes":       task.StaggerMinutes,
+			"trigger_task_id":       task.TriggerTaskID,
+			"trigger_on":            task.TriggerOn,
+			"enabled":               task.Enabled,
 		})
 		if result.Error != nil {
 			return result.Error
@@ -107,19 +114,42 @@ func (r *TaskRepository) UpdateWithDestinations(task *model.BackupTask, destinat
 			}
 		}
 
+		// 任务级禁止窗口整体替换
+		if err := tx.Where("task_id = ?", task.ID).Delete(&model.BlackoutWindow{}).Error; err != nil {
+			return err
+		}
+		for i := range task.BlackoutWindows {
+			window := task.BlackoutWindows[i]
+			window.ID = 0
+			window.TaskID = &task.ID
+			if err := tx.Create(&window).Error; err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
 
 func (r *TaskRepository) Delete(id uint) error {
-	result := r.db.Delete(&model.BackupTask{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return nil
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", id).Delete(&model.BlackoutWindow{}).Error; err != nil {
+			return err
+		}
+		// 下游任务失去上游后不再被依赖触发
+		if err := tx.Model(&model.BackupTask{}).Where("trigger_task_id = ?", id).
+			Updates(map[string]any{"trigger_task_id": nil, "trigger_on": ""}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&model.BackupTask{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
 }
 
 // FindPaginated 分页查询任务
@@ -131,7 +161,7 @@ func (r *TaskRepository) FindPaginated(params model.PaginationParams) ([]model.B
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("SourceServer").Preload("Destinations").
+	err := r.db.Preload("SourceServer").Preload("SourceDestination").Preload("Destinations").Preload("BlackoutWindows").
 		Order("created_at DESC").
 		Offset(params.GetOffset()).
 		Limit(params.GetLimit()).
@@ -1,9 +1,14 @@
 package scheduler
 
 import (
+	"context"
+
 	"github.com/mingzaily/bitwarden-backup/internal/model"
 )
 
-func (s *Scheduler) performBackup(task model.BackupTask, backupLog *model.BackupLog) error {
-	return s.performBackupToDestinations(task, backupLog)
+func (s *Scheduler) performBackup(ctx context.Context, task model.BackupTask, backupLog *model.BackupLog) error {
+	if task.IsReplication() {
+		return s.performReplication(ctx, task, backupLog)
+	}
+	return s.performBackupToDestinations(ctx, task, backupLog)
 }
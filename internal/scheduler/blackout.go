@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/database"
+	"github.com/mingzaily/bitwarden-backup/internal/logger"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// cronSpec builds the robfig/cron spec for a task. Without a timezone the
+// cron instance keeps using the process local time.
+func cronSpec(task model.BackupTask) string {
+	spec := normalizeCron(task.CronExpression)
+	if task.Timezone != "" {
+		spec = "CRON_TZ=" + task.Timezone + " " + spec
+	}
+	return spec
+}
+
+// findBlackout returns the window that blocks a run at now. Skip windows take
+// precedence over defer windows; among defer windows the latest end wins so
+// overlapping windows only defer once.
+func findBlackout(windows []model.BlackoutWindow, now time.Time) (model.BlackoutWindow, time.Time, bool) {
+	var match model.BlackoutWindow
+	var matchEnd time.Time
+	found := false
+	for _, window := range windows {
+		inside, end := window.Contains(now)
+		if !inside {
+			continue
+		}
+		if window.Action == model.BlackoutActionSkip {
+			return window, end, true
+		}
+		if !found || end.After(matchEnd) {
+			match, matchEnd, found = window, end, true
+		}
+	}
+	return match, matchEnd, found
+}
+
+// enqueueScheduledTask is the cron entry point. Scheduled runs that fall
+// inside a blackout window are skipped or deferred until the window ends;
+// manual executions go straight to enqueueTask and are never blocked.
+func (s *Scheduler) enqueueScheduledTask(taskID uint) {
+	if s.stopped.Load() {
+		return
+	}
+	if database.DB == nil {
+		s.enqueueTask(taskID)
+		return
+	}
+
+	var task model.BackupTask
+	if err := database.DB.First(&task, taskID).Error; err != nil {
+		logger.Module(logger.ModuleScheduler).Error("Failed to load task for blackout check", "id", taskID, "error", err)
+		s.enqueueTask(taskID)
+		return
+	}
+	var windows []model.BlackoutWindow
+	if err := database.DB.Where("enabled = ? AND (task_id IS NULL OR task_id = ?)", true, taskID).Find(&windows).Error; err != nil {
+		// Failing open keeps backups running when the blackout table is
+		// unreadable; a missed backup is worse than one in a quiet period.
+		logger.Module(logger.ModuleScheduler).Error("Failed to load blackout windows", "id", taskID, "error", err)
+		s.enqueueTask(taskID)
+		return
+	}
+
+	now := time.Now().In(task.Location())
+	window, end, blocked := findBlackout(windows, now)
+	if !blocked {
+		s.enqueueTask(taskID)
+		return
+	}
+
+	if window.Action == model.BlackoutActionSkip {
+		logger.Module(logger.ModuleScheduler).Info("Scheduled run skipped by blackout window", "id", taskID, "name", task.Name, "window", window.Describe())
+		s.recordSkippedRun(task, fmt.Sprintf("Scheduled run skipped: inside blackout window %s", window.Describe()))
+		return
+	}
+
+	delay := time.Until(end)
+	logger.Module(logger.ModuleScheduler).Info("Scheduled run deferred by blackout window", "id", taskID, "name", task.Name, "window", window.Describe(), "until", end.Format(time.RFC3339))
+	s.deferRun(taskID, delay)
+}
+
+// deferRun re-evaluates a scheduled run after delay, either because a
+// blackout window is active or to spread the fire. A task keeps at most one
+// deferred run; later fires merge into it like duplicate queue entries do.
+func (s *Scheduler) deferRun(taskID uint, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.deferred[taskID]; exists {
+		return
+	}
+	s.deferred[taskID] = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		delete(s.deferred, taskID)
+		s.mu.Unlock()
+		s.enqueueScheduledTask(taskID)
+	})
+}
+
+// cancelDeferred 停止任务尚未触发的延后运行。调用方需持有 s.mu。
+func (s *Scheduler) cancelDeferred(taskID uint) {
+	if timer, exists := s.deferred[taskID]; exists {
+		timer.Stop()
+		delete(s.deferred, taskID)
+	}
+}
+
+// recordSkippedRun 为被跳过的定时运行写入一条记录，便于在运行记录中查看原因
+func (s *Scheduler) recordSkippedRun(task model.BackupTask, reason string) {
+	now := time.Now()
+	backupLog := model.BackupLog{
+		TaskID:    task.ID,
+		Status:    "skipped",
+		Message:   reason,
+		StartTime: now,
+		EndTime:   &now,
+	}
+	if err := database.DB.Create(&backupLog).Error; err != nil {
+		logger.Module(logger.ModuleScheduler).Warn("Failed to record skipped run", "id", task.ID, "error", err)
+	}
+}
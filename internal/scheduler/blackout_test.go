@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+func TestCronSpecAddsTaskTimezone(t *testing.T) {
+	task := model.BackupTask{CronExpression: "0 2 * * *", Timezone: "Asia/Shanghai"}
+	if got, want := cronSpec(task), "CRON_TZ=Asia/Shanghai 0 0 2 * * *"; got != want {
+		t.Fatalf("cronSpec() = %q, want %q", got, want)
+	}
+	task.Timezone = ""
+	if got, want := cronSpec(task), "0 0 2 * * *"; got != want {
+		t.Fatalf("cronSpec() = %q, want %q", got, want)
+	}
+}
+
+func TestFindBlackoutPrefersSkipOverDefer(t *testing.T) {
+	now := time.Date(2025, 12, 6, 3, 0, 0, 0, time.UTC)
+	windows := []model.BlackoutWindow{
+		{ID: 1, StartTime: "02:00", EndTime: "05:00", Action: model.BlackoutActionDefer},
+		{ID: 2, StartTime: "02:30", EndTime: "03:30", Action: model.BlackoutActionSkip},
+	}
+
+	window, _, blocked := findBlackout(windows, now)
+	if !blocked || window.ID != 2 {
+		t.Fatalf("findBlackout() = window %d blocked %v, want skip window 2", window.ID, blocked)
+	}
+}
+
+func TestFindBlackoutDefersUntilLatestEnd(t *testing.T) {
+	now := time.Date(2025, 12, 6, 3, 0, 0, 0, time.UTC)
+	windows := []model.BlackoutWindow{
+		{ID: 1, StartTime: "02:00", EndTime: "04:00", Action: model.BlackoutActionDefer},
+		{ID: 2, StartTime: "02:30", EndTime: "05:00", Action: model.BlackoutActionDefer},
+		{ID: 3, StartTime: "06:00", EndTime: "07:00", Action: model.BlackoutActionSkip},
+	}
+
+	window, end, blocked := findBlackout(windows, now)
+	if !blocked || window.ID != 2 {
+		t.Fatalf("findBlackout() = window %d blocked %v, want defer window 2", window.ID, blocked)
+	}
+	if want := time.Date(2025, 12, 6, 5, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Fatalf("defer until %s, want %s", end, want)
+	}
+	if _, _, blocked := findBlackout(windows, now.Add(2*time.Hour+30*time.Minute)); blocked {
+		t.Fatal("05:30 is outside every window")
+	}
+}
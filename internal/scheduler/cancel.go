@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mingzaily/bitwarden-backup/internal/logger"
+)
+
+// errRunCancelled is the cancellation cause used for user-requested stops.
+// It lets executeTask distinguish a cancelled run from a timeout or failure.
+var errRunCancelled = errors.New("backup run cancelled by user")
+
+// activeRun 记录正在执行的任务及其取消函数
+type activeRun struct {
+	logID  uint
+	cancel context.CancelCauseFunc
+	// done is set once the run can no longer be cancelled. The entry stays
+	// until the queue entry is released, so the task is not reported as
+	// queued in between.
+	done bool
+}
+
+// beginRun registers the cancellable context of a dequeued task. It returns
+// false when the queue entry was cancelled before the worker reached it.
+func (s *Scheduler) beginRun(taskID uint, cancel context.CancelCauseFunc) bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	if !s.queuedTasks[taskID] {
+		return false
+	}
+	s.runs[taskID] = &activeRun{cancel: cancel}
+	return true
+}
+
+// attachRunLog links a running task to its execution record so the run can
+// also be cancelled by log ID.
+func (s *Scheduler) attachRunLog(taskID, logID uint) {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	if run, ok := s.runs[taskID]; ok {
+		run.logID = logID
+	}
+}
+
+// endRun detaches a task from cancellation. It is safe to call repeatedly.
+func (s *Scheduler) endRun(taskID uint) {
+	s.queueMu.Lock()
+	if run, ok := s.runs[taskID]; ok {
+		run.done = true
+	}
+	s.queueMu.Unlock()
+}
+
+// CancelTask 取消排队中或正在执行的任务。正在执行的任务会取消其上下文，
+// 由 exec.CommandContext 终止 Bitwarden CLI 子进程，并照常执行登出清理。
+func (s *Scheduler) CancelTask(taskID uint) bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	if run, ok := s.runs[taskID]; ok {
+		if run.done {
+			return false
+		}
+		run.cancel(errRunCancelled)
+		logger.Module(logger.ModuleScheduler).Info("Running task cancellation requested", "id", taskID, "log_id", run.logID)
+		return true
+	}
+	if s.queuedTasks[taskID] {
+		// The channel entry cannot be removed; processTask skips it because
+		// the task is no longer marked as queued.
+		delete(s.queuedTasks, taskID)
+		logger.Module(logger.ModuleScheduler).Info("Queued task cancelled", "id", taskID)
+		return true
+	}
+	return false
+}
+
+// CancelRun 按运行记录 ID 取消正在执行的任务
+func (s *Scheduler) CancelRun(logID uint) bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	for taskID, run := range s.runs {
+		if !run.done && run.logID != 0 && run.logID == logID {
+			run.cancel(errRunCancelled)
+			logger.Module(logger.ModuleScheduler).Info("Running task cancellation requested", "id", taskID, "log_id", logID)
+			return true
+		}
+	}
+	return false
+}
+
+func isRunCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errRunCancelled)
+}
@@ -0,0 +1,29 @@
+package scheduler
+
+import (
+	"github.com/mingzaily/bitwarden-backup/internal/database"
+	"github.com/mingzaily/bitwarden-backup/internal/logger"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// triggerDependents enqueues enabled tasks that depend on upstream and whose
+// trigger condition matches the final run status. Dependents go through the
+// shared queue like manual runs, so they run after the upstream task releases
+// the worker and are deduplicated with any other pending trigger.
+func (s *Scheduler) triggerDependents(upstream model.BackupTask, status string) {
+	if database.DB == nil {
+		return
+	}
+	var dependents []model.BackupTask
+	if err := database.DB.Where("trigger_task_id = ? AND enabled = ?", upstream.ID, true).Order("id ASC").Find(&dependents).Error; err != nil {
+		logger.Module(logger.ModuleScheduler).Error("Failed to load dependent tasks", "id", upstream.ID, "error", err)
+		return
+	}
+	for i := range dependents {
+		if !dependents[i].TriggeredBy(status) {
+			continue
+		}
+		logger.Module(logger.ModuleScheduler).Info("Triggering dependent task", "id", dependents[i].ID, "name", dependents[i].Name, "upstream", upstream.Name, "status", status)
+		s.enqueueTask(dependents[i].ID)
+	}
+}
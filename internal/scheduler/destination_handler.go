@@ -10,7 +10,7 @@ import (
 	"github.com/mingzaily/bitwarden-backup/internal/provider"
 )
 
-func (s *Scheduler) backupToDestination(requestCtx context.Context, dest model.BackupDestination, sourceFile, taskName, timestamp, filenameTemplate string, log func(source, message string)) (string, error) {
+func (s *Scheduler) backupToDestination(requestCtx context.Context, dest model.BackupDestination, sourceFile string, task model.BackupTask, runID uint, timestamp string, log func(source, message string)) (string, error) {
 	registry := provider.GetRegistry()
 
 	p, err := registry.Get(dest.Type)
@@ -22,14 +22,18 @@ func (s *Scheduler) backupToDestination(requestCtx context.Context, dest model.B
 	}
 
 	ctx := provider.BackupContext{
-		Context:          requestCtx,
 		SourceFile:       sourceFile,
-		TaskName:         taskName,
+		TaskID:           task.ID,
+		TaskName:         task.Name,
+		RunID:            runID,
 		Timestamp:        timestamp,
-		FilenameTemplate: filenameTemplate,
+		FilenameTemplate: model.NormalizeFilenameTemplate(task.FilenameTemplate),
 		Destination:      dest,
 		Log:              log,
 	}
+	uploadCtx, cancel := context.WithTimeout(requestCtx, provider.UploadTimeout(p, ctx))
+	defer cancel()
+	ctx.Context = uploadCtx
 
 	targetPath, err := p.Backup(ctx)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/mingzaily/bitwarden-backup/internal/model"
 	"github.com/mingzaily/bitwarden-backup/internal/provider"
@@ -32,9 +33,9 @@ func TestBackupToDestinationReturnsCleanupErrorWithArtifact(t *testing.T) {
 			MaxBackupCount: 1,
 		},
 		"/tmp/source.json",
-		"test task",
+		model.BackupTask{Name: "test task"},
+		1,
 		"20251204092928",
-		model.DefaultFilenameTemplate,
 		nil,
 	)
 	if err == nil {
@@ -44,3 +45,46 @@ func TestBackupToDestinationReturnsCleanupErrorWithArtifact(t *testing.T) {
 		t.Fatalf("backup path = %q, want uploaded artifact path", path)
 	}
 }
+
+// deadlineProvider records the deadline its upload runs under.
+type deadlineProvider struct {
+	kind     string
+	timeout  time.Duration
+	deadline time.Time
+}
+
+func (p *deadlineProvider) Type() string { return p.kind }
+
+func (p *deadlineProvider) Backup(ctx provider.BackupContext) (string, error) {
+	p.deadline, _ = ctx.Context.Deadline()
+	return "/backups/backup.json", nil
+}
+
+type slowUploadProvider struct{ deadlineProvider }
+
+func (p *slowUploadProvider) UploadTimeout(provider.BackupContext) time.Duration { return p.timeout }
+
+func TestBackupToDestinationUsesProviderUploadBudget(t *testing.T) {
+	plain := &deadlineProvider{kind: "test-default-budget"}
+	slow := &slowUploadProvider{deadlineProvider{kind: "test-long-budget", timeout: 45 * time.Minute}}
+	provider.GetRegistry().Register(plain)
+	provider.GetRegistry().Register(slow)
+
+	for _, tc := range []struct {
+		kind     string
+		deadline *time.Time
+		want     time.Duration
+	}{
+		{plain.kind, &plain.deadline, provider.DefaultUploadTimeout},
+		{slow.kind, &slow.deadline, 45 * time.Minute},
+	} {
+		start := time.Now()
+		if _, err := (&Scheduler{}).backupToDestination(context.Background(), model.BackupDestination{Type: tc.kind},
+			"/tmp/source.json", model.BackupTask{Name: "test task"}, 1, "20251204092928", nil); err != nil {
+			t.Fatalf("%s: backupToDestination() error = %v", tc.kind, err)
+		}
+		if budget := tc.deadline.Sub(start); budget < tc.want-time.Minute || budget > tc.want+time.Minute {
+			t.Errorf("%s: upload budget = %s, want about %s", tc.kind, budget, tc.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -22,11 +23,8 @@ func normalizeCron(expr string) string {
 }
 
 func (s *Scheduler) AddTask(task model.BackupTask) error {
-	cronExpr := normalizeCron(task.CronExpression)
 	taskID := task.ID // 只捕获任务 ID，执行时重新查询最新数据
-	entryID, err := s.cron.AddFunc(cronExpr, func() {
-		s.enqueueTask(taskID)
-	})
+	entryID, err := s.cron.AddFunc(cronSpec(task), s.scheduledJob(taskID))
 	if err != nil {
 		return fmt.Errorf("failed to add cron job: %w", err)
 	}
@@ -34,9 +32,10 @@ func (s *Scheduler) AddTask(task model.BackupTask) error {
 	// 保存任务ID到entry ID的映射
 	s.mu.Lock()
 	s.taskEntries[task.ID] = entryID
+	s.spreads[task.ID] = newSpreadConfig(task)
 	s.mu.Unlock()
 
-	logger.Module(logger.ModuleScheduler).Info("Task added", "name", task.Name, "id", task.ID, "cron", task.CronExpression)
+	logger.Module(logger.ModuleScheduler).Info("Task added", "name", task.Name, "id", task.ID, "cron", task.CronExpression, "timezone", task.Timezone, "jitter_minutes", task.JitterMinutes, "stagger_minutes", task.StaggerMinutes)
 	return nil
 }
 
@@ -73,7 +72,14 @@ func (s *Scheduler) TriggerTask(taskID uint) bool {
 }
 
 func (s *Scheduler) processTask(taskID uint) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	if !s.beginRun(taskID, cancel) {
+		logger.Module(logger.ModuleScheduler).Info("Task was cancelled while queued, skipping", "id", taskID)
+		return
+	}
 	defer s.removeFromQueue(taskID)
+	defer s.endRun(taskID)
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Module(logger.ModuleScheduler).Error("Task execution panic recovered", "id", taskID, "panic", r)
@@ -89,12 +95,13 @@ func (s *Scheduler) processTask(taskID uint) {
 		logger.Module(logger.ModuleScheduler).Info("Task is disabled, skipping execution", "id", taskID, "name", latestTask.Name)
 		return
 	}
-	s.executeTask(latestTask)
+	s.executeTask(ctx, latestTask)
 }
 
 func (s *Scheduler) removeFromQueue(taskID uint) {
 	s.queueMu.Lock()
 	delete(s.queuedTasks, taskID)
+	delete(s.runs, taskID)
 	s.queueMu.Unlock()
 }
 
@@ -103,6 +110,8 @@ func (s *Scheduler) RemoveTask(taskID uint) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.cancelDeferred(taskID)
+	delete(s.spreads, taskID)
 	if entryID, exists := s.taskEntries[taskID]; exists {
 		s.cron.Remove(entryID)
 		delete(s.taskEntries, taskID)
@@ -125,7 +134,7 @@ func (s *Scheduler) UpdateTask(task model.BackupTask) error {
 	return s.AddTask(task)
 }
 
-func (s *Scheduler) executeTask(task model.BackupTask) {
+func (s *Scheduler) executeTask(ctx context.Context, task model.BackupTask) {
 	logger.Module(logger.ModuleScheduler).Info("Executing task", "name", task.Name)
 
 	startTime := time.Now()
@@ -135,23 +144,47 @@ func (s *Scheduler) executeTask(task model.BackupTask) {
 		StartTime: startTime,
 	}
 	database.DB.Create(&backupLog)
+	s.attachRunLog(task.ID, backupLog.ID)
+	s.events.Open(backupLog.ID)
+	defer func() {
+		s.events.Close(backupLog.ID, backupLog.Status)
+	}()
+	// Deferred calls run in reverse order, so dependents are enqueued before
+	// the hub closes but after every return path has saved the final status.
+	defer func() {
+		s.triggerDependents(task, backupLog.Status)
+	}()
 
-	if err := s.performBackup(task, &backupLog); err != nil {
+	err := s.performBackup(ctx, task, &backupLog)
+	// Detach before reading the cancellation cause so a late cancel request
+	// cannot relabel a run that has already finished.
+	s.endRun(task.ID)
+
+	endTime := time.Now()
+	backupLog.EndTime = &endTime
+	if isRunCancelled(ctx) {
+		logger.Module(logger.ModuleScheduler).Warn("Task cancelled", "name", task.Name)
+		backupLog.Status = "cancelled"
+		if backupLog.Message == "" {
+			backupLog.Message = "Backup cancelled by user"
+		} else {
+			backupLog.Message = "Backup cancelled by user: " + backupLog.Message
+		}
+		database.DB.Save(&backupLog)
+		return
+	}
+	if err != nil {
 		logger.Module(logger.ModuleScheduler).Error("Task failed", "name", task.Name, "error", err)
-		endTime := time.Now()
 		backupLog.Status = "failed"
 		backupLog.Message = err.Error()
-		backupLog.EndTime = &endTime
 		database.DB.Save(&backupLog)
 		return
 	}
 
-	endTime := time.Now()
 	backupLog.Status = "success"
 	if backupLog.Message == "" {
 		backupLog.Message = "Backup completed successfully"
 	}
-	backupLog.EndTime = &endTime
 	database.DB.Save(&backupLog)
 	logger.Module(logger.ModuleScheduler).Info("Task completed successfully", "name", task.Name)
 }
@@ -1,6 +1,9 @@
 package scheduler
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestTriggerTaskDeduplicatesQueuedTask(t *testing.T) {
 	s := New()
@@ -28,3 +31,76 @@ func TestNextBackupTimestampIsMonotonic(t *testing.T) {
 		t.Fatalf("timestamps must use YYYYMMDDHHmmss format: first=%s second=%s", first, second)
 	}
 }
+
+func TestCancelTaskSkipsQueuedEntry(t *testing.T) {
+	s := New()
+
+	if !s.TriggerTask(7) {
+		t.Fatal("trigger should be accepted")
+	}
+	if !s.CancelTask(7) {
+		t.Fatal("queued task should be cancellable")
+	}
+	if s.CancelTask(7) {
+		t.Fatal("cancelled task should no longer be queued")
+	}
+
+	// The stale channel entry must not start a run once the worker reaches it.
+	if s.beginRun(<-s.taskQueue, func(error) {}) {
+		t.Fatal("cancelled queue entry should be skipped")
+	}
+}
+
+func TestCancelRunCancelsActiveContext(t *testing.T) {
+	s := New()
+	if !s.TriggerTask(7) {
+		t.Fatal("trigger should be accepted")
+	}
+	<-s.taskQueue
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	if !s.beginRun(7, cancel) {
+		t.Fatal("queued task should start")
+	}
+	s.attachRunLog(7, 99)
+
+	if s.CancelRun(98) {
+		t.Fatal("unknown log should not be cancelled")
+	}
+	if !s.CancelRun(99) {
+		t.Fatal("active run should be cancelled by log ID")
+	}
+	if !isRunCancelled(ctx) {
+		t.Fatalf("context cause = %v, want user cancellation", context.Cause(ctx))
+	}
+
+	s.endRun(7)
+	if s.CancelRun(99) {
+		t.Fatal("finished run should not be cancellable")
+	}
+}
+
+func TestCancelTaskIgnoresFinishedRun(t *testing.T) {
+	s := New()
+	if !s.TriggerTask(7) {
+		t.Fatal("trigger should be accepted")
+	}
+	<-s.taskQueue
+	if !s.beginRun(7, func(error) { t.Fatal("finished run should not be cancelled") }) {
+		t.Fatal("queued task should start")
+	}
+
+	// The queue entry is released only after the run has saved its result.
+	s.endRun(7)
+	if s.CancelTask(7) {
+		t.Fatal("finished run should not be reported as cancelled")
+	}
+	if s.TriggerTask(7) {
+		t.Fatal("task should stay deduplicated until its queue entry is released")
+	}
+	s.removeFromQueue(7)
+	if !s.TriggerTask(7) {
+		t.Fatal("task should be accepted once its queue entry is released")
+	}
+}
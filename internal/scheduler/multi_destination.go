@@ -52,16 +52,20 @@ func createExportPath(taskName, timestamp, suffix string) (string, string, error
 	return path, "", nil
 }
 
-func (s *Scheduler) performBackupToDestinations(task model.BackupTask, backupLog *model.BackupLog) error {
+func (s *Scheduler) performBackupToDestinations(runCtx context.Context, task model.BackupTask, backupLog *model.BackupLog) error {
 	var sourceServer model.ServerConfig
 	if err := database.DB.First(&sourceServer, task.SourceServerID).Error; err != nil {
 		return fmt.Errorf("failed to get source server: %w", err)
 	}
 
 	client := bitwarden.NewClient()
+	client.SetListener(s.newRunRecorder(backupLog.ID).record)
 
-	// 使用 defer 确保无论成功还是失败都保存执行日志
+	// 使用 defer 确保无论成功还是失败（包括取消）都保存执行日志
 	defer func() {
+		if isRunCancelled(runCtx) {
+			client.AddLog("运行已被用户取消")
+		}
 		if logs := client.GetLogs(); len(logs) > 0 {
 			if logsJSON, err := json.Marshal(logs); err == nil {
 				backupLog.ExecutionLogs = string(logsJSON)
@@ -75,7 +79,9 @@ func (s *Scheduler) performBackupToDestinations(task model.BackupTask, backupLog
 	}
 
 	client.AddLog(fmt.Sprintf("Executing task: %s", task.Name))
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	// The deadline covers the Bitwarden export; every destination gets its
+	// own upload budget in backupToDestination.
+	ctx, cancel := context.WithTimeout(runCtx, 5*time.Minute)
 	defer cancel()
 
 	needEncrypted := false
@@ -87,7 +93,7 @@ func (s *Scheduler) performBackupToDestinations(task model.BackupTask, backupLog
 			continue
 		}
 		enabledDestinationCount++
-		if dest.Type == "local" || dest.Type == "webdav" || dest.Type == "s3" {
+		if dest.StoresFiles() {
 			if dest.Encrypted {
 				needEncrypted = true
 				if encryptionPassword == "" && dest.EncryptionPassword != "" {
@@ -217,13 +223,18 @@ func (s *Scheduler) performBackupToDestinations(task model.BackupTask, backupLog
 		if !dest.Enabled {
 			continue
 		}
+		if isRunCancelled(runCtx) {
+			// Keep the artifacts already written but do not start another
+			// destination once the user stopped the run.
+			break
+		}
 
 		sourceFile := plainFile
-		if (dest.Type == "local" || dest.Type == "webdav" || dest.Type == "s3") && dest.Encrypted {
+		if dest.StoresFiles() && dest.Encrypted {
 			sourceFile = encryptedFile
 		}
 
-		targetPath, err := s.backupToDestination(ctx, dest, sourceFile, task.Name, timestamp, model.NormalizeFilenameTemplate(task.FilenameTemplate), client.AddLogWithSource)
+		targetPath, err := s.backupToDestination(runCtx, dest, sourceFile, task, backupLog.ID, timestamp, client.AddLogWithSource)
 		if targetPath != "" {
 			// A provider can finish the upload and then fail while applying
 			// retention. Keep the artifact visible in the execution record even
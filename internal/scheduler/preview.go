@@ -0,0 +1,29 @@
+package scheduler
+
+import "time"
+
+// NextRuns 返回任务接下来 count 次的触发时间（服务器时区）。任务未被调度时
+// ok 为 false。结果是 cron 的名义触发时间，不含随机延迟、错峰和禁止窗口。
+func (s *Scheduler) NextRuns(taskID uint, count int) ([]time.Time, bool) {
+	s.mu.RLock()
+	entryID, ok := s.taskEntries[taskID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	entry := s.cron.Entry(entryID)
+	if !entry.Valid() {
+		return nil, false
+	}
+	runs := make([]time.Time, 0, count)
+	next := time.Now()
+	for len(runs) < count {
+		next = entry.Schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next.In(time.Local))
+	}
+	return runs, true
+}
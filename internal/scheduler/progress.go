@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/database"
+	"github.com/mingzaily/bitwarden-backup/internal/events"
+	"github.com/mingzaily/bitwarden-backup/internal/logger"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// executionLogCheckpointInterval controls how often the execution logs of a
+// running task are written to its record. Live viewers use the event hub;
+// the checkpoint keeps the record useful if the process dies mid-run.
+const executionLogCheckpointInterval = 5 * time.Second
+
+// runRecorder publishes execution log entries of one run to the event hub and
+// periodically checkpoints them to the database. It is called synchronously
+// from the run goroutine through bitwarden.Client's listener.
+type runRecorder struct {
+	logID          uint
+	hub            *events.Hub
+	entries        []model.LogEntry
+	lastCheckpoint time.Time
+}
+
+func (s *Scheduler) newRunRecorder(logID uint) *runRecorder {
+	return &runRecorder{logID: logID, hub: s.events, lastCheckpoint: time.Now()}
+}
+
+func (r *runRecorder) record(entry model.LogEntry) {
+	r.entries = append(r.entries, entry)
+	if r.hub != nil {
+		r.hub.Publish(r.logID, entry)
+	}
+	if r.logID != 0 && time.Since(r.lastCheckpoint) >= executionLogCheckpointInterval {
+		r.checkpoint()
+	}
+}
+
+func (r *runRecorder) checkpoint() {
+	r.lastCheckpoint = time.Now()
+	if database.DB == nil {
+		return
+	}
+	logsJSON, err := json.Marshal(r.entries)
+	if err != nil {
+		return
+	}
+	if err := database.DB.Model(&model.BackupLog{}).Where("id = ?", r.logID).Update("execution_logs", string(logsJSON)).Error; err != nil {
+		logger.Module(logger.ModuleScheduler).Warn("Failed to checkpoint execution logs", "log_id", r.logID, "error", err)
+	}
+}
+
+// SubscribeRun 订阅正在执行的运行日志。ok 为 false 表示该运行已结束或不存在。
+func (s *Scheduler) SubscribeRun(logID uint) ([]model.LogEntry, <-chan events.Event, func(), bool) {
+	return s.events.Subscribe(logID)
+}
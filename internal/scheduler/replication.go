@@ -0,0 +1,308 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/bitwarden"
+	"github.com/mingzaily/bitwarden-backup/internal/database"
+	"github.com/mingzaily/bitwarden-backup/internal/logger"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/provider"
+)
+
+// replicationTimeout bounds one replication run; unlike an export it may copy
+// many files.
+const replicationTimeout = 30 * time.Minute
+
+// replicationArtifact is a backup file on the source destination together
+// with the backup task that produced it.
+type replicationArtifact struct {
+	task      model.BackupTask
+	name      string
+	timestamp string
+}
+
+// performReplication copies the backup files that backup tasks wrote to the
+// task's source destination into each of its destinations, skipping files the
+// destination already has, and then applies the destination's retention.
+func (s *Scheduler) performReplication(runCtx context.Context, task model.BackupTask, backupLog *model.BackupLog) error {
+	// The client only collects the execution log; replication never runs the
+	// Bitwarden CLI.
+	client := bitwarden.NewClientWithLogSink("replication", nil)
+	client.SetListener(s.newRunRecorder(backupLog.ID).record)
+	defer func() {
+		if isRunCancelled(runCtx) {
+			client.AddLog("运行已被用户取消")
+		}
+		if logs := client.GetLogs(); len(logs) > 0 {
+			if logsJSON, err := json.Marshal(logs); err == nil {
+				backupLog.ExecutionLogs = string(logsJSON)
+			}
+		}
+	}()
+
+	if task.SourceDestinationID == nil {
+		return fmt.Errorf("replication source destination is not set")
+	}
+	var source model.BackupDestination
+	if err := database.DB.First(&source, *task.SourceDestinationID).Error; err != nil {
+		return fmt.Errorf("failed to get replication source destination: %w", err)
+	}
+	if !source.Enabled {
+		client.AddLog(fmt.Sprintf("复制来源目标已停用: %s", source.Name))
+		return fmt.Errorf("replication source destination is disabled: %s", source.Name)
+	}
+
+	var tasks []model.BackupTask
+	if err := database.DB.Preload("Destinations").Find(&tasks).Error; err != nil {
+		return fmt.Errorf("failed to load backup tasks: %w", err)
+	}
+	producers := replicationProducers(tasks, source.ID)
+	if len(producers) == 0 {
+		client.AddLog(fmt.Sprintf("没有备份任务写入复制来源目标: %s", source.Name))
+		return fmt.Errorf("no backup task writes to %s", source.Name)
+	}
+
+	client.AddLog(fmt.Sprintf("Executing replication: %s (来源 %s)", task.Name, source.Name))
+	ctx, cancel := context.WithTimeout(runCtx, replicationTimeout)
+	defer cancel()
+
+	artifacts, err := listReplicationArtifacts(ctx, source, producers, client.AddLogWithSource)
+	if err != nil {
+		return err
+	}
+	client.AddLog(fmt.Sprintf("来源目标共有 %d 个备份文件", len(artifacts)))
+
+	var copiedPaths []string
+	var successCount, failCount, copiedCount int
+	var destinationErrors []string
+	for _, dest := range task.Destinations {
+		if !dest.Enabled || dest.ID == source.ID {
+			continue
+		}
+		if isRunCancelled(ctx) {
+			break
+		}
+		paths, err := s.replicateToDestination(ctx, task, backupLog.ID, source, dest, producers, artifacts, client.AddLogWithSource)
+		copiedCount += len(paths)
+		copiedPaths = append(copiedPaths, paths...)
+		if err != nil {
+			failCount++
+			destinationErrors = append(destinationErrors, fmt.Sprintf("%s: %v", dest.Name, err))
+			logger.Module(logger.ModuleScheduler).Error("Failed to replicate to destination", "destination", dest.Name, "error", err)
+		} else {
+			successCount++
+		}
+	}
+
+	if successCount == 0 && failCount == 0 {
+		client.AddLog("没有可用的已启用复制目标")
+		return fmt.Errorf("no enabled replication destinations")
+	}
+	if len(copiedPaths) > 0 {
+		backupLog.BackupFile = copiedPaths[0]
+	}
+	if failCount > 0 {
+		backupLog.Message = fmt.Sprintf("Replication completed with destination errors: %s", strings.Join(destinationErrors, "; "))
+	} else {
+		backupLog.Message = fmt.Sprintf("Replication completed successfully: %d files copied", copiedCount)
+	}
+	if successCount == 0 {
+		return fmt.Errorf("all %d replication destinations failed: %s", failCount, strings.Join(destinationErrors, "; "))
+	}
+	return nil
+}
+
+// replicationProducers returns the backup tasks that write to the source
+// destination; their filename templates identify the files to copy.
+func replicationProducers(tasks []model.BackupTask, sourceID uint) []model.BackupTask {
+	var producers []model.BackupTask
+	for _, task := range tasks {
+		if task.IsReplication() {
+			continue
+		}
+		if slices.ContainsFunc(task.Destinations, func(dest model.BackupDestination) bool { return dest.ID == sourceID }) {
+			producers = append(producers, task)
+		}
+	}
+	return producers
+}
+
+// listReplicationArtifacts lists the source files of every producer, newest
+// first. Legacy names without a {time} value cannot be renamed for the
+// destination and are skipped.
+func listReplicationArtifacts(ctx context.Context, source model.BackupDestination, producers []model.BackupTask, log func(source, message string)) ([]replicationArtifact, error) {
+	p, err := provider.GetRegistry().Get(source.Type)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := p.(provider.ArtifactLister)
+	if !ok {
+		return nil, fmt.Errorf("destination type %s does not support listing backups", source.Type)
+	}
+
+	var artifacts []replicationArtifact
+	for _, producer := range producers {
+		sourceCtx := replicationContext(ctx, producer, source, 0, "", log)
+		files, err := lister.ListArtifacts(sourceCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", source.Name, err)
+		}
+		for _, file := range files {
+			timestamp, ok := provider.BackupTimestamp(file.Name, sourceCtx)
+			if !ok {
+				continue
+			}
+			artifacts = append(artifacts, replicationArtifact{task: producer, name: file.Name, timestamp: timestamp})
+		}
+	}
+	slices.SortStableFunc(artifacts, func(a, b replicationArtifact) int {
+		return strings.Compare(b.timestamp, a.timestamp)
+	})
+	return artifacts, nil
+}
+
+// replicateToDestination copies the artifacts dest is missing and applies its
+// retention for every producer. It returns the paths of the copied files.
+func (s *Scheduler) replicateToDestination(ctx context.Context, task model.BackupTask, runID uint, source, dest model.BackupDestination, producers []model.BackupTask, artifacts []replicationArtifact, log func(source, message string)) ([]string, error) {
+	registry := provider.GetRegistry()
+	sourceProvider, err := registry.Get(source.Type)
+	if err != nil {
+		return nil, err
+	}
+	downloader, ok := sourceProvider.(provider.ArtifactDownloader)
+	if !ok {
+		return nil, fmt.Errorf("destination type %s does not support downloading backups", source.Type)
+	}
+	p, err := registry.Get(dest.Type)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := p.(provider.ArtifactLister)
+	if !ok {
+		return nil, fmt.Errorf("destination type %s does not support listing backups", dest.Type)
+	}
+	if dest.Encrypted && !source.Encrypted {
+		return nil, fmt.Errorf("encrypted destination cannot receive plain backups from %s", source.Name)
+	}
+
+	var copied []string
+	var copyErrors []error
+	for _, producer := range producers {
+		destCtx := replicationContext(ctx, producer, dest, runID, "", log)
+		existing, err := lister.ListArtifacts(destCtx)
+		if err != nil {
+			return copied, fmt.Errorf("failed to list destination: %w", err)
+		}
+		present := make(map[string]bool, len(existing))
+		for _, file := range existing {
+			present[file.Name] = true
+		}
+
+		// Only the newest files the retention keeps are copied, otherwise they
+		// would be uploaded and deleted again in the same run.
+		considered := 0
+		for _, artifact := range artifacts {
+			if artifact.task.ID != producer.ID {
+				continue
+			}
+			if dest.MaxBackupCount > 0 && considered >= dest.MaxBackupCount {
+				break
+			}
+			considered++
+			if isRunCancelled(ctx) {
+				return copied, context.Cause(ctx)
+			}
+
+			copyCtx := replicationContext(ctx, producer, dest, runID, artifact.timestamp, log)
+			if present[provider.BackupFilename(copyCtx)] {
+				continue
+			}
+			sourceCtx := replicationContext(ctx, producer, source, runID, artifact.timestamp, log)
+			targetPath, err := copyArtifact(task, downloader, sourceCtx, p, copyCtx, artifact.name)
+			if err != nil {
+				copyErrors = append(copyErrors, fmt.Errorf("%s: %w", artifact.name, err))
+				continue
+			}
+			copied = append(copied, targetPath)
+		}
+
+		if dest.MaxBackupCount > 0 {
+			if rp, ok := p.(provider.RetentionProvider); ok {
+				deleted, err := rp.Cleanup(destCtx, dest.MaxBackupCount)
+				if err != nil {
+					destCtx.AddLog(dest.Type, "清理旧备份失败: "+err.Error())
+					copyErrors = append(copyErrors, fmt.Errorf("failed to clean up old backups: %w", err))
+				} else if deleted > 0 {
+					destCtx.AddLog(dest.Type, "已清理旧备份: "+fmt.Sprintf("%d 个", deleted))
+				}
+			}
+		}
+	}
+
+	if len(copied) == 0 && len(copyErrors) == 0 {
+		log("replication", fmt.Sprintf("%s 已是最新，无需复制", dest.Name))
+	}
+	if len(copyErrors) > 0 {
+		return copied, errors.Join(copyErrors...)
+	}
+	return copied, nil
+}
+
+// copyArtifact downloads one file into a private temp file and uploads it
+// with the destination provider, which names it for the destination medium.
+func copyArtifact(task model.BackupTask, downloader provider.ArtifactDownloader, sourceCtx provider.BackupContext, uploader provider.DestinationProvider, destCtx provider.BackupContext, name string) (string, error) {
+	tempFile, tempDir, err := createExportPath(task.Name, destCtx.Timestamp, ".json")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.Remove(tempFile)
+		if tempDir != "" {
+			_ = os.Remove(tempDir)
+		}
+	}()
+
+	file, err := os.OpenFile(tempFile, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to open temp file: %w", err)
+	}
+	if err := downloader.Download(sourceCtx, name, file); err != nil {
+		file.Close()
+		sourceCtx.AddLog(sourceCtx.Destination.Type, "下载备份失败: "+err.Error())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	destCtx.SourceFile = tempFile
+	targetPath, err := uploader.Backup(destCtx)
+	if err != nil {
+		destCtx.AddLog(destCtx.Destination.Type, "服务商执行失败: "+err.Error())
+		return "", err
+	}
+	return targetPath, nil
+}
+
+// replicationContext builds the provider context of a producer's files on
+// dest, so names and retention follow the producer's filename template.
+func replicationContext(ctx context.Context, producer model.BackupTask, dest model.BackupDestination, runID uint, timestamp string, log func(source, message string)) provider.BackupContext {
+	return provider.BackupContext{
+		Context:          ctx,
+		TaskID:           producer.ID,
+		TaskName:         producer.Name,
+		RunID:            runID,
+		Timestamp:        timestamp,
+		FilenameTemplate: model.NormalizeFilenameTemplate(producer.FilenameTemplate),
+		Destination:      dest,
+		Log:              log,
+	}
+}
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+func TestReplicateToDestinationCopiesMissingArtifacts(t *testing.T) {
+	source := model.BackupDestination{ID: 1, Name: "disk", Type: "local", LocalPath: t.TempDir()}
+	dest := model.BackupDestination{ID: 2, Name: "mirror", Type: "local", LocalPath: t.TempDir(), MaxBackupCount: 2}
+	producer := model.BackupTask{ID: 5, Name: "nightly", FilenameTemplate: "{task_name}_{medium}_{time}.json", Destinations: []model.BackupDestination{source}}
+
+	for _, timestamp := range []string{"20251201020000", "20251202020000", "20251203020000"} {
+		name := filepath.Join(source.LocalPath, "nightly_local_"+timestamp+".json")
+		if err := os.WriteFile(name, []byte(timestamp), 0600); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+	}
+	// Already replicated by an earlier run.
+	if err := os.WriteFile(filepath.Join(dest.LocalPath, "nightly_local_20251203020000.json"), []byte("20251203020000"), 0600); err != nil {
+		t.Fatalf("write destination: %v", err)
+	}
+
+	producers := replicationProducers([]model.BackupTask{producer, {ID: 6, Type: model.TaskTypeReplication, Destinations: []model.BackupDestination{source}}}, source.ID)
+	if len(producers) != 1 || producers[0].ID != producer.ID {
+		t.Fatalf("producers = %+v", producers)
+	}
+	artifacts, err := listReplicationArtifacts(context.Background(), source, producers, nil)
+	if err != nil {
+		t.Fatalf("listReplicationArtifacts() error = %v", err)
+	}
+	if len(artifacts) != 3 || artifacts[0].timestamp != "20251203020000" {
+		t.Fatalf("artifacts = %+v", artifacts)
+	}
+
+	task := model.BackupTask{ID: 9, Name: "mirror nightly", Type: model.TaskTypeReplication}
+	log := func(string, string) {}
+	copied, err := (&Scheduler{}).replicateToDestination(context.Background(), task, 1, source, dest, producers, artifacts, log)
+	if err != nil {
+		t.Fatalf("replicateToDestination() error = %v", err)
+	}
+	// The retention keeps two files, so the oldest one is not copied at all.
+	want := filepath.Join(dest.LocalPath, "nightly_local_20251202020000.json")
+	if !slices.Equal(copied, []string{want}) {
+		t.Fatalf("copied = %v, want %v", copied, want)
+	}
+	if content, err := os.ReadFile(want); err != nil || string(content) != "20251202020000" {
+		t.Fatalf("replicated file = %q, %v", content, err)
+	}
+	entries, err := os.ReadDir(dest.LocalPath)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("destination entries = %v, %v", entries, err)
+	}
+
+	copied, err = (&Scheduler{}).replicateToDestination(context.Background(), task, 2, source, dest, producers, artifacts, log)
+	if err != nil || len(copied) != 0 {
+		t.Fatalf("second run copied %v, %v", copied, err)
+	}
+
+	dest.Encrypted = true
+	if _, err := (&Scheduler{}).replicateToDestination(context.Background(), task, 3, source, dest, producers, artifacts, log); err == nil {
+		t.Fatal("plain backups were replicated to an encrypted destination")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/mingzaily/bitwarden-backup/internal/events"
 	"github.com/mingzaily/bitwarden-backup/internal/logger"
 	"github.com/robfig/cron/v3"
 )
@@ -12,10 +13,13 @@ import (
 type Scheduler struct {
 	cron        *cron.Cron
 	taskEntries map[uint]cron.EntryID // 任务ID -> cron entry ID 映射
-	mu          sync.RWMutex          // 保护 taskEntries 的并发访问
+	deferred    map[uint]*time.Timer  // 因抖动、错峰或禁止窗口延后的定时运行
+	spreads     map[uint]spreadConfig // 任务ID -> 抖动与错峰设置
+	mu          sync.RWMutex          // 保护 taskEntries、deferred 和 spreads 的并发访问
 
 	taskQueue     chan uint
 	queuedTasks   map[uint]bool
+	runs          map[uint]*activeRun // 任务ID -> 正在执行的运行，受 queueMu 保护
 	queueMu       sync.Mutex
 	stopChan      chan struct{}
 	workerDone    chan struct{}
@@ -24,16 +28,21 @@ type Scheduler struct {
 	stopped       atomic.Bool
 	timestampMu   sync.Mutex
 	lastTimestamp time.Time
+	events        *events.Hub // 运行中任务的实时日志
 }
 
 func New() *Scheduler {
 	return &Scheduler{
 		cron:        cron.New(cron.WithSeconds()),
 		taskEntries: make(map[uint]cron.EntryID),
+		deferred:    make(map[uint]*time.Timer),
+		spreads:     make(map[uint]spreadConfig),
 		taskQueue:   make(chan uint, 100),
 		queuedTasks: make(map[uint]bool),
+		runs:        make(map[uint]*activeRun),
 		stopChan:    make(chan struct{}),
 		workerDone:  make(chan struct{}),
+		events:      events.NewHub(),
 	}
 }
 
@@ -59,6 +68,11 @@ func (s *Scheduler) Stop() {
 		s.queueMu.Unlock()
 
 		s.cron.Stop()
+		s.mu.Lock()
+		for taskID := range s.deferred {
+			s.cancelDeferred(taskID)
+		}
+		s.mu.Unlock()
 		close(s.stopChan)
 
 		select {
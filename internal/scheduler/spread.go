@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/logger"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+// spreadConfig 记录任务的抖动与错峰设置，用于在 cron 触发时计算延迟
+type spreadConfig struct {
+	spec    string
+	jitter  time.Duration
+	stagger time.Duration
+}
+
+func newSpreadConfig(task model.BackupTask) spreadConfig {
+	return spreadConfig{
+		spec:    cronSpec(task),
+		jitter:  time.Duration(task.JitterMinutes) * time.Minute,
+		stagger: time.Duration(task.StaggerMinutes) * time.Minute,
+	}
+}
+
+// scheduledJob wraps the cron callback of a task. Tasks sharing one cron
+// expression would otherwise all fire at the same second and serialize behind
+// the Bitwarden CLI lock, so the wrapper delays the fire by the task's
+// stagger offset plus a random jitter before blackout checks and enqueueing.
+func (s *Scheduler) scheduledJob(taskID uint) func() {
+	return func() {
+		delay := s.spreadDelay(taskID, rand.N[time.Duration])
+		if delay <= 0 {
+			s.enqueueScheduledTask(taskID)
+			return
+		}
+		logger.Module(logger.ModuleScheduler).Info("Scheduled run delayed", "id", taskID, "delay", delay.Round(time.Second).String())
+		s.deferRun(taskID, delay)
+	}
+}
+
+// spreadDelay computes the delay for one fire. randN must return a value in
+// [0, n) and is injected so tests can make jitter deterministic.
+func (s *Scheduler) spreadDelay(taskID uint, randN func(time.Duration) time.Duration) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	config, ok := s.spreads[taskID]
+	if !ok {
+		return 0
+	}
+
+	var delay time.Duration
+	if config.stagger > 0 {
+		// Tasks with stagger enabled and an identical spec split the window
+		// evenly, ordered by task ID so offsets stay stable across restarts.
+		var group []uint
+		for id, other := range s.spreads {
+			if other.stagger > 0 && other.spec == config.spec {
+				group = append(group, id)
+			}
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i] < group[j] })
+		index := sort.Search(len(group), func(i int) bool { return group[i] >= taskID })
+		delay = config.stagger * time.Duration(index) / time.Duration(len(group))
+	}
+	if config.jitter > 0 {
+		delay += randN(config.jitter)
+	}
+	return delay
+}
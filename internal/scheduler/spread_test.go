@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+)
+
+func TestSpreadDelayStaggersIdenticalSchedules(t *testing.T) {
+	s := New()
+	for _, id := range []uint{3, 1, 2} {
+		s.spreads[id] = newSpreadConfig(model.BackupTask{ID: id, CronExpression: "0 2 * * *", StaggerMinutes: 30})
+	}
+	// A different expression must not join the group.
+	s.spreads[4] = newSpreadConfig(model.BackupTask{ID: 4, CronExpression: "0 3 * * *", StaggerMinutes: 30})
+
+	noJitter := func(time.Duration) time.Duration { t.Fatal("jitter should not be drawn"); return 0 }
+	for id, want := range map[uint]time.Duration{1: 0, 2: 10 * time.Minute, 3: 20 * time.Minute, 4: 0} {
+		if got := s.spreadDelay(id, noJitter); got != want {
+			t.Errorf("task %d delay = %s, want %s", id, got, want)
+		}
+	}
+}
+
+func TestSpreadDelayAddsJitterWithinBound(t *testing.T) {
+	s := New()
+	s.spreads[1] = newSpreadConfig(model.BackupTask{ID: 1, CronExpression: "0 2 * * *", JitterMinutes: 5})
+
+	var bound time.Duration
+	got := s.spreadDelay(1, func(n time.Duration) time.Duration {
+		bound = n
+		return n - 1
+	})
+	if bound != 5*time.Minute {
+		t.Fatalf("jitter bound = %s, want 5m", bound)
+	}
+	if got != 5*time.Minute-1 {
+		t.Fatalf("delay = %s, want just under 5m", got)
+	}
+	if got := s.spreadDelay(99, nil); got != 0 {
+		t.Fatalf("unscheduled task delay = %s, want 0", got)
+	}
+}
@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"github.com/mingzaily/bitwarden-backup/internal/database"
+	"github.com/mingzaily/bitwarden-backup/internal/logger"
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/provider"
+)
+
+// SweepLocalTempFiles removes the temp files that runs interrupted by a crash
+// left in local destinations. Call it before Start, while no run writes.
+func (s *Scheduler) SweepLocalTempFiles() {
+	var dests []model.BackupDestination
+	if err := database.DB.Where("type = ?", "local").Find(&dests).Error; err != nil {
+		logger.Module(logger.ModuleScheduler).Warn("Failed to load local destinations for temp file sweep", "error", err)
+		return
+	}
+
+	swept := make(map[string]bool)
+	for _, dest := range dests {
+		if dest.LocalPath == "" || swept[dest.LocalPath] {
+			continue
+		}
+		swept[dest.LocalPath] = true
+		removed, err := provider.SweepLocalTempFiles(dest.LocalPath)
+		if err != nil {
+			logger.Module(logger.ModuleScheduler).Warn("Failed to sweep local temp files", "destination", dest.Name, "error", err)
+		}
+		if removed > 0 {
+			logger.Module(logger.ModuleScheduler).Info("Removed leftover local temp files", "destination", dest.Name, "count", removed)
+		}
+	}
+}
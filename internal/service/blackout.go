@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/repository"
+)
+
+type BlackoutService struct {
+	repo *repository.BlackoutRepository
+}
+
+func NewBlackoutService(repo *repository.BlackoutRepository) *BlackoutService {
+	return &BlackoutService{repo: repo}
+}
+
+func (s *BlackoutService) GetGlobal() ([]model.BlackoutWindow, error) {
+	return s.repo.FindGlobal()
+}
+
+func (s *BlackoutService) GetByID(id uint) (*model.BlackoutWindow, error) {
+	return s.repo.FindGlobalByID(id)
+}
+
+func (s *BlackoutService) Create(window *model.BlackoutWindow) error {
+	return s.repo.Create(window)
+}
+
+func (s *BlackoutService) Update(id uint, window *model.BlackoutWindow) error {
+	window.ID = id
+	return s.repo.Update(window)
+}
+
+func (s *BlackoutService) Delete(id uint) error {
+	return s.repo.Delete(id)
+}
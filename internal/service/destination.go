@@ -52,26 +52,34 @@ func (s *DestinationService) Toggle(id uint) error {
 	return s.repo.Update(dest)
 }
 
-// TestConnection validates a destination without creating a backup artifact.
-// At present WebDAV is the only provider with a safe connection probe.
-func (s *DestinationService) TestConnection(id uint) error {
+// TestConnection validates a destination without creating a backup artifact
+// and returns the result of each check. Providers opt in by implementing
+// provider.ConnectionTester; a plain tester reports a single check.
+func (s *DestinationService) TestConnection(id uint) ([]provider.ConnectionCheck, error) {
 	destination, err := s.repo.FindByID(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	p, err := provider.GetRegistry().Get(destination.Type)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tester, ok := p.(provider.ConnectionTester)
 	if !ok {
-		return fmt.Errorf("destination type %s does not support connection testing", destination.Type)
+		return nil, fmt.Errorf("destination type %s does not support connection testing", destination.Type)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// The server check runs a full Bitwarden CLI login cycle.
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
 	defer cancel()
-	return tester.Test(ctx, *destination)
+	if checker, ok := p.(provider.ConnectionChecker); ok {
+		return checker.CheckConnection(ctx, *destination), nil
+	}
+	if err := tester.Test(ctx, *destination); err != nil {
+		return []provider.ConnectionCheck{{Name: "connection", Detail: err.Error()}}, nil
+	}
+	return []provider.ConnectionCheck{{Name: "connection", OK: true}}, nil
 }
 
 // GetPaginated 分页获取备份目标
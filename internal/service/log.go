@@ -17,6 +17,10 @@ func (s *LogService) GetAll() ([]model.BackupLog, error) {
 	return s.repo.FindAll()
 }
 
+func (s *LogService) GetByID(id uint) (*model.BackupLog, error) {
+	return s.repo.FindByID(id)
+}
+
 func (s *LogService) GetByTaskID(taskID uint) ([]model.BackupLog, error) {
 	return s.repo.FindByTaskID(taskID)
 }
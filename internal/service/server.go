@@ -1,7 +1,12 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/mingzaily/bitwarden-backup/internal/model"
+	"github.com/mingzaily/bitwarden-backup/internal/provider"
 	"github.com/mingzaily/bitwarden-backup/internal/repository"
 )
 
@@ -38,6 +43,24 @@ func (s *ServerService) Delete(id uint) error {
 	return s.repo.Delete(id)
 }
 
+// ListOrganizations logs in to the server and lists the organizations and
+// collections a server destination can import into.
+func (s *ServerService) ListOrganizations(id uint) ([]model.OrganizationResponse, error) {
+	server, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !server.Enabled {
+		return nil, fmt.Errorf("server is disabled: %s", server.Name)
+	}
+
+	// Listing collections costs one CLI call per organization on top of a
+	// full login and unlock cycle.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	return provider.ListServerOrganizations(ctx, *server)
+}
+
 // GetPaginated 分页获取服务器
 func (s *ServerService) GetPaginated(params model.PaginationParams, enabled *bool) ([]model.ServerConfig, int64, error) {
 	return s.repo.FindPaginated(params, enabled)
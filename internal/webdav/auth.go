@@ -0,0 +1,217 @@
+package webdav
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Authentication modes accepted by SetAuthMode.
+const (
+	AuthBasic  = "basic"
+	AuthDigest = "digest"
+	AuthBearer = "bearer"
+	AuthNone   = "none"
+)
+
+// SetAuthMode switches from Basic to Digest, a bearer token or no HTTP
+// authentication, e.g. when a client certificate identifies the caller.
+func (c *Client) SetAuthMode(mode, bearerToken string) {
+	if mode == "" {
+		mode = AuthBasic
+	}
+	c.authMode = mode
+	c.bearerToken = bearerToken
+}
+
+// authorize sets the credentials that can be sent up front. Digest needs a
+// challenge first and is handled by do.
+func (c *Client) authorize(req *http.Request) {
+	switch c.authMode {
+	case AuthBasic:
+		req.SetBasicAuth(c.username, c.password)
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	case AuthDigest:
+		if header, ok := c.digest.authorization(req, c.username, c.password); ok {
+			req.Header.Set("Authorization", header)
+		}
+	}
+}
+
+// do sends a request. With Digest auth the first request of a client, or
+// one sent with a stale nonce, is answered with a challenge and replayed
+// once with the computed response; later requests reuse the challenge so
+// upload bodies are normally sent only once.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil || c.authMode != AuthDigest || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge, ok := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorResponse))
+	resp.Body.Close()
+
+	c.digest.reset(challenge)
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	header, _ := c.digest.authorization(retry, c.username, c.password)
+	retry.Header.Set("Authorization", header)
+	return c.httpClient.Do(retry)
+}
+
+// digestAuth keeps the last Digest challenge (RFC 7616) and its nonce count.
+type digestAuth struct {
+	mu        sync.Mutex
+	challenge map[string]string
+	count     uint32
+}
+
+func (d *digestAuth) reset(challenge map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.challenge = challenge
+	d.count = 0
+}
+
+func (d *digestAuth) authorization(req *http.Request, username, password string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.challenge == nil {
+		return "", false
+	}
+	d.count++
+	params := d.challenge
+	algorithm := params["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	newHash := md5.New
+	if strings.HasPrefix(strings.ToUpper(algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	digest := func(parts ...string) string {
+		return hashHex(newHash, strings.Join(parts, ":"))
+	}
+
+	cnonceBytes := make([]byte, 12)
+	_, _ = rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nc := fmt.Sprintf("%08x", d.count)
+	uri := req.URL.RequestURI()
+
+	ha1 := digest(username, params["realm"], password)
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = digest(ha1, params["nonce"], cnonce)
+	}
+	ha2 := digest(req.Method, uri)
+
+	fields := []string{
+		fmt.Sprintf("username=%q", username),
+		fmt.Sprintf("realm=%q", params["realm"]),
+		fmt.Sprintf("nonce=%q", params["nonce"]),
+		fmt.Sprintf("uri=%q", uri),
+		"algorithm=" + algorithm,
+	}
+	if digestQOPAuth(params["qop"]) {
+		response := digest(ha1, params["nonce"], nc, cnonce, "auth", ha2)
+		fields = append(fields, fmt.Sprintf("response=%q", response), "qop=auth", "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	} else {
+		fields = append(fields, fmt.Sprintf("response=%q", digest(ha1, params["nonce"], ha2)))
+	}
+	if opaque, ok := params["opaque"]; ok {
+		fields = append(fields, fmt.Sprintf("opaque=%q", opaque))
+	}
+	return "Digest " + strings.Join(fields, ", "), true
+}
+
+func hashHex(newHash func() hash.Hash, value string) string {
+	h := newHash()
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func digestQOPAuth(qop string) bool {
+	for _, option := range strings.Split(qop, ",") {
+		if strings.TrimSpace(option) == "auth" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDigestChallenge picks the Digest challenge among the WWW-Authenticate
+// headers, preferring SHA-256 when the server offers several algorithms.
+func parseDigestChallenge(headers []string) (map[string]string, bool) {
+	var chosen map[string]string
+	for _, header := range headers {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		if params["nonce"] == "" {
+			continue
+		}
+		algorithm := strings.ToUpper(params["algorithm"])
+		switch algorithm {
+		case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+		default:
+			continue
+		}
+		if chosen == nil || strings.HasPrefix(algorithm, "SHA-256") {
+			chosen = params
+		}
+	}
+	return chosen, chosen != nil
+}
+
+// parseAuthParams parses comma separated key=value pairs whose values may be
+// quoted strings containing commas.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return params
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " \t")
+		var value strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[key] = value.String()
+	}
+}
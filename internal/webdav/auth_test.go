@@ -0,0 +1,190 @@
+package webdav
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func md5Hex(value string) string {
+	sum := md5.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDigestAuthAnswersChallengeAndReusesNonce(t *testing.T) {
+	const nonce = "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	var (
+		mu         sync.Mutex
+		challenges int
+		uploaded   = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		header := r.Header.Get("Authorization")
+		params := parseAuthParams(strings.TrimPrefix(header, "Digest "))
+		ha1 := md5Hex("alice:vault:s3cret")
+		ha2 := md5Hex(r.Method + ":" + params["uri"])
+		want := md5Hex(strings.Join([]string{ha1, nonce, params["nc"], params["cnonce"], "auth", ha2}, ":"))
+		if !strings.HasPrefix(header, "Digest ") || params["response"] != want || params["uri"] != r.URL.RequestURI() || params["opaque"] != "5ccc" {
+			challenges++
+			w.Header().Add("WWW-Authenticate", `Basic realm="vault"`)
+			w.Header().Add("WWW-Authenticate", `Digest realm="vault", qop="auth,auth-int", nonce="`+nonce+`", opaque="5ccc"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			uploaded[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMultiStatus)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/dav", "alice", "s3cret")
+	client.SetAuthMode(AuthDigest, "")
+	// The challenged PUT is replayed with its body.
+	if err := client.UploadFile(writeUploadFile(t, `{"encrypted":true}`), "/backup.json"); err != nil {
+		t.Fatalf("UploadFile returned error: %v", err)
+	}
+	// Later requests reuse the nonce without another challenge.
+	if err := client.UploadFile(writeUploadFile(t, `{"encrypted":true}`), "/vault/backup.json"); err != nil {
+		t.Fatalf("UploadFile returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if uploaded["/dav/backup.json"] != `{"encrypted":true}` || uploaded["/dav/vault/backup.json"] != `{"encrypted":true}` {
+		t.Fatalf("uploaded = %q", uploaded)
+	}
+	if challenges != 1 {
+		t.Fatalf("challenges = %d, want 1", challenges)
+	}
+}
+
+func TestBearerAuthSendsToken(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = io.WriteString(w, `<D:multistatus xmlns:D="DAV:"/>`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/dav", "ignored", "ignored")
+	client.SetAuthMode(AuthBearer, "token-123")
+	if _, err := client.ListFiles("/backup"); err != nil {
+		t.Fatalf("ListFiles returned error: %v", err)
+	}
+	if header != "Bearer token-123" {
+		t.Fatalf("Authorization = %q", header)
+	}
+}
+
+// newTestClientCertificate returns a self-signed client certificate and key
+// in PEM form.
+func newTestClientCertificate(t *testing.T) (certPEM, keyPEM string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "bitwarden-backup"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certPEM, keyPEM, cert
+}
+
+func TestMutualTLSWithCustomCA(t *testing.T) {
+	certPEM, keyPEM, clientCert := newTestClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("auth mode none sent Authorization %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = io.WriteString(w, `<D:multistatus xmlns:D="DAV:"/>`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	// Without the CA the server certificate is not trusted.
+	client := NewClient(server.URL+"/dav", "", "")
+	if _, err := client.ListFiles("/backup"); err == nil {
+		t.Fatal("ListFiles trusted an unknown CA")
+	}
+
+	// With the CA but no client certificate the handshake is rejected.
+	config, err := NewTLSConfig("", "", caPEM)
+	if err != nil {
+		t.Fatalf("NewTLSConfig returned error: %v", err)
+	}
+	client.SetTLSConfig(config)
+	if _, err := client.ListFiles("/backup"); err == nil {
+		t.Fatal("ListFiles succeeded without a client certificate")
+	}
+
+	config, err = NewTLSConfig(certPEM, keyPEM, caPEM)
+	if err != nil {
+		t.Fatalf("NewTLSConfig returned error: %v", err)
+	}
+	client = NewClient(server.URL+"/dav", "", "")
+	client.SetAuthMode(AuthNone, "")
+	client.SetTLSConfig(config)
+	if _, err := client.ListFiles("/backup"); err != nil {
+		t.Fatalf("ListFiles returned error: %v", err)
+	}
+}
+
+func TestNewTLSConfigRejectsInvalidPEM(t *testing.T) {
+	certPEM, _, _ := newTestClientCertificate(t)
+	_, otherKey, _ := newTestClientCertificate(t)
+	if _, err := NewTLSConfig(certPEM, otherKey, ""); err == nil {
+		t.Error("NewTLSConfig accepted a key that does not match the certificate")
+	}
+	if _, err := NewTLSConfig("", "", "not a certificate"); err == nil {
+		t.Error("NewTLSConfig accepted a CA bundle without certificates")
+	}
+	if config, err := NewTLSConfig("", "", ""); config != nil || err != nil {
+		t.Errorf("NewTLSConfig() = %v, %v, want nil for no settings", config, err)
+	}
+}
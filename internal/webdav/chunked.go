@@ -0,0 +1,148 @@
+package webdav
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Nextcloud requires chunks between 5 MiB and 5 GiB, except the last one,
+// and at most 10000 of them.
+const defaultChunkSize = 10 << 20
+
+// errChunkingUnsupported means the server has no chunking v2 upload
+// collection; the caller falls back to a single PUT.
+var errChunkingUnsupported = errors.New("WebDAV chunked upload is not supported")
+
+// Files URLs of Nextcloud and ownCloud: <root>/remote.php/dav/files/<user>/...
+var chunkFilesPathPattern = regexp.MustCompile(`^(.*/remote\.php/dav)/files/([^/]+)(?:/|$)`)
+
+// chunkUploadsURL returns the chunking v2 upload collection that belongs to
+// the configured files URL.
+func (c *Client) chunkUploadsURL() (string, bool) {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", false
+	}
+	match := chunkFilesPathPattern.FindStringSubmatch(base.Path)
+	if match == nil {
+		return "", false
+	}
+	base.Path = match[1] + "/uploads/" + match[2]
+	base.RawPath = ""
+	return base.String(), true
+}
+
+// uploadChunked implements Nextcloud chunking v2: MKCOL a transfer
+// collection, PUT numbered chunks into it and MOVE the assembled .file to
+// the destination. A failed chunk is retried on its own, so a dropped
+// connection does not restart the whole transfer. The transfer collection is
+// deleted when the upload fails.
+func (c *Client) uploadChunked(ctx context.Context, uploadsURL, fullURL string, file io.ReaderAt, size int64) (err error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to create upload id: %w", err)
+	}
+	transferURL := uploadsURL + "/bitwarden-backup-" + hex.EncodeToString(id)
+
+	req, err := c.newRequest(ctx, "MKCOL", transferURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Destination", fullURL)
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to start chunked upload: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		resp.Body.Close()
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		resp.Body.Close()
+		return errChunkingUnsupported
+	default:
+		defer resp.Body.Close()
+		return responseError("WebDAV chunked upload start", resp)
+	}
+	defer func() {
+		if err != nil {
+			c.discard(ctx, transferURL)
+		}
+	}()
+
+	for index, offset := 1, int64(0); offset < size; index, offset = index+1, offset+c.chunkSize {
+		length := min(c.chunkSize, size-offset)
+		chunkURL := fmt.Sprintf("%s/%05d", transferURL, index)
+		if err := c.withRetry(ctx, func() error {
+			return c.putChunk(ctx, chunkURL, fullURL, io.NewSectionReader(file, offset, length), length, size)
+		}); err != nil {
+			return fmt.Errorf("failed to upload chunk %d: %w", index, err)
+		}
+	}
+
+	// The server assembles the chunks during the MOVE, which may take a
+	// while for large files but is bounded by the same request timeout.
+	move, err := c.newRequest(ctx, "MOVE", transferURL+"/.file", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	move.Header.Set("Destination", fullURL)
+	move.Header.Set("OC-Total-Length", strconv.FormatInt(size, 10))
+	move.Header.Set("Overwrite", "T")
+	resp, err = c.do(move)
+	if err != nil {
+		return fmt.Errorf("failed to assemble chunked upload: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return responseError("WebDAV chunked upload assembly", resp)
+	}
+	return nil
+}
+
+func (c *Client) putChunk(ctx context.Context, chunkURL, fullURL string, body io.Reader, length, size int64) error {
+	req, err := c.newRequest(ctx, http.MethodPut, chunkURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = length
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Destination", fullURL)
+	req.Header.Set("OC-Total-Length", strconv.FormatInt(size, 10))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return responseError("WebDAV chunk upload", resp)
+	}
+	return nil
+}
+
+// discard deletes targetURL, also after the caller gave up, so an aborted
+// chunked transfer does not wait for the server's expiry job and a failed
+// PUT leaves no partial file behind.
+func (c *Client) discard(ctx context.Context, targetURL string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	req, err := c.newRequest(abortCtx, http.MethodDelete, targetURL, nil)
+	if err != nil {
+		return
+	}
+	if resp, err := c.do(req); err == nil {
+		resp.Body.Close()
+	}
+}
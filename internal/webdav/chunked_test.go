@@ -0,0 +1,377 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeNextcloud emulates the files and chunking v2 uploads endpoints of a
+// Nextcloud server for the user alice.
+type fakeNextcloud struct {
+	mu        sync.Mutex
+	files     map[string]string
+	transfers map[string]map[string]string
+	requests  []string
+	// failChunk answers the first PUT of the named chunk with 503.
+	failChunk string
+	// noChunking answers MKCOL under /uploads/ with 405 like a plain server.
+	noChunking bool
+}
+
+func newFakeNextcloud(t *testing.T) (*fakeNextcloud, *Client) {
+	t.Helper()
+	fake := &fakeNextcloud{files: make(map[string]string), transfers: make(map[string]map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(fake.serveHTTP))
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL+"/remote.php/dav/files/alice/", "alice", "app-password")
+	client.chunkSize = 4
+	client.retryDelay = 0
+	return fake, client
+}
+
+func (f *fakeNextcloud) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	const uploads = "/remote.php/dav/uploads/alice/"
+	name := strings.TrimPrefix(r.URL.Path, "/remote.php/dav/files/alice")
+	transfer, chunk, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, uploads), "/")
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.Method == "PROPFIND":
+		w.WriteHeader(http.StatusMultiStatus)
+	case r.Method == "MKCOL" && strings.HasPrefix(r.URL.Path, uploads):
+		if f.noChunking {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !strings.HasSuffix(r.Header.Get("Destination"), "/remote.php/dav/files/alice/vault/backup.json") {
+			http.Error(w, "bad destination", http.StatusBadRequest)
+			return
+		}
+		f.transfers[transfer] = make(map[string]string)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, uploads):
+		chunks := f.transfers[transfer]
+		if chunks == nil || r.Header.Get("Destination") == "" || r.Header.Get("OC-Total-Length") == "" {
+			http.Error(w, "bad chunk", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if chunk == f.failChunk {
+			f.failChunk = ""
+			http.Error(w, "storage busy", http.StatusServiceUnavailable)
+			return
+		}
+		chunks[chunk] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "MOVE" && chunk == ".file":
+		chunks := f.transfers[transfer]
+		names := make([]string, 0, len(chunks))
+		for name := range chunks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var assembled strings.Builder
+		for _, name := range names {
+			assembled.WriteString(chunks[name])
+		}
+		if strconv.Itoa(assembled.Len()) != r.Header.Get("OC-Total-Length") {
+			http.Error(w, "length mismatch", http.StatusBadRequest)
+			return
+		}
+		target := strings.TrimPrefix(r.Header.Get("Destination"), "http://"+r.Host+"/remote.php/dav/files/alice")
+		f.files[target] = assembled.String()
+		delete(f.transfers, transfer)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, uploads):
+		delete(f.transfers, transfer)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.files[name] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeUploadFile(t *testing.T, content string) string {
+	t.Helper()
+	localPath := filepath.Join(t.TempDir(), "backup.json")
+	if err := os.WriteFile(localPath, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	return localPath
+}
+
+func TestUploadFileUsesNextcloudChunking(t *testing.T) {
+	fake, client := newFakeNextcloud(t)
+	fake.failChunk = "00002"
+
+	if err := client.UploadFile(writeUploadFile(t, `{"encrypted":true}`), "/vault/backup.json"); err != nil {
+		t.Fatalf("UploadFile returned error: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if got := fake.files["/vault/backup.json"]; got != `{"encrypted":true}` {
+		t.Fatalf("assembled file = %q", got)
+	}
+	if len(fake.transfers) != 0 {
+		t.Fatalf("transfer collections left behind: %v", fake.transfers)
+	}
+	// Only the failed chunk is sent twice.
+	counts := make(map[string]int)
+	for _, request := range fake.requests {
+		if strings.HasPrefix(request, "PUT ") {
+			counts[request[strings.LastIndex(request, "/")+1:]]++
+		}
+	}
+	want := map[string]int{"00001": 1, "00002": 2, "00003": 1, "00004": 1, "00005": 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Fatalf("chunk PUTs = %v, want %v", counts, want)
+	}
+}
+
+func TestUploadFileFallsBackWithoutChunking(t *testing.T) {
+	fake, client := newFakeNextcloud(t)
+	fake.noChunking = true
+
+	if err := client.UploadFile(writeUploadFile(t, `{"encrypted":true}`), "/vault/backup.json"); err != nil {
+		t.Fatalf("UploadFile returned error: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if got := fake.files["/vault/backup.json"]; got != `{"encrypted":true}` {
+		t.Fatalf("uploaded file = %q", got)
+	}
+}
+
+func TestUploadFileAbortsFailedChunkedUpload(t *testing.T) {
+	fake, client := newFakeNextcloud(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/00002") {
+			fake.mu.Lock()
+			fake.requests = append(fake.requests, r.Method+" "+r.URL.Path)
+			fake.mu.Unlock()
+			http.Error(w, "quota exceeded", http.StatusInsufficientStorage)
+			return
+		}
+		fake.serveHTTP(w, r)
+	}))
+	defer server.Close()
+	client.baseURL = server.URL + "/remote.php/dav/files/alice"
+
+	err := client.UploadFile(writeUploadFile(t, `{"encrypted":true}`), "/vault/backup.json")
+	if err == nil || !strings.Contains(err.Error(), "chunk 2") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("UploadFile error = %v, want chunk failure", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.transfers) != 0 {
+		t.Fatalf("transfer collection was not deleted: %v", fake.transfers)
+	}
+	last := fake.requests[len(fake.requests)-1]
+	if !strings.HasPrefix(last, "DELETE /remote.php/dav/uploads/alice/bitwarden-backup-") {
+		t.Fatalf("last request = %q, want transfer deletion", last)
+	}
+}
+
+func TestUploadFileResumesInterruptedPut(t *testing.T) {
+	const content = `{"encrypted":true,"data":"0123456789"}`
+	var (
+		mu     sync.Mutex
+		stored string
+		ranges []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", strconv.Itoa(len(stored)))
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			contentRange := r.Header.Get("Content-Range")
+			ranges = append(ranges, contentRange)
+			if contentRange == "" {
+				// Keep the first half, then let the proxy time out.
+				stored = string(body[:len(body)/2])
+				w.WriteHeader(http.StatusGatewayTimeout)
+				return
+			}
+			start, _ := strconv.Atoi(strings.TrimPrefix(strings.Split(contentRange, "-")[0], "bytes "))
+			if start != len(stored) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			stored += string(body)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMultiStatus)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/dav", "user", "password")
+	client.retryDelay = 0
+	if err := client.UploadFile(writeUploadFile(t, content), "/backup.json"); err != nil {
+		t.Fatalf("UploadFile returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if stored != content {
+		t.Fatalf("stored = %q, want %q", stored, content)
+	}
+	half := len(content) / 2
+	want := []string{"", "bytes " + strconv.Itoa(half) + "-" + strconv.Itoa(len(content)-1) + "/" + strconv.Itoa(len(content))}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("PUT ranges = %q, want %q", ranges, want)
+	}
+}
+
+func TestUploadFileRestartsWhenRangedPutIsIgnored(t *testing.T) {
+	const content = `{"encrypted":true}`
+	var (
+		mu   sync.Mutex
+		puts int
+		body string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		case http.MethodPut:
+			// Replaces the file with whatever was sent, Content-Range or not.
+			data, _ := io.ReadAll(r.Body)
+			puts++
+			body = string(data)
+			if puts == 1 {
+				body = body[:5]
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMultiStatus)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/dav", "user", "password")
+	client.retryDelay = 0
+	if err := client.UploadFileContext(context.Background(), writeUploadFile(t, content), "/backup.json"); err != nil {
+		t.Fatalf("UploadFile returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if body != content || puts != 3 {
+		t.Fatalf("stored %q after %d PUTs, want full content after a restarted upload", body, puts)
+	}
+}
+
+func TestUploadFileDeletesTailWhenLastRangedPutIsIgnored(t *testing.T) {
+	const content = `{"encrypted":true}`
+	var (
+		mu      sync.Mutex
+		puts    int
+		body    string
+		deleted bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodHead:
+			if body == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			puts++
+			switch puts {
+			case 1:
+				body = string(data[:5])
+				w.WriteHeader(http.StatusBadGateway)
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				// The last attempt resumes, and the server stores only the tail.
+				body = string(data)
+				w.WriteHeader(http.StatusCreated)
+			}
+		case http.MethodDelete:
+			body, deleted = "", true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMultiStatus)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/dav", "user", "password")
+	client.retryDelay = 0
+	if err := client.UploadFileContext(context.Background(), writeUploadFile(t, content), "/backup.json"); err == nil {
+		t.Fatal("UploadFile should fail when the last resumed PUT was not applied")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if puts != maxUploadAttempts || !deleted || body != "" {
+		t.Fatalf("after %d PUTs deleted=%v stored=%q, want the truncated file removed", puts, deleted, body)
+	}
+}
+
+func TestUploadFileKeepsNothingAfterFailedPut(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		body    string
+		deletes int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		case http.MethodPut:
+			w.WriteHeader(http.StatusForbidden)
+		case http.MethodDelete:
+			deletes++
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMultiStatus)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/dav", "user", "password")
+	client.retryDelay = 0
+	if err := client.UploadFile(writeUploadFile(t, `{"encrypted":true}`), "/backup.json"); err == nil {
+		t.Fatal("UploadFile should fail on a rejected PUT")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if deletes != 0 {
+		t.Fatalf("DELETE sent %d times although nothing was stored", deletes)
+	}
+}
@@ -1,21 +1,84 @@
 package webdav
 
 import (
+	"context"
+	"io"
+	"net/http"
 	"strings"
+	"time"
 )
 
+// DefaultTimeout bounds each WebDAV request when the destination does not
+// configure a timeout.
+const DefaultTimeout = 60 * time.Second
+
 // Client WebDAV 客户端
 type Client struct {
 	baseURL  string
 	username string
 	password string
+	// authMode is one of the Auth* constants.
+	authMode    string
+	bearerToken string
+	digest      digestAuth
+
+	httpClient *http.Client
+	// chunkSize is the Nextcloud chunking v2 chunk size; smaller files and
+	// servers without chunking use a single PUT.
+	chunkSize  int64
+	retryDelay time.Duration
 }
 
 // NewClient 创建 WebDAV 客户端
 func NewClient(baseURL, username, password string) *Client {
 	return &Client{
-		baseURL:  strings.TrimSuffix(baseURL, "/"),
-		username: username,
-		password: password,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		username:   username,
+		password:   password,
+		authMode:   AuthBasic,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		chunkSize:  defaultChunkSize,
+		retryDelay: 2 * time.Second,
+	}
+}
+
+// SetTimeout bounds every request, including the body transfer of a PUT or
+// of one chunk. Zero keeps DefaultTimeout.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// UploadBudget is the time an upload of size bytes may take when every
+// request runs into the timeout: each chunk, or the single PUT, with all its
+// attempts, size checks and back-off, plus the directory and assembly
+// requests around them.
+func (c *Client) UploadBudget(size int64) time.Duration {
+	requests := int64(1)
+	if size > c.chunkSize {
+		requests = (size + c.chunkSize - 1) / c.chunkSize
+	}
+	timeout := c.httpClient.Timeout
+	attempt := 2*timeout + maxUploadAttempts*c.retryDelay
+	return time.Duration(requests*maxUploadAttempts)*attempt + 4*timeout
+}
+
+// newRequest builds an authenticated request bound to ctx. Section readers
+// get a GetBody so a Digest challenge can replay the upload.
+func (c *Client) newRequest(ctx context.Context, method, fullURL string, body io.Reader) (*http.Request, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if section, ok := body.(*io.SectionReader); ok {
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(io.NewSectionReader(section, 0, section.Size())), nil
+		}
 	}
+	c.authorize(req)
+	return req, nil
 }
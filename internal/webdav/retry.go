@@ -0,0 +1,166 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxUploadAttempts bounds the tries of one PUT or one chunk.
+const maxUploadAttempts = 3
+
+// retryable reports whether a failed upload request may succeed when it is
+// repeated: transport errors and the statuses of an overloaded server or
+// proxy.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		return true
+	}
+	switch respErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusLocked, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// wait backs off before the next attempt, giving up when ctx is done.
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(c.retryDelay * time.Duration(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// withRetry runs one idempotent request until it succeeds, fails with a
+// permanent error or runs out of attempts.
+func (c *Client) withRetry(ctx context.Context, request func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := request()
+		if err == nil || attempt >= maxUploadAttempts || !retryable(ctx, err) {
+			return err
+		}
+		if waitErr := c.wait(ctx, attempt); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// putFile uploads with a single PUT and retries transient failures. A retry
+// resumes with Content-Range when the server kept a partial file. Servers
+// without ranged PUT must reject it (RFC 9110); one that ignores the header
+// stores only the remainder, which the size check after the PUT catches.
+// Both cases fall back to a full upload. When the attempts run out, whatever
+// the server kept under the backup name is deleted.
+func (c *Client) putFile(ctx context.Context, fullURL string, file io.ReaderAt, size int64) error {
+	resume := true
+	var offset int64
+	for attempt := 1; ; attempt++ {
+		err := c.put(ctx, fullURL, file, offset, size)
+		if err == nil && offset > 0 {
+			if stored, headErr := c.remoteSize(ctx, fullURL); headErr != nil || stored != size {
+				err = fmt.Errorf("resumed WebDAV upload was not applied at offset %d", offset)
+				resume = false
+			}
+		}
+		if err == nil {
+			return nil
+		}
+
+		retry := retryable(ctx, err)
+		var respErr *ResponseError
+		if offset > 0 && errors.As(err, &respErr) && (respErr.StatusCode == http.StatusBadRequest ||
+			respErr.StatusCode == http.StatusRequestedRangeNotSatisfiable || respErr.StatusCode == http.StatusNotImplemented) {
+			resume, retry = false, true
+		}
+		if !retry || attempt >= maxUploadAttempts {
+			c.discardPartial(ctx, fullURL, offset)
+			return err
+		}
+		if waitErr := c.wait(ctx, attempt); waitErr != nil {
+			c.discardPartial(ctx, fullURL, offset)
+			return err
+		}
+
+		offset = 0
+		if resume {
+			if stored, headErr := c.remoteSize(ctx, fullURL); headErr == nil && stored > 0 && stored < size {
+				offset = stored
+			}
+		}
+	}
+}
+
+// discardPartial deletes what a failed upload left at fullURL: the tail of a
+// resumed PUT or a partly written file. Retention and replication would
+// otherwise take it for a backup.
+func (c *Client) discardPartial(ctx context.Context, fullURL string, offset int64) {
+	if offset == 0 {
+		checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		stored, err := c.remoteSize(checkCtx, fullURL)
+		cancel()
+		if err == nil && stored == 0 {
+			return
+		}
+	}
+	c.discard(ctx, fullURL)
+}
+
+// put sends the file from offset on; a non-zero offset is a ranged PUT.
+func (c *Client) put(ctx context.Context, fullURL string, file io.ReaderAt, offset, size int64) error {
+	var body io.Reader = http.NoBody
+	if size > offset {
+		body = io.NewSectionReader(file, offset, size-offset)
+	}
+	req, err := c.newRequest(ctx, http.MethodPut, fullURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = size - offset
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if offset > 0 {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, size-1, size))
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return responseError("WebDAV upload", resp)
+	}
+	return nil
+}
+
+// remoteSize returns the stored size of a file, 0 when it does not exist and
+// -1 when the server does not report a length.
+func (c *Client) remoteSize(ctx context.Context, fullURL string) (int64, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, fullURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return 0, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.ContentLength, nil
+	}
+	return 0, responseError("WebDAV size check", resp)
+}
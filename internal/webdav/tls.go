@@ -0,0 +1,47 @@
+package webdav
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// NewTLSConfig builds the TLS settings of a destination: an optional client
+// certificate for mutual TLS and an optional PEM CA bundle trusted in
+// addition to the system roots. It returns nil when neither is configured.
+func NewTLSConfig(clientCert, clientKey, caBundle string) (*tls.Config, error) {
+	if strings.TrimSpace(clientCert) == "" && strings.TrimSpace(clientKey) == "" && strings.TrimSpace(caBundle) == "" {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if strings.TrimSpace(clientCert) != "" || strings.TrimSpace(clientKey) != "" {
+		certificate, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	if strings.TrimSpace(caBundle) != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caBundle)) {
+			return nil, fmt.Errorf("CA bundle contains no PEM certificate")
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// SetTLSConfig uses config for HTTPS connections; nil keeps the defaults.
+func (c *Client) SetTLSConfig(config *tls.Config) {
+	if config == nil {
+		return
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	c.httpClient.Transport = transport
+}
@@ -3,6 +3,7 @@ package webdav
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,11 +16,6 @@ import (
 	"github.com/mingzaily/bitwarden-backup/internal/safety"
 )
 
-// httpClient 带超时的 HTTP 客户端
-var httpClient = &http.Client{
-	Timeout: 60 * time.Second,
-}
-
 const (
 	maxPropfindResponse = 4 << 20
 	maxErrorResponse    = 64 << 10
@@ -89,15 +85,13 @@ func (c *Client) ensureCollection(ctx context.Context, remotePath string) error
 		return err
 	}
 
-	probe, err := http.NewRequest("PROPFIND", fullURL, nil)
+	probe, err := c.newRequest(ctx, "PROPFIND", fullURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create directory check request: %w", err)
 	}
-	probe = probe.WithContext(ctx)
-	probe.SetBasicAuth(c.username, c.password)
 	probe.Header.Set("Depth", "0")
 
-	resp, err := httpClient.Do(probe)
+	resp, err := c.do(probe)
 	if err != nil {
 		return fmt.Errorf("failed to check WebDAV directory: %w", err)
 	}
@@ -111,14 +105,12 @@ func (c *Client) ensureCollection(ctx context.Context, remotePath string) error
 	}
 	resp.Body.Close()
 
-	create, err := http.NewRequest("MKCOL", fullURL, nil)
+	create, err := c.newRequest(ctx, "MKCOL", fullURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create directory request: %w", err)
 	}
-	create = create.WithContext(ctx)
-	create.SetBasicAuth(c.username, c.password)
 
-	resp, err = httpClient.Do(create)
+	resp, err = c.do(create)
 	if err != nil {
 		return fmt.Errorf("failed to create WebDAV directory: %w", err)
 	}
@@ -162,7 +154,9 @@ func (c *Client) UploadFile(localPath, remotePath string) error {
 }
 
 // UploadFileContext 上传文件到 WebDAV，并将调用方的取消信号传递给目录
-// 检查、目录创建和 PUT 请求。
+// 检查、目录创建和 PUT 请求。Files larger than one chunk use Nextcloud
+// chunking v2 when the server offers it, otherwise a single PUT with
+// resumable retries.
 func (c *Client) UploadFileContext(ctx context.Context, localPath, remotePath string) error {
 	if ctx == nil {
 		ctx = context.Background()
@@ -192,31 +186,16 @@ func (c *Client) UploadFileContext(ctx context.Context, localPath, remotePath st
 		}
 	}
 
-	// 创建 PUT 请求
-	req, err := http.NewRequest("PUT", fullURL, file)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req = req.WithContext(ctx)
-
-	// 设置认证
-	req.SetBasicAuth(c.username, c.password)
-	req.ContentLength = fileInfo.Size()
-	req.Header.Set("Content-Type", "application/octet-stream")
-
-	// 发送请求
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to upload: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
-		return responseError("WebDAV upload", resp)
+	size := fileInfo.Size()
+	if size > c.chunkSize {
+		if uploadsURL, ok := c.chunkUploadsURL(); ok {
+			err := c.uploadChunked(ctx, uploadsURL, fullURL, file, size)
+			if !errors.Is(err, errChunkingUnsupported) {
+				return err
+			}
+		}
 	}
-
-	return nil
+	return c.putFile(ctx, fullURL, file, size)
 }
 
 // FileInfo WebDAV 文件信息
@@ -263,16 +242,13 @@ func (c *Client) ListFilesContext(ctx context.Context, remotePath string) ([]Fil
 		return nil, err
 	}
 
-	req, err := http.NewRequest("PROPFIND", fullURL, nil)
+	req, err := c.newRequest(ctx, "PROPFIND", fullURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	req = req.WithContext(ctx)
-
-	req.SetBasicAuth(c.username, c.password)
 	req.Header.Set("Depth", "1")
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
@@ -327,6 +303,36 @@ func (c *Client) Test(ctx context.Context, remotePath string) error {
 	return err
 }
 
+// DownloadContext 下载远程文件并写入 w
+func (c *Client) DownloadContext(ctx context.Context, remotePath string, w io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	fullURL, err := c.requestURL(remotePath)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("WebDAV download", resp)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	return nil
+}
+
 // Delete 删除远程文件
 func (c *Client) Delete(remotePath string) error {
 	return c.DeleteContext(context.Background(), remotePath)
@@ -342,15 +348,12 @@ func (c *Client) DeleteContext(ctx context.Context, remotePath string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("DELETE", fullURL, nil)
+	req, err := c.newRequest(ctx, "DELETE", fullURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
-	req = req.WithContext(ctx)
-
-	req.SetBasicAuth(c.username, c.password)
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to delete: %w", err)
 	}
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRequestURLJoinsBaseAndRemotePath(t *testing.T) {
@@ -147,6 +148,19 @@ func TestUploadFileIncludesWebDAVResponseBody(t *testing.T) {
 	}
 }
 
+func TestUploadBudgetCoversEveryChunk(t *testing.T) {
+	client := NewClient("https://dav.example.com/", "user", "password")
+	client.SetTimeout(10 * time.Minute)
+
+	single := client.UploadBudget(1 << 20)
+	if single <= 5*time.Minute {
+		t.Fatalf("UploadBudget() = %s, want the request timeout to extend it", single)
+	}
+	if chunked := client.UploadBudget(100 << 20); chunked < 10*single/2 {
+		t.Fatalf("UploadBudget() of ten chunks = %s, want it to grow with the chunk count (single PUT %s)", chunked, single)
+	}
+}
+
 func TestTestChecksCollectionWithoutUploading(t *testing.T) {
 	var method string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -6,13 +6,16 @@ const handleResponse = async (response) => {
   if (response.status === 204) return null
   if (!response.ok) {
     let message = `HTTP ${response.status}`
+    let data = null
     try {
-      const data = await response.json()
+      data = await response.json()
       message = data.error || message
     } catch {
       // Keep the generic HTTP message when the server did not return JSON.
     }
-    throw new Error(message)
+    const error = new Error(message)
+    error.data = data
+    throw error
   }
   return response.json()
 }
@@ -88,6 +91,7 @@ export const serversApi = {
   create: (data) => request('/servers', { method: 'POST', body: JSON.stringify(data) }),
   update: (id, data) => request(`/servers/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
   setEnabled: (id, enabled) => request(`/servers/${id}/enabled`, { method: 'PATCH', body: JSON.stringify({ enabled }) }),
+  organizations: (id) => request(`/servers/${id}/organizations`),
   delete: (id) => request(`/servers/${id}`, { method: 'DELETE' })
 }
 
@@ -109,10 +113,24 @@ export const tasksApi = {
   update: (id, data) => request(`/tasks/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
   setEnabled: (id, enabled) => request(`/tasks/${id}/enabled`, { method: 'PATCH', body: JSON.stringify({ enabled }) }),
   delete: (id) => request(`/tasks/${id}`, { method: 'DELETE' }),
-  execute: (id) => request(`/tasks/${id}/execute`, { method: 'POST' })
+  execute: (id) => request(`/tasks/${id}/execute`, { method: 'POST' }),
+  cancelQueue: (id) => request(`/tasks/${id}/queue`, { method: 'DELETE' }),
+  schedule: (id, count = 10) => request(`/tasks/${id}/schedule?count=${count}`)
+}
+
+export const cronApi = {
+  preview: (data) => request('/cron/preview', { method: 'POST', body: JSON.stringify(data) })
+}
+
+export const blackoutsApi = {
+  getAll: () => request('/blackouts'),
+  create: (data) => request('/blackouts', { method: 'POST', body: JSON.stringify(data) }),
+  update: (id, data) => request(`/blackouts/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
+  delete: (id) => request(`/blackouts/${id}`, { method: 'DELETE' })
 }
 
 export const logsApi = {
   getAll: (params = {}) => request(paginatedPath('logs', params)),
-  deleteMany: (ids) => request('/logs', { method: 'DELETE', body: JSON.stringify({ ids }) })
+  deleteMany: (ids) => request('/logs', { method: 'DELETE', body: JSON.stringify({ ids }) }),
+  cancel: (id) => request(`/logs/${id}/cancel`, { method: 'POST' })
 }